@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/storage"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+// multipartOverhead is allowed on top of the attachment size limit for the
+// multipart boundaries and part headers.
+const multipartOverhead = 64 << 10
+
+// minTransferRate is the slowest link, in bytes per second, that attachments
+// are expected to cross. A transfer is given as long as its size takes at
+// this rate on top of the server's usual timeouts.
+const minTransferRate = 32 << 10
+
+func (app *application) uploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !app.authorizeNote(w, r, noteID, data.RoleEditor) {
+		return
+	}
+
+	maxBytes := app.config.attachments.maxBytes
+
+	err = extendDeadlines(w, maxBytes+multipartOverhead, true)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+multipartOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("body must be multipart/form-data"))
+		return
+	}
+
+	var part io.Reader
+	var filename string
+
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			switch {
+			case errors.Is(err, io.EOF):
+				app.failedValidationResponse(w, r, map[string]string{"file": "must be provided"})
+			case errors.As(err, &maxBytesError):
+				app.badRequestResponse(w, r, fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit))
+			default:
+				app.badRequestResponse(w, r, err)
+			}
+			return
+		}
+
+		if p.FormName() == "file" {
+			part = p
+			filename = filepath.Base(filepath.Clean("/" + p.FileName()))
+			break
+		}
+	}
+
+	if filename == "/" || filename == "." {
+		filename = ""
+	}
+
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(part, maxBytes+1))
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.failedValidationResponse(w, r, map[string]string{
+				"file": fmt.Sprintf("must not be larger than %d bytes", maxBytes),
+			})
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	attachment := &data.Attachment{
+		NoteID:   noteID,
+		Filename: filename,
+		Size:     size,
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+	}
+
+	v := validator.New()
+
+	data.ValidateAttachment(v, attachment, maxBytes)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	attachment.ContentType, err = sniffContentType(tmp)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	attachment.StorageKey, err = newStorageKey(noteID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.blobs.Put(r.Context(), attachment.StorageKey, tmp, attachment.Size, attachment.ContentType)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Attachments.Insert(attachment)
+	if err != nil {
+		if delErr := app.blobs.Delete(context.Background(), attachment.StorageKey); delErr != nil {
+			app.logError(r, delErr)
+		}
+
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/notes/%d/attachments/%d", noteID, attachment.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"attachment": attachment}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !app.authorizeNote(w, r, noteID, data.RoleViewer) {
+		return
+	}
+
+	attachments, err := app.models.Attachments.GetAllForNote(noteID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"attachments": attachments}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) downloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	id, err := app.readNamedIDParam(r, "attachment_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !app.authorizeNote(w, r, noteID, data.RoleViewer) {
+		return
+	}
+
+	attachment, err := app.models.Attachments.Get(noteID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	blob, err := app.blobs.Get(r.Context(), attachment.StorageKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer blob.Close()
+
+	err = extendDeadlines(w, attachment.Size, false)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("ETag", `"`+attachment.Checksum+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	_, err = io.Copy(w, blob)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+func (app *application) deleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	id, err := app.readNamedIDParam(r, "attachment_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !app.authorizeNote(w, r, noteID, data.RoleEditor) {
+		return
+	}
+
+	err = app.models.Attachments.Delete(noteID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.background(app.purgeDeletedBlobs)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "attachment successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// purgeDeletedBlobs removes blobs queued for deletion from the blob store.
+// Blobs that fail to delete stay queued and are retried on the next run.
+func (app *application) purgeDeletedBlobs() {
+	keys, err := app.models.Attachments.PendingBlobDeletions(100)
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+
+	for _, key := range keys {
+		err := app.blobs.Delete(context.Background(), key)
+		if err != nil {
+			app.logger.Error(err.Error(), "storage_key", key)
+			continue
+		}
+
+		err = app.models.Attachments.ConfirmBlobDeletion(key)
+		if err != nil {
+			app.logger.Error(err.Error(), "storage_key", key)
+		}
+	}
+}
+
+// extendDeadlines gives a transfer of size bytes time to finish at
+// minTransferRate before the server gives up on writing the response, and on
+// reading the request body too if read is set. Writers that don't support
+// deadlines, such as httptest's recorder, are left as they are.
+func extendDeadlines(w http.ResponseWriter, size int64, read bool) error {
+	rc := http.NewResponseController(w)
+	transfer := time.Duration(size) * time.Second / minTransferRate
+
+	if read {
+		err := rc.SetReadDeadline(time.Now().Add(serverReadTimeout + transfer))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+	}
+
+	err := rc.SetWriteDeadline(time.Now().Add(serverWriteTimeout + transfer))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
+
+// sniffContentType detects the type of an uploaded file from its first 512
+// bytes, ignoring whatever the client claimed, and rewinds it.
+func sniffContentType(f *os.File) (string, error) {
+	_, err := f.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 512)
+
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", err
+	}
+
+	return http.DetectContentType(buf[:n]), nil
+}
+
+func newStorageKey(noteID int64) (string, error) {
+	b := make([]byte, 16)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("notes/%d/%s", noteID, hex.EncodeToString(b)), nil
+}
@@ -0,0 +1,208 @@
+package main_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newUploadRequest(t *testing.T, path, filename string, content []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestUploadAttachmentHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	note := createTestNote(t, app, "Attachment Upload", "Body", []string{"attachments"})
+	path := fmt.Sprintf("/v1/notes/%d/attachments", note.ID)
+
+	tests := []struct {
+		name           string
+		req            *http.Request
+		expectedStatus int
+	}{
+		{
+			name:           "valid upload",
+			req:            newUploadRequest(t, path, "notes.txt", []byte("hello world")),
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "path in filename is stripped",
+			req:            newUploadRequest(t, path, "../../etc/passwd", []byte("hello world")),
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "empty file",
+			req:            newUploadRequest(t, path, "empty.txt", nil),
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "non-existent note",
+			req:            newUploadRequest(t, "/v1/notes/999999/attachments", "notes.txt", []byte("hello")),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "not multipart",
+			req:            httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(`{"file":"x"}`)),
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+
+			router := app.routes()
+			router.ServeHTTP(rr, tt.req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+
+			if tt.expectedStatus == http.StatusCreated {
+				var response struct {
+					Attachment struct {
+						Filename    string `json:"filename"`
+						ContentType string `json:"content_type"`
+					} `json:"attachment"`
+				}
+				if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+					t.Fatal(err)
+				}
+				if response.Attachment.ContentType != "text/plain; charset=utf-8" {
+					t.Errorf("expected sniffed text/plain content type, got %q", response.Attachment.ContentType)
+				}
+				if response.Attachment.Filename == "" || bytes.ContainsRune([]byte(response.Attachment.Filename), '/') {
+					t.Errorf("expected a bare filename, got %q", response.Attachment.Filename)
+				}
+			}
+		})
+	}
+}
+
+func TestAttachmentRoundTrip(t *testing.T) {
+	app := newTestApplication(t)
+
+	note := createTestNote(t, app, "Attachment Round Trip", "Body", []string{"attachments"})
+	content := []byte("<html><script>alert(1)</script></html>")
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, newUploadRequest(t, fmt.Sprintf("/v1/notes/%d/attachments", note.ID), "page.html", content))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, rr.Code)
+	}
+
+	location := rr.Header().Get("Location")
+
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, location, http.NoBody))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	body, _ := io.ReadAll(rr.Body)
+	if !bytes.Equal(body, content) {
+		t.Errorf("expected %q, got %q", content, body)
+	}
+	if got := rr.Header().Get("Content-Disposition"); got != `attachment; filename=page.html` {
+		t.Errorf("expected attachment disposition, got %q", got)
+	}
+	if got := rr.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("expected nosniff, got %q", got)
+	}
+
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, location, http.NoBody))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, location, http.NoBody))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d after delete, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestSlowAttachmentUpload(t *testing.T) {
+	app := newTestApplication(t)
+
+	note := createTestNote(t, app, "Slow Upload", "Body", []string{"attachments"})
+
+	// The server's timeouts are far shorter than the upload takes, as they
+	// would be for a large file on a slow link.
+	srv := httptest.NewUnstartedServer(app.routes())
+	srv.Config.ReadTimeout = 200 * time.Millisecond
+	srv.Config.WriteTimeout = 200 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	go func() {
+		fw, err := mw.CreateFormFile("file", "slow.txt")
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		for i := range 8 {
+			time.Sleep(100 * time.Millisecond)
+
+			if _, err := fmt.Fprintf(fw, "chunk %d\n", i); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+
+		pw.CloseWithError(mw.Close())
+	}()
+
+	resp, err := http.Post(fmt.Sprintf("%s/v1/notes/%d/attachments", srv.URL, note.ID), mw.FormDataContentType(), pr)
+	if err != nil {
+		t.Fatalf("expected the upload to finish, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, resp.StatusCode, body)
+	}
+
+	var input struct {
+		Attachment struct {
+			Size int64 `json:"size"`
+		} `json:"attachment"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&input); err != nil {
+		t.Fatal(err)
+	}
+	if input.Attachment.Size != 64 {
+		t.Errorf("expected all 64 bytes to arrive, got %d", input.Attachment.Size)
+	}
+}
@@ -0,0 +1,152 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Auditor")
+	_, otherToken := createTestUser(t, app, "Outsider")
+
+	rr := serveAs(app, token, http.MethodPost, "/v1/notes", `{"title": "Budget", "body": "Draft", "tags": []}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		Note struct {
+			ID int64 `json:"id"`
+		} `json:"note"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	notePath := fmt.Sprintf("/v1/notes/%d", created.Note.ID)
+
+	mutations := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPut, notePath, `{"title": "Budget", "body": "Final", "tags": [], "version": 1}`},
+		{http.MethodPut, notePath, `{"title": "Budget", "body": "Final", "tags": [], "archived": true, "version": 2}`},
+		{http.MethodPut, notePath, `{"title": "Budget", "body": "Final", "tags": [], "archived": false, "version": 3}`},
+		{http.MethodPut, notePath + "/pin", ""},
+		{http.MethodDelete, notePath, ""},
+	}
+
+	var lastRequestID string
+
+	for _, m := range mutations {
+		req := httptest.NewRequest(m.method, m.path, strings.NewReader(m.body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("User-Agent", "audit-test/1.0")
+
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", m.method, m.path, http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		lastRequestID = rr.Header().Get("X-Request-ID")
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		query          string
+		expectedStatus int
+		expected       []string
+	}{
+		{"anonymous", "", "", http.StatusUnauthorized, nil},
+		{"unknown action", token, "?action=note.read", http.StatusUnprocessableEntity, nil},
+		{"bad time", token, "?since=yesterday", http.StatusUnprocessableEntity, nil},
+		{"note history", token, fmt.Sprintf("?note_id=%d&sort=id", created.Note.ID), http.StatusOK,
+			[]string{"note.create", "note.update", "note.archive", "note.restore", "note.pin", "note.delete"}},
+		{"by action", token, fmt.Sprintf("?note_id=%d&action=note.archive", created.Note.ID), http.StatusOK, []string{"note.archive"}},
+		{"other user", otherToken, fmt.Sprintf("?note_id=%d", created.Note.ID), http.StatusOK, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serveAs(app, tt.token, http.MethodGet, "/v1/audit"+tt.query, "")
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+
+			if tt.expected == nil {
+				return
+			}
+
+			var response struct {
+				Events []struct {
+					Action    string `json:"action"`
+					RequestID string `json:"request_id"`
+					UserAgent string `json:"user_agent"`
+				} `json:"audit_events"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			actions := []string{}
+			for _, event := range response.Events {
+				actions = append(actions, event.Action)
+			}
+
+			if strings.Join(actions, ",") != strings.Join(tt.expected, ",") {
+				t.Fatalf("expected actions %v, got %v", tt.expected, actions)
+			}
+
+			if len(response.Events) == 6 {
+				last := response.Events[5]
+				if last.RequestID != lastRequestID || last.UserAgent != "audit-test/1.0" {
+					t.Errorf("expected the request's ID and user agent, got %+v", last)
+				}
+			}
+		})
+	}
+
+	type verification struct {
+		Valid      bool   `json:"valid"`
+		VerifiedAt string `json:"verified_at"`
+	}
+
+	verify := func(token string) verification {
+		t.Helper()
+
+		rr := serveAs(app, token, http.MethodGet, "/v1/audit/verify", "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d verifying, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		var response struct {
+			Verification verification `json:"verification"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		return response.Verification
+	}
+
+	first := verify(token)
+	if !first.Valid {
+		t.Error("expected the audit log to verify")
+	}
+
+	time.Sleep(time.Second)
+
+	if again := verify(otherToken); again != first {
+		t.Errorf("expected the latest check to be reused, got %+v after %+v", again, first)
+	}
+}
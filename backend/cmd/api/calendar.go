@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/ical"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+// calendarTokenTTL is long enough that a subscribed calendar keeps working
+// until its token is replaced.
+const calendarTokenTTL = 10 * 365 * 24 * time.Hour
+
+// createCalendarTokenHandler issues the secret for the user's calendar feed.
+// Calendar apps can't send an Authorization header, so the token goes in the
+// feed URL instead; issuing a new one revokes the old URL.
+func (app *application) createCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	err := app.models.Tokens.DeleteAllForUser(data.ScopeCalendar, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	token, err := app.models.Tokens.New(user.ID, calendarTokenTTL, data.ScopeCalendar)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	url := fmt.Sprintf("/v1/calendar.ics?token=%s", token.Plaintext)
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"calendar_token": token, "url": url}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showCalendarHandler serves the user's reminders as events and their notes
+// with due dates as to-dos. The ETag is a hash of the feed, which is built
+// only from stored data, so it changes only when that data does.
+func (app *application) showCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, token); !v.Valid() {
+		app.invalidAuthenticationTokenResponse(w, r)
+		return
+	}
+
+	user, err := app.models.Users.WithContext(r.Context()).GetForToken(data.ScopeCalendar, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	reminders, err := app.models.Reminders.WithContext(r.Context()).GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	notes, err := app.notes(r).GetAllDue(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	cal := &ical.Calendar{Name: "Notes"}
+
+	for _, reminder := range reminders {
+		// The rule was validated when the reminder was saved.
+		rec, _ := data.ParseRecurrence(reminder.Recurrence)
+
+		cal.Events = append(cal.Events, ical.Event{
+			UID:     fmt.Sprintf("reminder-%d@notes", reminder.ID),
+			Stamp:   reminder.CreatedAt,
+			Start:   reminder.FireAt,
+			Summary: reminder.NoteTitle,
+			RRule:   rec.String(),
+			Alarm:   true,
+		})
+	}
+
+	for _, note := range notes {
+		cal.Todos = append(cal.Todos, ical.Todo{
+			UID:         fmt.Sprintf("note-%d@notes", note.ID),
+			Stamp:       note.UpdatedAt,
+			Due:         *note.DueAt,
+			Summary:     note.Title,
+			Description: note.Body,
+			Categories:  note.Tags,
+			Completed:   note.Archived,
+		})
+	}
+
+	body := cal.Encode()
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	// Keep the token in the URL out of Referer headers and shared caches.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(body)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
@@ -0,0 +1,109 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCalendarFeed(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Scheduler")
+
+	rr := serveAs(app, token, http.MethodPost, "/v1/notes", `{"title": "File taxes", "body": "Receipts, forms", "tags": ["money"], "due_at": "2999-04-15T17:00:00Z"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		Note struct {
+			ID int64 `json:"id"`
+		} `json:"note"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	rr = serveAs(app, token, http.MethodPost, fmt.Sprintf("/v1/notes/%d/reminders", created.Note.ID), `{"fire_at": "2999-04-01T09:00:00Z", "recurrence": "FREQ=WEEKLY", "channel": "email"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating reminder, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	newFeedURL := func() string {
+		rr := serveAs(app, token, http.MethodPost, "/v1/tokens/calendar", "")
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d creating calendar token, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+
+		var response struct {
+			URL string `json:"url"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		return response.URL
+	}
+
+	getFeed := func(url, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	url := newFeedURL()
+
+	rr = getFeed(url, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	if ct := rr.Header().Get("Content-Type"); ct != "text/calendar; charset=utf-8" {
+		t.Errorf("expected a calendar content type, got %q", ct)
+	}
+
+	for _, line := range []string{
+		"DTSTART:29990401T090000Z",
+		"RRULE:FREQ=WEEKLY;INTERVAL=1",
+		"SUMMARY:File taxes",
+		"DUE:29990415T170000Z",
+		`DESCRIPTION:Receipts\, forms`,
+	} {
+		if !strings.Contains(rr.Body.String(), "\r\n"+line+"\r\n") {
+			t.Errorf("expected the feed to contain %q, got:\n%s", line, rr.Body.String())
+		}
+	}
+
+	etag := rr.Header().Get("ETag")
+
+	if rr := getFeed(url, etag); rr.Code != http.StatusNotModified {
+		t.Errorf("expected status %d for an unchanged feed, got %d", http.StatusNotModified, rr.Code)
+	}
+
+	rr = serveAs(app, token, http.MethodPut, fmt.Sprintf("/v1/notes/%d", created.Note.ID), `{"title": "File taxes", "body": "Receipts, forms", "tags": ["money"], "due_at": "2999-04-30T17:00:00Z", "version": 1}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d updating note, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	if rr := getFeed(url, etag); rr.Code != http.StatusOK || rr.Header().Get("ETag") == etag {
+		t.Errorf("expected a new ETag after the note changed, got status %d and ETag %q", rr.Code, rr.Header().Get("ETag"))
+	}
+
+	newFeedURL()
+
+	if rr := getFeed(url, ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d for a revoked token, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	if rr := getFeed("/v1/calendar.ics", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d without a token, got %d", http.StatusUnauthorized, rr.Code)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+// authorizeNote checks that the current user holds at least the required
+// role on a note and writes an error response if not, returning false.
+// Notes without an owner predate collaboration and stay open to everyone.
+// Owned notes are hidden from anyone who isn't a collaborator.
+func (app *application) authorizeNote(w http.ResponseWriter, r *http.Request, noteID int64, required string) bool {
+	user := app.contextGetUser(r)
+
+	role, owned, err := app.models.Collaborators.GetRole(noteID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return false
+	}
+
+	switch {
+	case !owned:
+		return true
+	case role == "" && user.IsAnonymous():
+		app.authenticationRequiredResponse(w, r)
+		return false
+	case role == "":
+		app.notFoundResponse(w, r)
+		return false
+	case !data.RoleAllows(role, required):
+		app.notPermittedResponse(w, r)
+		return false
+	}
+
+	return true
+}
+
+func (app *application) listCollaboratorsHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !app.authorizeNote(w, r, noteID, data.RoleViewer) {
+		return
+	}
+
+	collaborators, err := app.models.Collaborators.GetAllForNote(noteID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"collaborators": collaborators}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) inviteCollaboratorHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	role, owned, err := app.models.Collaborators.GetRole(noteID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	switch {
+	case !owned:
+		app.errorResponse(w, r, http.StatusForbidden, "only notes with an owner can have collaborators")
+		return
+	case role == "":
+		app.notFoundResponse(w, r)
+		return
+	case role != data.RoleOwner:
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateCollaborator(v, input.Email, input.Role); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	invitee, err := app.models.Users.WithContext(r.Context()).GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("email", "no user with this email address exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	collaborator := &data.Collaborator{
+		NoteID: noteID,
+		UserID: invitee.ID,
+		Name:   invitee.Name,
+		Email:  invitee.Email,
+		Role:   input.Role,
+	}
+
+	err = app.models.Collaborators.Invite(collaborator, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCollaborator):
+			v.AddError("email", "this user is already a collaborator on the note")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/notes/%d/collaborators", noteID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"collaborator": collaborator}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) acceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	collaborator, err := app.models.Collaborators.Accept(noteID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	collaborator.Name = user.Name
+	collaborator.Email = user.Email
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"collaborator": collaborator}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// removeCollaboratorHandler lets the owner remove a collaborator or withdraw
+// an invitation, and lets collaborators leave or decline on their own behalf.
+func (app *application) removeCollaboratorHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	userID, err := app.readNamedIDParam(r, "user_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	if userID != user.ID && !app.authorizeNote(w, r, noteID, data.RoleOwner) {
+		return
+	}
+
+	err = app.models.Collaborators.Remove(noteID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "collaborator successfully removed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,115 @@
+package main_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serveAs(app *testApp, token, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestCollaboration(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, ownerToken := createTestUser(t, app, "Owner")
+	viewer, viewerToken := createTestUser(t, app, "Viewer")
+	editor, editorToken := createTestUser(t, app, "Editor")
+	_, strangerToken := createTestUser(t, app, "Stranger")
+
+	rr := serveAs(app, ownerToken, http.MethodPost, "/v1/notes", `{"title": "Team Note", "body": "Body", "tags": ["team"]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating note, got %d", http.StatusCreated, rr.Code)
+	}
+
+	var created struct {
+		Note struct {
+			ID int64 `json:"id"`
+		} `json:"note"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	notePath := fmt.Sprintf("/v1/notes/%d", created.Note.ID)
+	collaboratorsPath := notePath + "/collaborators"
+
+	for _, invite := range []struct{ email, role string }{{viewer.Email, "viewer"}, {editor.Email, "editor"}} {
+		rr = serveAs(app, ownerToken, http.MethodPost, collaboratorsPath, fmt.Sprintf(`{"email": %q, "role": %q}`, invite.email, invite.role))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d inviting %s, got %d: %s", http.StatusCreated, invite.role, rr.Code, rr.Body.String())
+		}
+	}
+
+	update := `{"title": "Team Note", "body": "Edited", "tags": ["team"]}`
+
+	tests := []struct {
+		name           string
+		token          string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"anonymous read", "", http.MethodGet, notePath, "", http.StatusUnauthorized},
+		{"stranger read", strangerToken, http.MethodGet, notePath, "", http.StatusNotFound},
+		{"pending viewer read", viewerToken, http.MethodGet, notePath, "", http.StatusNotFound},
+		{"viewer accepts", viewerToken, http.MethodPost, collaboratorsPath + "/accept", "", http.StatusOK},
+		{"editor accepts", editorToken, http.MethodPost, collaboratorsPath + "/accept", "", http.StatusOK},
+		{"accepting twice", editorToken, http.MethodPost, collaboratorsPath + "/accept", "", http.StatusNotFound},
+		{"viewer read", viewerToken, http.MethodGet, notePath, "", http.StatusOK},
+		{"viewer update", viewerToken, http.MethodPut, notePath, update, http.StatusForbidden},
+		{"viewer delete", viewerToken, http.MethodDelete, notePath, "", http.StatusForbidden},
+		{"viewer invites", viewerToken, http.MethodPost, collaboratorsPath, `{"email": "x@example.com", "role": "viewer"}`, http.StatusForbidden},
+		{"editor update", editorToken, http.MethodPut, notePath, update, http.StatusOK},
+		{"editor delete", editorToken, http.MethodDelete, notePath, "", http.StatusForbidden},
+		{"duplicate invite", ownerToken, http.MethodPost, collaboratorsPath, fmt.Sprintf(`{"email": %q, "role": "editor"}`, viewer.Email), http.StatusUnprocessableEntity},
+		{"inviting as owner", ownerToken, http.MethodPost, collaboratorsPath, fmt.Sprintf(`{"email": %q, "role": "owner"}`, viewer.Email), http.StatusUnprocessableEntity},
+		{"viewer removes editor", viewerToken, http.MethodDelete, fmt.Sprintf("%s/%d", collaboratorsPath, editor.ID), "", http.StatusForbidden},
+		{"viewer leaves", viewerToken, http.MethodDelete, fmt.Sprintf("%s/%d", collaboratorsPath, viewer.ID), "", http.StatusOK},
+		{"former viewer read", viewerToken, http.MethodGet, notePath, "", http.StatusNotFound},
+		{"owner removes editor", ownerToken, http.MethodDelete, fmt.Sprintf("%s/%d", collaboratorsPath, editor.ID), "", http.StatusOK},
+		{"owner delete", ownerToken, http.MethodDelete, notePath, "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		rr := serveAs(app, tt.token, tt.method, tt.path, tt.body)
+
+		if rr.Code != tt.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.name, tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestInviteCollaboratorToUnownedNote(t *testing.T) {
+	app := newTestApplication(t)
+
+	note := createTestNote(t, app, "Unowned Note", "Body", []string{"team"})
+	_, token := createTestUser(t, app, "Claimant")
+	invitee, _ := createTestUser(t, app, "Invitee")
+
+	path := fmt.Sprintf("/v1/notes/%d/collaborators", note.ID)
+	body := fmt.Sprintf(`{"email": %q, "role": "viewer"}`, invitee.Email)
+
+	rr := serveAs(app, "", http.MethodPost, path, body)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d for anonymous invite, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	rr = serveAs(app, token, http.MethodPost, path, body)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status %d for invite to unowned note, got %d", http.StatusForbidden, rr.Code)
+	}
+}
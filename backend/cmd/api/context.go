@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+)
+
+type contextKey string
+
+const (
+	userContextKey      = contextKey("user")
+	requestIDContextKey = contextKey("request_id")
+	routeContextKey     = contextKey("route")
+)
+
+func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	return r.WithContext(ctx)
+}
+
+func (app *application) contextGetUser(r *http.Request) *data.User {
+	user, ok := r.Context().Value(userContextKey).(*data.User)
+	if !ok {
+		panic("missing user value in request context")
+	}
+
+	return user
+}
+
+func (app *application) contextSetRequestID(r *http.Request, requestID string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, requestID)
+	return r.WithContext(ctx)
+}
+
+// contextGetRequestID returns the request's ID, or an empty string for
+// requests that didn't pass through the requestID middleware.
+func (app *application) contextGetRequestID(r *http.Request) string {
+	requestID, _ := r.Context().Value(requestIDContextKey).(string)
+	return requestID
+}
+
+func (app *application) contextSetRoute(r *http.Request, route string) *http.Request {
+	ctx := context.WithValue(r.Context(), routeContextKey, route)
+	return r.WithContext(ctx)
+}
+
+// contextGetRoute returns the pattern of the route serving the request. For
+// requests that didn't pass through logRequest it falls back on the pattern
+// the mux matched, if any, and otherwise on unmatchedRoute.
+func (app *application) contextGetRoute(r *http.Request) string {
+	if route, ok := r.Context().Value(routeContextKey).(string); ok {
+		return route
+	}
+
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+
+	return unmatchedRoute
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+// showDailyNoteHandler returns the current user's daily note for a date,
+// creating it from their template the first time it's asked for. The date
+// may also be "today", which is worked out in the user's time zone.
+func (app *application) showDailyNoteHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	date := r.PathValue("date")
+
+	var day time.Time
+
+	if date == "today" {
+		day = time.Now().In(user.Location())
+	} else {
+		var err error
+
+		day, err = time.Parse(time.DateOnly, date)
+
+		v := validator.New()
+
+		if v.Check(err == nil, "date", "must be a date in YYYY-MM-DD format or today"); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	note, created, err := app.notes(r).GetOrCreateDaily(user, day)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if created {
+		app.indexNote(note)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"note": note, "created": created}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
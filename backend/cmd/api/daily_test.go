@@ -0,0 +1,76 @@
+package main_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDailyNoteHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Diarist")
+
+	rr := serveAs(app, token, http.MethodPatch, "/v1/users/me", `{"time_zone": "Pacific/Kiritimati", "daily_template": "## {{date}}\n"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d updating settings, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	type response struct {
+		Note struct {
+			ID    int64    `json:"id"`
+			Title string   `json:"title"`
+			Body  string   `json:"body"`
+			Tags  []string `json:"tags"`
+		} `json:"note"`
+		Created bool `json:"created"`
+	}
+
+	getDaily := func(date string, expectedStatus int) response {
+		rr := serveAs(app, token, http.MethodGet, "/v1/notes/daily/"+date, "")
+		if rr.Code != expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", date, expectedStatus, rr.Code, rr.Body.String())
+		}
+
+		var res response
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	first := getDaily("2024-02-29", http.StatusOK)
+	if !first.Created || first.Note.Title != "2024-02-29" || first.Note.Body != "## 2024-02-29\n" || len(first.Note.Tags) != 1 || first.Note.Tags[0] != "daily" {
+		t.Errorf("expected a new daily note from the template, got %+v", first)
+	}
+
+	again := getDaily("2024-02-29", http.StatusOK)
+	if again.Created || again.Note.ID != first.Note.ID {
+		t.Errorf("expected the same note back, got %+v", again)
+	}
+
+	loc, err := time.LoadLocation("Pacific/Kiritimati")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if today := getDaily("today", http.StatusOK); today.Note.Title != time.Now().In(loc).Format(time.DateOnly) {
+		t.Errorf("expected today in the user's time zone, got %q", today.Note.Title)
+	}
+
+	// "related" is also a sub-route of /v1/notes/{id}; the daily route wins.
+	for _, date := range []string{"2023-02-29", "yesterday", "2024-1-01", "related"} {
+		getDaily(date, http.StatusUnprocessableEntity)
+	}
+
+	rr = serveAs(app, token, http.MethodPatch, "/v1/users/me", `{"time_zone": "Nowhere/Special"}`)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d for a bad time zone, got %d", http.StatusUnprocessableEntity, rr.Code)
+	}
+
+	rr = serveAs(app, "", http.MethodGet, "/v1/notes/daily/today", "")
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d for an anonymous user, got %d", http.StatusUnauthorized, rr.Code)
+	}
+}
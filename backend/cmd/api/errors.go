@@ -3,19 +3,36 @@ package main
 import (
 	"fmt"
 	"net/http"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
 )
 
+// logError logs an error along with the route pattern of the request it
+// arose from, as found by logRequest, so that errors raised by middleware
+// ahead of the routes are logged with one too. As with logRequest, the URI
+// is left out so that tokens in it aren't logged.
 func (app *application) logError(r *http.Request, err error) {
 	var (
 		method = r.Method
-		uri    = r.URL.RequestURI()
+		route  = app.contextGetRoute(r)
 	)
 
-	app.logger.Error(err.Error(), "method", method, "uri", uri)
+	app.logger.ErrorContext(r.Context(), err.Error(), "method", method, "route", route)
 }
 
+// errorResponse sends an error message along with the request's ID, which
+// the client can quote when reporting the problem.
 func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
-	env := envelope{"error": message}
+	app.errorEnvelopeResponse(w, r, status, envelope{"error": message})
+}
+
+// errorEnvelopeResponse sends env, which holds an error message along with
+// any details of the error, with the request's ID added as errorResponse
+// does.
+func (app *application) errorEnvelopeResponse(w http.ResponseWriter, r *http.Request, status int, env envelope) {
+	if requestID := app.contextGetRequestID(r); requestID != "" {
+		env["request_id"] = requestID
+	}
 
 	err := app.writeJSON(w, status, env, nil)
 	if err != nil {
@@ -48,3 +65,55 @@ func (app *application) methodNotAllowedResponse(w http.ResponseWriter, r *http.
 func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
 	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
 }
+
+func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request, conflict *data.MergeConflict) {
+	env := envelope{
+		"error":    "unable to merge your edit with changes made since, please resolve the conflicts and try again",
+		"conflict": conflict,
+	}
+
+	app.errorEnvelopeResponse(w, r, http.StatusConflict, env)
+}
+
+func (app *application) taskChangedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the task has changed since it was read, please fetch it again and retry"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) sharePasswordRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="shared note", charset="UTF-8"`)
+
+	message := "a valid password is required to view this note"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) duplicateEmailResponse(w http.ResponseWriter, r *http.Request) {
+	app.failedValidationResponse(w, r, map[string]string{"email": "a user with this email address already exists"})
+}
+
+func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid authentication credentials"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "invalid or missing authentication token"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "you must be authenticated to access this resource"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) versionConflictResponse(w http.ResponseWriter, r *http.Request) {
+	message := "unable to update the record due to an edit conflict, please try again"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
@@ -73,7 +73,7 @@ func TestMethodNotAllowedResponse(t *testing.T) {
 		{
 			name:           "GET to POST-only endpoint",
 			method:         http.MethodGet,
-			path:           "/v1/notes",
+			path:           "/v1/users",
 			expectedStatus: http.StatusMethodNotAllowed,
 		},
 		{
@@ -1,19 +1,38 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json" // New import
 	"errors"
 	"fmt"
+	"html/template"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/julienschmidt/httprouter"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
 )
 
 type envelope map[string]any
 
+var htmlDocument = template.Must(template.New("document").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<article>
+{{.Body}}
+</article>
+</body>
+</html>
+`))
+
 func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
 	js, err := json.MarshalIndent(data, "", "\t")
 	if err != nil {
@@ -39,18 +58,64 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 	return nil
 }
 
+// writeHTML sends already-sanitized HTML wrapped in a minimal document. The
+// content security policy stops any script from running should something
+// slip past sanitization.
+func (app *application) writeHTML(w http.ResponseWriter, status int, title string, body string, headers http.Header) error {
+	var buf bytes.Buffer
+
+	err := htmlDocument.Execute(&buf, map[string]any{
+		"Title": title,
+		"Body":  template.HTML(body), //nolint:gosec // body has been sanitized by the markdown renderer
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, values := range headers {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src https: data:; style-src 'unsafe-inline'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_, err = w.Write(buf.Bytes())
+
+	return err
+}
+
+// wantsHTML reports whether the client asked for HTML rather than JSON.
+func (app *application) wantsHTML(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/html") && !strings.Contains(accept, "application/json")
+}
+
 func (app *application) readIDParam(r *http.Request) (int64, error) {
-	params := httprouter.ParamsFromContext(r.Context())
+	return app.readNamedIDParam(r, "id")
+}
 
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+func (app *application) readNamedIDParam(r *http.Request, name string) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
 	if err != nil || id < 1 {
-		return 0, errors.New("invalid id parameter")
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
 
 	return id, nil
 }
 
+// notes returns the note model with its queries traced as part of r, and
+// its changes audited as made by r's user.
+func (app *application) notes(r *http.Request) data.NoteModel {
+	return app.models.Notes.WithContext(app.auditContext(r))
+}
+
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	_, span := app.tracer.Start(r.Context(), "json.decode")
+	defer span.End()
+
 	r.Body = http.MaxBytesReader(w, r.Body, 1_048_576)
 
 	dec := json.NewDecoder(r.Body)
@@ -101,3 +166,88 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 
 	return nil
 }
+
+func (app *application) readString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	return s
+}
+
+func (app *application) readCSV(qs url.Values, key string, defaultValue []string) []string {
+	csv := qs.Get(key)
+
+	if csv == "" {
+		return defaultValue
+	}
+
+	return strings.Split(csv, ",")
+}
+
+// readBool returns nil when the key is absent so that callers can tell "not
+// filtered" apart from false.
+func (app *application) readBool(qs url.Values, key string, v *validator.Validator) *bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return nil
+	}
+
+	return &b
+}
+
+func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+
+	return i
+}
+
+// readTime reads an RFC 3339 timestamp, returning nil when the key is
+// absent.
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.AddError(key, "must be an RFC 3339 timestamp")
+		return nil
+	}
+
+	return &t
+}
+
+func (app *application) background(fn func()) {
+	go func() {
+		defer func() {
+			pv := recover()
+			if pv != nil {
+				app.logger.Error(fmt.Sprintf("%v", pv))
+			}
+		}()
+
+		fn()
+	}()
+}
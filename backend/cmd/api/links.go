@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+)
+
+func (app *application) showBacklinksHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !app.authorizeNote(w, r, id, data.RoleViewer) {
+		return
+	}
+
+	backlinks, err := app.models.Links.GetBacklinks(id, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"backlinks": backlinks}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showOutgoingLinksHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !app.authorizeNote(w, r, id, data.RoleViewer) {
+		return
+	}
+
+	links, err := app.models.Links.GetOutgoing(id, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"links": links}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listDanglingLinksHandler(w http.ResponseWriter, r *http.Request) {
+	links, err := app.models.Links.GetDangling(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"links": links}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,107 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+)
+
+func TestLinkHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	target := createTestNote(t, app, "Handler Link Target", "Body", []string{"links"})
+	source := createTestNote(t, app, "Handler Link Source", "See [[Handler Link Target]] and [[Nowhere]]", []string{"links"})
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{
+			name:           "backlinks",
+			path:           fmt.Sprintf("/v1/notes/%d/backlinks", target.ID),
+			expectedStatus: http.StatusOK,
+			expectedCount:  1,
+		},
+		{
+			name:           "outgoing links",
+			path:           fmt.Sprintf("/v1/notes/%d/outgoing-links", source.ID),
+			expectedStatus: http.StatusOK,
+			expectedCount:  2,
+		},
+		{
+			name:           "backlinks of non-existent note",
+			path:           "/v1/notes/999999/backlinks",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "outgoing links of invalid id",
+			path:           "/v1/notes/0/outgoing-links",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, http.NoBody)
+			rr := httptest.NewRecorder()
+
+			router := app.routes()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				var response map[string][]json.RawMessage
+				if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+					t.Fatal(err)
+				}
+				for _, items := range response {
+					if len(items) != tt.expectedCount {
+						t.Errorf("expected %d entries, got %d", tt.expectedCount, len(items))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestListDanglingLinksHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	source := createTestNote(t, app, "Dangling Source", "See [[A Note Nobody Wrote]]", []string{"links"})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/links/dangling", http.NoBody)
+	rr := httptest.NewRecorder()
+
+	router := app.routes()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response struct {
+		Links []data.DanglingLink `json:"links"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, link := range response.Links {
+		if link.SourceID == source.ID && link.Text == "A Note Nobody Wrote" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected dangling link from note %d", source.ID)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// contextHandler adds the ID of the request being served, if any, to every
+// record logged with a request's context, so that all of a request's log
+// lines can be picked out together.
+type contextHandler struct {
+	slog.Handler
+}
+
+func newContextHandler(h slog.Handler) slog.Handler {
+	if _, ok := h.(contextHandler); ok {
+		return h
+	}
+
+	return contextHandler{Handler: h}
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID, ok := ctx.Value(requestIDContextKey).(string); ok && requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// recordingResponseWriter records the status code and size of a response for
+// the access log and metrics.
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (lw *recordingResponseWriter) WriteHeader(status int) {
+	if !lw.wroteHeader {
+		lw.status = status
+		lw.wroteHeader = true
+	}
+
+	lw.ResponseWriter.WriteHeader(status)
+}
+
+func (lw *recordingResponseWriter) Write(b []byte) (int, error) {
+	if !lw.wroteHeader {
+		lw.WriteHeader(http.StatusOK)
+	}
+
+	n, err := lw.ResponseWriter.Write(b)
+	lw.bytes += n
+
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (lw *recordingResponseWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
@@ -8,15 +8,37 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/markdown"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/notify"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/related"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/storage"
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const version = "1.0.0"
 
+const defaultAttachmentMaxBytes = 10 << 20
+
+const defaultSimilarityThreshold = 0.3
+
+const defaultReminderPollInterval = 30 * time.Second
+
+const defaultWebhookPollInterval = 5 * time.Second
+
+// The API server's read and write timeouts. Attachment transfers extend them
+// to suit the size of the file.
+const (
+	serverReadTimeout  = 5 * time.Second
+	serverWriteTimeout = 10 * time.Second
+)
+
 type config struct {
 	port int
 	env  string
@@ -29,39 +51,99 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	attachments struct {
+		maxBytes int64
+		store    string
+		dir      string
+		s3       storage.S3Config
+	}
+	search struct {
+		similarityThreshold float64
+	}
+	reminders struct {
+		pollInterval time.Duration
+		smtp         notify.SMTPConfig
+	}
+	webhooks struct {
+		pollInterval time.Duration
+	}
+	metrics struct {
+		port int
+	}
+	tracing struct {
+		exporter    string
+		endpoint    string
+		sampleRatio float64
+	}
 }
 
 type AppInterface interface {
 	GetRoutes() http.Handler
+	GetMetricsRoutes() http.Handler
 	GetModels() data.Models
 }
 
 type application struct {
-	config config
-	logger *slog.Logger
-	models data.Models
+	config   config
+	logger   *slog.Logger
+	models   data.Models
+	renderer *markdown.Renderer
+	blobs    storage.BlobStore
+	related  *related.Index
+	mailer   *notify.Mailer
+	webhook  *notify.Webhook
+	metrics  *appMetrics
+	tracer   trace.Tracer
+
+	auditVerifier auditVerifier
 }
 
 func (app *application) GetRoutes() http.Handler {
 	return app.routes()
 }
 
+func (app *application) GetMetricsRoutes() http.Handler {
+	return app.metricsRoutes()
+}
+
 func (app *application) GetModels() data.Models {
 	return app.models
 }
 
 func NewApplication(db *sql.DB, logger *slog.Logger, env string, trustedOrigins []string) AppInterface {
+	var cfg config
+
+	cfg.env = env
+	cfg.cors.trustedOrigins = trustedOrigins
+	cfg.attachments.maxBytes = defaultAttachmentMaxBytes
+	cfg.search.similarityThreshold = defaultSimilarityThreshold
+	cfg.reminders.pollInterval = defaultReminderPollInterval
+	cfg.webhooks.pollInterval = defaultWebhookPollInterval
+
+	blobs := storage.NewLocalStore(filepath.Join(os.TempDir(), "notes-attachments"))
+
+	app := newApplication(cfg, db, logger, blobs)
+
+	err := app.loadRelatedIndex()
+	if err != nil {
+		logger.Error(err.Error())
+	}
+
+	return app
+}
+
+func newApplication(cfg config, db *sql.DB, logger *slog.Logger, blobs storage.BlobStore) *application {
 	return &application{
-		config: config{
-			env: env,
-			cors: struct {
-				trustedOrigins []string
-			}{
-				trustedOrigins: trustedOrigins,
-			},
-		},
-		logger: logger,
-		models: data.NewModels(db),
+		config:   cfg,
+		logger:   slog.New(newContextHandler(logger.Handler())),
+		models:   data.NewModels(db),
+		renderer: markdown.NewRenderer(1000),
+		blobs:    blobs,
+		related:  related.NewIndex(),
+		mailer:   notify.NewMailer(cfg.reminders.smtp),
+		webhook:  notify.NewWebhook(nil),
+		metrics:  newAppMetrics(db),
+		tracer:   otel.Tracer(tracerName),
 	}
 }
 
@@ -81,10 +163,72 @@ func main() {
 		return nil
 	})
 
+	flag.Int64Var(&cfg.attachments.maxBytes, "attachments-max-bytes", defaultAttachmentMaxBytes, "Maximum attachment upload size in bytes")
+	flag.StringVar(&cfg.attachments.store, "blob-store", "local", "Attachment blob store (local|s3)")
+	flag.StringVar(&cfg.attachments.dir, "blob-dir", "./attachments", "Directory for the local blob store")
+	flag.StringVar(&cfg.attachments.s3.Endpoint, "s3-endpoint", "", "S3-compatible endpoint URL")
+	flag.StringVar(&cfg.attachments.s3.Region, "s3-region", "us-east-1", "S3 region")
+	flag.StringVar(&cfg.attachments.s3.Bucket, "s3-bucket", "", "S3 bucket for attachments")
+	flag.StringVar(&cfg.attachments.s3.AccessKey, "s3-access-key", os.Getenv("NOTES_S3_ACCESS_KEY"), "S3 access key")
+	flag.StringVar(&cfg.attachments.s3.SecretKey, "s3-secret-key", os.Getenv("NOTES_S3_SECRET_KEY"), "S3 secret key")
+
+	flag.Float64Var(&cfg.search.similarityThreshold, "search-similarity-threshold", defaultSimilarityThreshold, "Minimum title similarity (0-1) for search suggestions")
+
+	flag.DurationVar(&cfg.reminders.pollInterval, "reminder-poll-interval", defaultReminderPollInterval, "How often to check for due reminders")
+	flag.StringVar(&cfg.reminders.smtp.Host, "smtp-host", "", "SMTP host for reminder emails")
+	flag.IntVar(&cfg.reminders.smtp.Port, "smtp-port", 25, "SMTP port")
+	flag.StringVar(&cfg.reminders.smtp.Username, "smtp-username", "", "SMTP username")
+	flag.StringVar(&cfg.reminders.smtp.Password, "smtp-password", os.Getenv("NOTES_SMTP_PASSWORD"), "SMTP password")
+	flag.StringVar(&cfg.reminders.smtp.Sender, "smtp-sender", "Notes <no-reply@notes.local>", "SMTP sender")
+
+	flag.DurationVar(&cfg.webhooks.pollInterval, "webhook-poll-interval", defaultWebhookPollInterval, "How often to check for due webhook deliveries")
+
+	flag.IntVar(&cfg.metrics.port, "metrics-port", 0, "Port to serve /metrics on instead of the API port (0 serves it on the API port)")
+
+	flag.StringVar(&cfg.tracing.exporter, "trace-exporter", "none", "Where to export traces (none|stdout|otlp)")
+	flag.StringVar(&cfg.tracing.endpoint, "otlp-endpoint", "http://localhost:4318", "OTLP/HTTP collector URL for the otlp trace exporter")
+	flag.Float64Var(&cfg.tracing.sampleRatio, "trace-sample-ratio", 1, "Fraction (0-1) of new traces to sample")
+
 	flag.Parse()
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
+	if cfg.search.similarityThreshold < 0 || cfg.search.similarityThreshold > 1 {
+		logger.Error("search-similarity-threshold must be between 0 and 1")
+		os.Exit(1)
+	}
+
+	if cfg.reminders.pollInterval <= 0 {
+		logger.Error("reminder-poll-interval must be positive")
+		os.Exit(1)
+	}
+
+	if cfg.webhooks.pollInterval <= 0 {
+		logger.Error("webhook-poll-interval must be positive")
+		os.Exit(1)
+	}
+
+	if cfg.metrics.port < 0 {
+		logger.Error("metrics-port must not be negative")
+		os.Exit(1)
+	}
+
+	// Metrics asked for on the API's own port stay on the API's listener.
+	if cfg.metrics.port == cfg.port {
+		cfg.metrics.port = 0
+	}
+
+	if cfg.tracing.sampleRatio < 0 || cfg.tracing.sampleRatio > 1 {
+		logger.Error("trace-sample-ratio must be between 0 and 1")
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := setupTracing(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	db, err := openDB(&cfg)
 	if err != nil {
 		logger.Error(err.Error())
@@ -93,20 +237,50 @@ func main() {
 
 	logger.Info("database connection pool established")
 
-	app := NewApplication(db, logger, cfg.env, cfg.cors.trustedOrigins)
+	blobs, err := openBlobStore(&cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		db.Close()
+		os.Exit(1)
+	}
+
+	app := newApplication(cfg, db, logger, blobs)
+
+	err = app.loadRelatedIndex()
+	if err != nil {
+		logger.Error(err.Error())
+		db.Close()
+		os.Exit(1)
+	}
+
+	app.background(app.purgeDeletedBlobs)
+	app.background(app.runReminderScheduler)
+	app.background(app.runWebhookWorker)
+
+	if cfg.metrics.port != 0 {
+		app.background(app.serveMetrics)
+	}
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.port),
-		Handler:      app.GetRoutes(),
+		Handler:      app.routes(),
 		IdleTimeout:  time.Minute,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		ReadTimeout:  serverReadTimeout,
+		WriteTimeout: serverWriteTimeout,
 		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
 	}
 
 	logger.Info("starting server", "addr", srv.Addr, "env", cfg.env)
 
 	err = srv.ListenAndServe()
+
+	// Flush spans still waiting in the batcher before exiting.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if shutdownErr := shutdownTracing(ctx); shutdownErr != nil {
+		logger.Error(shutdownErr.Error())
+	}
+	cancel()
+
 	if err != nil {
 		logger.Error(err.Error())
 		db.Close()
@@ -137,3 +311,14 @@ func openDB(cfg *config) (*sql.DB, error) {
 
 	return db, nil
 }
+
+func openBlobStore(cfg *config) (storage.BlobStore, error) {
+	switch cfg.attachments.store {
+	case "local":
+		return storage.NewLocalStore(cfg.attachments.dir), nil
+	case "s3":
+		return storage.NewS3Store(cfg.attachments.s3, nil)
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", cfg.attachments.store)
+	}
+}
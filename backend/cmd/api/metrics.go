@@ -0,0 +1,117 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests that matched no route, so that probing
+// random paths doesn't create a series per path.
+const unmatchedRoute = "unmatched"
+
+type appMetrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	panics   prometheus.Counter
+}
+
+// newAppMetrics registers the API's metrics, including the statistics of
+// the database connection pool if there is one.
+func newAppMetrics(db *sql.DB) *appMetrics {
+	reg := prometheus.NewRegistry()
+	factory := promauto.With(reg)
+
+	m := &appMetrics{
+		registry: reg,
+		requests: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests served.",
+		}, []string{"route", "status"}),
+		duration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "status"}),
+		inFlight: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests being served.",
+		}),
+		panics: factory.NewCounter(prometheus.CounterOpts{
+			Name: "http_panics_recovered_total",
+			Help: "Number of panics recovered while serving HTTP requests.",
+		}),
+	}
+
+	// The sql.DBStats of the pool set up by openDB, as go_sql_* metrics.
+	if db != nil {
+		reg.MustRegister(collectors.NewDBStatsCollector(db, "notes"))
+	}
+
+	return m
+}
+
+// metricsHandler serves every metric in the Prometheus exposition format.
+func (app *application) metricsHandler() http.Handler {
+	return promhttp.HandlerFor(app.metrics.registry, promhttp.HandlerOpts{
+		ErrorLog: slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+	})
+}
+
+// metricsRoutes serves /metrics alone, for when it has been moved off the
+// API onto a port of its own.
+func (app *application) metricsRoutes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", app.metricsHandler())
+
+	return mux
+}
+
+// instrument counts and times each request, labelled by the pattern of the
+// route that served it as given by route and by its status code.
+func (app *application) instrument(route func(*http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		pattern := route(r)
+
+		app.metrics.inFlight.Inc()
+		defer app.metrics.inFlight.Dec()
+
+		rw := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rw, r)
+
+		status := strconv.Itoa(rw.status)
+
+		app.metrics.requests.WithLabelValues(pattern, status).Inc()
+		app.metrics.duration.WithLabelValues(pattern, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// serveMetrics serves /metrics on the metrics port, if one is set.
+func (app *application) serveMetrics() {
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", app.config.metrics.port),
+		Handler:      app.metricsRoutes(),
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	app.logger.Info("starting metrics server", "addr", srv.Addr)
+
+	err := srv.ListenAndServe()
+	if err != nil {
+		app.logger.Error(err.Error())
+	}
+}
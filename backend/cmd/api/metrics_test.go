@@ -0,0 +1,64 @@
+package main_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Operator")
+
+	serveAs(app, "", http.MethodGet, "/v1/healthcheck", "")
+	serveAs(app, "", http.MethodGet, "/v1/healthcheck", "")
+	serveAs(app, token, http.MethodGet, "/v1/notes/123456789", "")
+	serveAs(app, "", http.MethodGet, "/v1/no-such-route/abc", "")
+	serveAs(app, "", http.MethodDelete, "/v1/notes/daily/2026-01-01", "")
+
+	rr := httptest.NewRecorder()
+	app.GetMetricsRoutes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected metrics to be servable on a port of their own, got status %d", rr.Code)
+	}
+
+	rr = serveAs(app, "", http.MethodGet, "/metrics", "")
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("expected the Prometheus text format, got %q", ct)
+	}
+
+	body := rr.Body.String()
+
+	expected := []string{
+		`http_requests_total{route="GET /v1/healthcheck",status="200"} 2`,
+		`http_requests_total{route="GET /v1/notes/{id}",status="404"} 1`,
+		`http_requests_total{route="unmatched",status="404"} 2`,
+		`http_requests_total{route="/v1/notes/daily/{date}",status="405"} 1`,
+		`http_request_duration_seconds_bucket{route="GET /v1/healthcheck",status="200",le="+Inf"} 2`,
+		`http_request_duration_seconds_count{route="GET /v1/healthcheck",status="200"} 2`,
+		"# TYPE http_requests_in_flight gauge",
+		"http_requests_in_flight 1",
+		"http_panics_recovered_total 0",
+		"# TYPE go_sql_open_connections gauge",
+		"# TYPE go_sql_wait_count_total counter",
+		"# TYPE go_sql_wait_duration_seconds_total counter",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected the metrics to include %q, got:\n%s", line, body)
+		}
+	}
+
+	if strings.Contains(body, "no-such-route") {
+		t.Error("expected unmatched paths not to be used as labels")
+	}
+}
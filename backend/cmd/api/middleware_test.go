@@ -1,9 +1,14 @@
 package main_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	api "github.com/johndennehy101/note-taking-web-app/backend/cmd/api"
 )
 
 func TestEnableCORS(t *testing.T) {
@@ -140,3 +145,99 @@ func TestRecoverPanic(t *testing.T) {
 		}
 	})
 }
+
+func TestRequestID(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{"generated", "", ""},
+		{"kept", "req-42_a.b", "req-42_a.b"},
+		{"unsafe replaced", "bad id\r\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/healthcheck", http.NoBody)
+			if tt.header != "" {
+				req.Header.Set("X-Request-ID", tt.header)
+			}
+
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			got := rr.Header().Get("X-Request-ID")
+
+			switch {
+			case tt.expected != "" && got != tt.expected:
+				t.Errorf("expected request ID %q, got %q", tt.expected, got)
+			case tt.expected == "" && len(got) != 32:
+				t.Errorf("expected a generated request ID, got %q", got)
+			}
+		})
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	app := &testApp{AppInterface: api.NewApplication(testDB, logger, "testing", nil)}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/shared/secret-share-token?token=secret-calendar-token", http.NoBody)
+	req.Header.Set("X-Request-ID", "trace-123")
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	size := rr.Body.Len()
+
+	var response struct {
+		Error     string `json:"error"`
+		RequestID string `json:"request_id"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.RequestID != "trace-123" {
+		t.Errorf("expected the request ID in the error body, got %q", response.RequestID)
+	}
+
+	var entry struct {
+		Msg        string `json:"msg"`
+		RequestID  string `json:"request_id"`
+		Method     string `json:"method"`
+		Route      string `json:"route"`
+		Status     int    `json:"status"`
+		Bytes      int    `json:"bytes"`
+		RemoteAddr string `json:"remote_addr"`
+	}
+
+	found := false
+
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("failed to decode log line %q: %v", line, err)
+		}
+		if entry.Msg == "request" {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		t.Fatalf("expected an access log line, got %s", buf.String())
+	}
+
+	if entry.RequestID != "trace-123" || entry.Method != http.MethodGet || entry.Route != "GET /v1/shared/{token}" ||
+		entry.Status != http.StatusNotFound || entry.Bytes != size || entry.RemoteAddr != req.RemoteAddr {
+		t.Errorf("unexpected access log entry %+v", entry)
+	}
+
+	if bytes.Contains(buf.Bytes(), []byte("secret-")) {
+		t.Errorf("expected tokens to be kept out of the log, got %s", buf.String())
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func (app *application) createNotebookHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name     string `json:"name"`
+		ParentID *int64 `json:"parent_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	notebook := &data.Notebook{
+		Name:     input.Name,
+		ParentID: input.ParentID,
+	}
+
+	if !user.IsAnonymous() {
+		notebook.OwnerID = &user.ID
+	}
+
+	v := validator.New()
+
+	data.ValidateNotebook(v, notebook)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkNotebookParent(v, notebook, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Notebooks.Insert(notebook, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/notebooks/%d", notebook.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"notebook": notebook}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listNotebooksHandler(w http.ResponseWriter, r *http.Request) {
+	notebooks, err := app.models.Notebooks.GetAll(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"notebooks": notebooks}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showNotebookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	notebook, err := app.models.Notebooks.Get(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"notebook": notebook}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateNotebookHandler renames a notebook and moves it to the given parent,
+// or to the top level when parent_id is null.
+func (app *application) updateNotebookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	notebook, err := app.models.Notebooks.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name     string `json:"name"`
+		ParentID *int64 `json:"parent_id"`
+		Version  *int   `json:"version"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Version != nil && *input.Version != notebook.Version {
+		app.versionConflictResponse(w, r)
+		return
+	}
+
+	notebook.Name = input.Name
+	notebook.ParentID = input.ParentID
+
+	v := validator.New()
+
+	data.ValidateNotebook(v, notebook)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkNotebookParent(v, notebook, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Notebooks.Update(notebook)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNotebookCycle):
+			v.AddError("parent_id", "must not be the notebook itself or one of its descendants")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.versionConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"notebook": notebook}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteNotebookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Notebooks.Get(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Notebooks.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "notebook successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listNotebookNotesHandler lists the notes filed in a notebook. Passing
+// recursive=true includes the notes of every nested notebook as well.
+func (app *application) listNotebookNotesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	recursive := r.URL.Query().Get("recursive")
+
+	v := validator.New()
+
+	v.Check(validator.PermittedValue(recursive, "", "true", "false"), "recursive", "must be true or false")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	_, err = app.models.Notebooks.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	notes, err := app.models.Notebooks.GetNotes(id, user.ID, recursive == "true")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"notes": notes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// moveNoteHandler files a note in a notebook, or takes it out of its notebook
+// when notebook_id is null.
+func (app *application) moveNoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		NotebookID *int64 `json:"notebook_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if !app.authorizeNote(w, r, id, data.RoleEditor) {
+		return
+	}
+
+	note, err := app.notes(r).Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+
+	err = app.checkNoteNotebook(v, input.NotebookID, app.contextGetUser(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.notes(r).Move(note, input.NotebookID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"note": note}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkNotebookParent records a validation error unless the notebook's
+// parent, if it has one, is visible to the user and has the same owner.
+func (app *application) checkNotebookParent(v *validator.Validator, notebook *data.Notebook, user *data.User) error {
+	if notebook.ParentID == nil {
+		return nil
+	}
+
+	parent, err := app.models.Notebooks.Get(*notebook.ParentID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("parent_id", "must be an existing notebook")
+			return nil
+		default:
+			return err
+		}
+	}
+
+	sameOwner := (parent.OwnerID == nil && notebook.OwnerID == nil) ||
+		(parent.OwnerID != nil && notebook.OwnerID != nil && *parent.OwnerID == *notebook.OwnerID)
+
+	v.Check(sameOwner, "parent_id", "must be a notebook with the same owner")
+
+	return nil
+}
+
+// checkNoteNotebook records a validation error unless notebookID is nil or
+// names a notebook visible to the user.
+func (app *application) checkNoteNotebook(v *validator.Validator, notebookID *int64, user *data.User) error {
+	if notebookID == nil {
+		return nil
+	}
+
+	_, err := app.models.Notebooks.Get(*notebookID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("notebook_id", "must be an existing notebook")
+			return nil
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
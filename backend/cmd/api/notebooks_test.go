@@ -0,0 +1,95 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestNotebookHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Organiser")
+	_, otherToken := createTestUser(t, app, "Other")
+
+	createNotebook := func(body string) int64 {
+		rr := serveAs(app, token, http.MethodPost, "/v1/notebooks", body)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d creating notebook, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+
+		var response struct {
+			Notebook struct {
+				ID int64 `json:"id"`
+			} `json:"notebook"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		return response.Notebook.ID
+	}
+
+	parent := createNotebook(`{"name": "Projects"}`)
+	child := createNotebook(fmt.Sprintf(`{"name": "Website", "parent_id": %d}`, parent))
+
+	rr := serveAs(app, token, http.MethodPost, "/v1/notes", fmt.Sprintf(`{"title": "Filed Note", "body": "Body", "tags": [], "notebook_id": %d}`, child))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		Note struct {
+			ID int64 `json:"id"`
+		} `json:"note"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedNotes  int
+	}{
+		{"direct notes", token, http.MethodGet, fmt.Sprintf("/v1/notebooks/%d/notes", parent), "", http.StatusOK, 0},
+		{"recursive notes", token, http.MethodGet, fmt.Sprintf("/v1/notebooks/%d/notes?recursive=true", parent), "", http.StatusOK, 1},
+		{"invalid recursive flag", token, http.MethodGet, fmt.Sprintf("/v1/notebooks/%d/notes?recursive=yes", parent), "", http.StatusUnprocessableEntity, -1},
+		{"other user cannot see notebook", otherToken, http.MethodGet, fmt.Sprintf("/v1/notebooks/%d", parent), "", http.StatusNotFound, -1},
+		{"other user cannot nest under it", otherToken, http.MethodPost, "/v1/notebooks", fmt.Sprintf(`{"name": "Sneaky", "parent_id": %d}`, parent), http.StatusUnprocessableEntity, -1},
+		{"cycle", token, http.MethodPut, fmt.Sprintf("/v1/notebooks/%d", parent), fmt.Sprintf(`{"name": "Projects", "parent_id": %d}`, child), http.StatusUnprocessableEntity, -1},
+		{"own parent", token, http.MethodPut, fmt.Sprintf("/v1/notebooks/%d", parent), fmt.Sprintf(`{"name": "Projects", "parent_id": %d}`, parent), http.StatusUnprocessableEntity, -1},
+		{"stale version", token, http.MethodPut, fmt.Sprintf("/v1/notebooks/%d", child), fmt.Sprintf(`{"name": "Website", "parent_id": %d, "version": 99}`, parent), http.StatusConflict, -1},
+		{"move child to top level", token, http.MethodPut, fmt.Sprintf("/v1/notebooks/%d", child), `{"name": "Website", "parent_id": null}`, http.StatusOK, -1},
+		{"recursive notes after move", token, http.MethodGet, fmt.Sprintf("/v1/notebooks/%d/notes?recursive=true", parent), "", http.StatusOK, 0},
+		{"move note into parent", token, http.MethodPut, fmt.Sprintf("/v1/notes/%d/notebook", created.Note.ID), fmt.Sprintf(`{"notebook_id": %d}`, parent), http.StatusOK, -1},
+		{"move note into missing notebook", token, http.MethodPut, fmt.Sprintf("/v1/notes/%d/notebook", created.Note.ID), `{"notebook_id": 999999}`, http.StatusUnprocessableEntity, -1},
+		{"notes after note move", token, http.MethodGet, fmt.Sprintf("/v1/notebooks/%d/notes", parent), "", http.StatusOK, 1},
+		{"delete parent", token, http.MethodDelete, fmt.Sprintf("/v1/notebooks/%d", parent), "", http.StatusOK, -1},
+		{"child survives as top level", token, http.MethodGet, fmt.Sprintf("/v1/notebooks/%d", child), "", http.StatusOK, -1},
+	}
+
+	for _, tt := range tests {
+		rr := serveAs(app, tt.token, tt.method, tt.path, tt.body)
+
+		if rr.Code != tt.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.name, tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+
+		if tt.expectedNotes >= 0 {
+			var response struct {
+				Notes []json.RawMessage `json:"notes"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Notes) != tt.expectedNotes {
+				t.Errorf("%s: expected %d notes, got %d", tt.name, tt.expectedNotes, len(response.Notes))
+			}
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
 	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
@@ -11,9 +13,11 @@ import (
 
 func (app *application) createNoteHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title string   `json:"title"`
-		Body  string   `json:"body"`
-		Tags  []string `json:"tags"`
+		Title      string     `json:"title"`
+		Body       string     `json:"body"`
+		Tags       []string   `json:"tags"`
+		NotebookID *int64     `json:"notebook_id"`
+		DueAt      *time.Time `json:"due_at"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -23,26 +27,36 @@ func (app *application) createNoteHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	note := &data.Note{
-		Title: input.Title,
-		Body:  input.Body,
-		Tags:  input.Tags,
+		Title:      input.Title,
+		Body:       input.Body,
+		Tags:       input.Tags,
+		NotebookID: input.NotebookID,
+		DueAt:      input.DueAt,
 	}
 
 	v := validator.New()
 
 	data.ValidateNote(v, note)
 
+	err = app.checkNoteNotebook(v, note.NotebookID, app.contextGetUser(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Notes.Insert(note)
+	err = app.notes(r).InsertOwned(note, app.contextGetUser(r))
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.indexNote(note)
+
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/notes/%d", note.ID))
 
@@ -52,6 +66,10 @@ func (app *application) createNoteHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// updateNoteHandler replaces a note's title, body, tags, archived flag and
+// due date with those in the request, so leaving out due_at clears the due
+// date. An edit based on an older version is merged with the changes made
+// since, the due date included.
 func (app *application) updateNoteHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -59,7 +77,11 @@ func (app *application) updateNoteHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	note, err := app.models.Notes.Get(id)
+	if !app.authorizeNote(w, r, id, data.RoleEditor) {
+		return
+	}
+
+	note, err := app.notes(r).Get(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -71,10 +93,12 @@ func (app *application) updateNoteHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	var input struct {
-		Title    string   `json:"title"`
-		Body     string   `json:"body"`
-		Tags     []string `json:"tags"`
-		Archived bool     `json:"archived"`
+		Title    string     `json:"title"`
+		Body     string     `json:"body"`
+		Tags     []string   `json:"tags"`
+		Archived bool       `json:"archived"`
+		DueAt    *time.Time `json:"due_at"`
+		Version  *int       `json:"version"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -87,9 +111,15 @@ func (app *application) updateNoteHandler(w http.ResponseWriter, r *http.Request
 	note.Body = input.Body
 	note.Archived = input.Archived
 	note.Tags = input.Tags
+	note.DueAt = input.DueAt
+
+	if input.Version != nil {
+		note.Version = *input.Version
+	}
 
 	v := validator.New()
 
+	v.Check(note.Version > 0, "version", "must be a positive integer")
 	data.ValidateNote(v, note)
 
 	if !v.Valid() {
@@ -97,12 +127,27 @@ func (app *application) updateNoteHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Notes.Update(note)
+	rewritten, err := app.notes(r).Update(note)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		var conflict *data.MergeConflict
+		switch {
+		case errors.As(err, &conflict):
+			app.editConflictResponse(w, r, conflict)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
+	app.indexNote(note)
+
+	// Notes whose links followed a change of title have new bodies too.
+	for _, other := range rewritten {
+		app.indexNote(other)
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"note": note}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -116,7 +161,22 @@ func (app *application) showNoteHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	note, err := app.models.Notes.Get(id)
+	render := r.URL.Query().Get("render")
+
+	v := validator.New()
+
+	v.Check(validator.PermittedValue(render, "", "html"), "render", "must be html if provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.authorizeNote(w, r, id, data.RoleViewer) {
+		return
+	}
+
+	note, err := app.notes(r).Get(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -127,7 +187,25 @@ func (app *application) showNoteHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"note": note}, nil)
+	if render == "" && !app.wantsHTML(r) {
+		err = app.writeJSON(w, http.StatusOK, envelope{"note": note}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	html, err := app.renderer.RenderNote(note.ID, note.Version, note.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if app.wantsHTML(r) {
+		err = app.writeHTML(w, http.StatusOK, note.Title, html, nil)
+	} else {
+		err = app.writeJSON(w, http.StatusOK, envelope{"note": note, "html": html}, nil)
+	}
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -140,7 +218,11 @@ func (app *application) deleteNoteHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Notes.Delete(id)
+	if !app.authorizeNote(w, r, id, data.RoleOwner) {
+		return
+	}
+
+	err = app.notes(r).Delete(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -151,8 +233,151 @@ func (app *application) deleteNoteHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.related.Remove(id)
+
+	app.background(app.purgeDeletedBlobs)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "note successfully deleted"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+func (app *application) listNotesHandler(w http.ResponseWriter, r *http.Request) {
+	app.listNotes(w, r, false)
+}
+
+func (app *application) listFavoriteNotesHandler(w http.ResponseWriter, r *http.Request) {
+	app.listNotes(w, r, true)
+}
+
+// listNotes lists the notes visible to the current user, filtered by a text
+// query, tags and archived state, and paginated. Pinned notes are always
+// listed first. The title parameter, which predates text, still searches
+// titles alone.
+func (app *application) listNotes(w http.ResponseWriter, r *http.Request, favoritesOnly bool) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	query := data.NoteQuery{
+		Q:             app.readString(qs, "q", ""),
+		Text:          app.readString(qs, "text", ""),
+		Title:         app.readString(qs, "title", ""),
+		Tags:          app.readCSV(qs, "tags", []string{}),
+		Archived:      app.readBool(qs, "archived", v),
+		FavoritesOnly: favoritesOnly,
+	}
+
+	filters := app.readNoteFilters(qs, "-updated_at", v)
+
+	data.ValidateNoteQuery(v, query)
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	app.writeNoteListing(w, r, query, filters)
+}
+
+// readNoteFilters reads the pagination and sort parameters for a note
+// listing, sorting by defaultSort unless the client asks otherwise.
+func (app *application) readNoteFilters(qs url.Values, defaultSort string, v *validator.Validator) data.Filters {
+	return data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", defaultSort),
+		SortSafelist: data.NoteSortSafelist,
+	}
+}
+
+// writeNoteListing runs a note query for the current user and writes the
+// matching page of notes along with the pagination metadata.
+func (app *application) writeNoteListing(w http.ResponseWriter, r *http.Request, query data.NoteQuery, filters data.Filters) {
+	notes, metadata, err := app.notes(r).GetAll(query, filters, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"notes": notes, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// suggestNotesHandler offers note titles similar to q for search-as-you-type,
+// tolerating partial words and typos.
+func (app *application) suggestNotesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	q := app.readString(qs, "q", "")
+	limit := app.readInt(qs, "limit", 10, v)
+
+	v.Check(q != "", "q", "must be provided")
+	v.Check(len(q) <= 200, "q", "must not be more than 200 bytes long")
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 25, "limit", "must be a maximum of 25")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	suggestions, err := app.notes(r).Suggest(q, app.config.search.similarityThreshold, limit, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// setNoteFlagHandler returns a handler that sets one of a note's flags, such
+// as pinned or favorite, to value using the given model method.
+func (app *application) setNoteFlagHandler(set func(data.NoteModel, *data.Note, bool) error, value bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		if !app.authorizeNote(w, r, id, data.RoleEditor) {
+			return
+		}
+
+		note, err := app.notes(r).Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = set(app.notes(r), note, value)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"note": note}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
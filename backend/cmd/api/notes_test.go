@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
 )
@@ -514,3 +518,389 @@ func TestArchivedPropertyGet(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateNoteHandlerStaleVersion(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name           string
+		clientBody     string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "clean merge",
+			clientBody:     "one\ntwo\nclient",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "server\ntwo\nclient",
+		},
+		{
+			name:           "conflicting edit",
+			clientBody:     "client\ntwo\nthree",
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			note := createTestNote(t, app, "Shared", "one\ntwo\nthree", []string{"test"})
+
+			server := *note
+			server.Body = "server\ntwo\nthree"
+			if _, err := app.GetModels().Notes.Update(&server); err != nil {
+				t.Fatal(err)
+			}
+
+			body, err := json.Marshal(map[string]interface{}{
+				"title":   note.Title,
+				"body":    tt.clientBody,
+				"tags":    note.Tags,
+				"version": 1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/v1/notes/%d", note.ID), bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			router := app.routes()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			switch tt.expectedStatus {
+			case http.StatusOK:
+				var response struct {
+					Note data.Note `json:"note"`
+				}
+				if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+					t.Fatal(err)
+				}
+				if response.Note.Body != tt.expectedBody {
+					t.Errorf("expected merged body %q, got %q", tt.expectedBody, response.Note.Body)
+				}
+			case http.StatusConflict:
+				var response struct {
+					Conflict  data.MergeConflict `json:"conflict"`
+					RequestID string             `json:"request_id"`
+				}
+				if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+					t.Fatal(err)
+				}
+				if response.RequestID == "" {
+					t.Error("expected the request ID in the conflict response")
+				}
+				if response.Conflict.Base == nil || response.Conflict.Server == nil || response.Conflict.Client == nil {
+					t.Error("expected base, server and client versions in conflict response")
+				}
+				if response.Conflict.Merged == nil || !strings.Contains(response.Conflict.Merged.Body, "<<<<<<<") {
+					t.Error("expected conflict markers in merged body")
+				}
+			}
+		})
+	}
+}
+
+func TestShowNoteHandlerRendered(t *testing.T) {
+	app := newTestApplication(t)
+
+	note := createTestNote(t, app, "Rendered <Note>", "# Heading\n\n- [x] done\n\n<script>alert(1)</script>", []string{"test"})
+
+	tests := []struct {
+		name                string
+		query               string
+		accept              string
+		expectedStatus      int
+		expectedContentType string
+	}{
+		{
+			name:                "render query parameter",
+			query:               "?render=html",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+		},
+		{
+			name:                "accept header",
+			accept:              "text/html,application/xhtml+xml",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/html; charset=utf-8",
+		},
+		{
+			name:           "unsupported render format",
+			query:          "?render=pdf",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/notes/%d%s", note.ID, tt.query), http.NoBody)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+
+			router := app.routes()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			if rr.Header().Get("Content-Type") != tt.expectedContentType {
+				t.Errorf("expected Content-Type %q, got %q", tt.expectedContentType, rr.Header().Get("Content-Type"))
+			}
+
+			var html string
+			if tt.expectedContentType == "application/json" {
+				var response struct {
+					HTML string `json:"html"`
+				}
+				if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+					t.Fatal(err)
+				}
+				html = response.HTML
+			} else {
+				html = rr.Body.String()
+				if !strings.Contains(html, "<title>Rendered &lt;Note&gt;</title>") {
+					t.Errorf("expected escaped title in document, got %s", html)
+				}
+			}
+
+			if !strings.Contains(html, "Heading</h1>") || !strings.Contains(html, `type="checkbox"`) {
+				t.Errorf("expected rendered markdown, got %s", html)
+			}
+			if strings.Contains(html, "<script>") {
+				t.Errorf("expected script to be sanitized, got %s", html)
+			}
+		})
+	}
+}
+
+func TestPinnedAndFavoriteNotes(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Pinner")
+	_, otherToken := createTestUser(t, app, "Other")
+
+	tag := fmt.Sprintf("flags-%d", time.Now().UnixNano())
+
+	var ids []int64
+	for _, title := range []string{"First", "Second", "Third"} {
+		rr := serveAs(app, token, http.MethodPost, "/v1/notes", fmt.Sprintf(`{"title": %q, "body": "Body", "tags": [%q]}`, title, tag))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+
+		var created struct {
+			Note struct {
+				ID int64 `json:"id"`
+			} `json:"note"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, created.Note.ID)
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		method         string
+		path           string
+		expectedStatus int
+		expectedTitles []string
+	}{
+		{"pin third", token, http.MethodPut, fmt.Sprintf("/v1/notes/%d/pin", ids[2]), http.StatusOK, nil},
+		{"favorite first", token, http.MethodPut, fmt.Sprintf("/v1/notes/%d/favorite", ids[0]), http.StatusOK, nil},
+		{"other user cannot pin", otherToken, http.MethodPut, fmt.Sprintf("/v1/notes/%d/pin", ids[0]), http.StatusNotFound, nil},
+		{"pinned first despite sort", token, http.MethodGet, "/v1/notes?sort=title&tags=" + tag, http.StatusOK, []string{"Third", "First", "Second"}},
+		{"favorites", token, http.MethodGet, "/v1/notes/favorites?tags=" + tag, http.StatusOK, []string{"First"}},
+		{"other user sees nothing", otherToken, http.MethodGet, "/v1/notes?tags=" + tag, http.StatusOK, []string{}},
+		{"unpin third", token, http.MethodDelete, fmt.Sprintf("/v1/notes/%d/pin", ids[2]), http.StatusOK, nil},
+		{"sort after unpin", token, http.MethodGet, "/v1/notes?sort=title&tags=" + tag, http.StatusOK, []string{"First", "Second", "Third"}},
+		{"invalid sort", token, http.MethodGet, "/v1/notes?sort=body", http.StatusUnprocessableEntity, nil},
+		{"invalid page size", token, http.MethodGet, "/v1/notes?page_size=0", http.StatusUnprocessableEntity, nil},
+	}
+
+	for _, tt := range tests {
+		rr := serveAs(app, tt.token, tt.method, tt.path, "")
+
+		if rr.Code != tt.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.name, tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+
+		if tt.expectedTitles == nil {
+			continue
+		}
+
+		var response struct {
+			Notes []struct {
+				Title string `json:"title"`
+			} `json:"notes"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+
+		titles := []string{}
+		for _, note := range response.Notes {
+			titles = append(titles, note.Title)
+		}
+
+		if !slices.Equal(titles, tt.expectedTitles) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expectedTitles, titles)
+		}
+	}
+}
+
+func TestListNotesSearchQuery(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Querier")
+
+	tag := fmt.Sprintf("q-%d", time.Now().UnixNano())
+
+	for _, note := range []string{
+		fmt.Sprintf(`{"title": "React hooks", "body": "Body", "tags": [%q, "dev"]}`, tag),
+		fmt.Sprintf(`{"title": "Old React notes", "body": "Body", "tags": [%q, "dev", "archive"]}`, tag),
+		fmt.Sprintf(`{"title": "Groceries", "body": "Body", "tags": [%q]}`, tag),
+	} {
+		rr := serveAs(app, token, http.MethodPost, "/v1/notes", note)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+	}
+
+	tests := []struct {
+		name           string
+		q              string
+		expectedStatus int
+		expectedTitles []string
+	}{
+		{"tag and negated tag", "tag:dev AND -tag:archive", http.StatusOK, []string{"React hooks"}},
+		{"quoted title", `title:"react"`, http.StatusOK, []string{"Old React notes", "React hooks"}},
+		{"grouping", "(tag:archive OR title:groceries) archived:false", http.StatusOK, []string{"Groceries", "Old React notes"}},
+		{"updated in the past", "updated:<2000-01-01", http.StatusOK, []string{}},
+		{"syntax error", "tag:dev AND (title:react", http.StatusUnprocessableEntity, nil},
+	}
+
+	for _, tt := range tests {
+		rr := serveAs(app, token, http.MethodGet, fmt.Sprintf("/v1/notes?sort=title&tags=%s&q=%s", tag, url.QueryEscape(tt.q)), "")
+
+		if rr.Code != tt.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.name, tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+
+		if tt.expectedTitles == nil {
+			if !strings.Contains(rr.Body.String(), "position 25") {
+				t.Errorf("%s: expected the error position in %s", tt.name, rr.Body.String())
+			}
+			continue
+		}
+
+		var response struct {
+			Notes []struct {
+				Title string `json:"title"`
+			} `json:"notes"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+
+		titles := []string{}
+		for _, note := range response.Notes {
+			titles = append(titles, note.Title)
+		}
+
+		if !slices.Equal(titles, tt.expectedTitles) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expectedTitles, titles)
+		}
+	}
+}
+
+func TestListNotesTextAndTitle(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Searcher")
+
+	tag := fmt.Sprintf("t-%d", time.Now().UnixNano())
+
+	for _, note := range []string{
+		fmt.Sprintf(`{"title": "Kayak trip", "body": "Body", "tags": [%q]}`, tag),
+		fmt.Sprintf(`{"title": "Packing", "body": "Bring the kayak", "tags": [%q]}`, tag),
+	} {
+		rr := serveAs(app, token, http.MethodPost, "/v1/notes", note)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+	}
+
+	tests := []struct {
+		param          string
+		expectedTitles []string
+	}{
+		{"text", []string{"Kayak trip", "Packing"}},
+		{"title", []string{"Kayak trip"}},
+	}
+
+	for _, tt := range tests {
+		rr := serveAs(app, token, http.MethodGet, fmt.Sprintf("/v1/notes?sort=title&tags=%s&%s=kayak", tag, tt.param), "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.param, http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		var response struct {
+			Notes []struct {
+				Title string `json:"title"`
+			} `json:"notes"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+
+		titles := []string{}
+		for _, note := range response.Notes {
+			titles = append(titles, note.Title)
+		}
+
+		if !slices.Equal(titles, tt.expectedTitles) {
+			t.Errorf("%s: expected %v, got %v", tt.param, tt.expectedTitles, titles)
+		}
+	}
+}
+
+func TestSuggestNotesHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	createTestNote(t, app, "Learning React", "Body", []string{})
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"typo", "/v1/notes/suggest?q=recat", http.StatusOK},
+		{"missing q", "/v1/notes/suggest", http.StatusUnprocessableEntity},
+		{"limit too large", "/v1/notes/suggest?q=react&limit=100", http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		rr := serveAs(app, "", http.MethodGet, tt.path, "")
+
+		if rr.Code != tt.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.name, tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+
+		if rr.Code == http.StatusOK && !strings.Contains(rr.Body.String(), "Learning React") {
+			t.Errorf("%s: expected the note among suggestions, got %s", tt.name, rr.Body.String())
+		}
+	}
+}
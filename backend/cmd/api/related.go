@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/related"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+// tagSuggestionNeighbours is how many of the notes most similar to a draft
+// contribute their tags to its suggestions.
+const tagSuggestionNeighbours = 20
+
+// loadRelatedIndex fills the related notes index with every stored note. It
+// runs once at startup; from then on handlers keep the index up to date as
+// notes change. The index is held in memory by each instance of the API, so
+// when several run side by side, an instance only sees the changes made
+// through it until it is restarted.
+func (app *application) loadRelatedIndex() error {
+	notes, err := app.models.Notes.GetAllContent()
+	if err != nil {
+		return err
+	}
+
+	for _, note := range notes {
+		app.indexNote(note)
+	}
+
+	return nil
+}
+
+func (app *application) indexNote(note *data.Note) {
+	app.related.Add(note.ID, note.Title, note.Body, note.Tags)
+}
+
+// listRelatedNotesHandler lists the notes most similar to a note, leaving out
+// any the current user can't see.
+func (app *application) listRelatedNotesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	limit := app.readInt(r.URL.Query(), "limit", 5, v)
+
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 20, "limit", "must be a maximum of 20")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.authorizeNote(w, r, id, data.RoleViewer) {
+		return
+	}
+
+	if !app.related.Contains(id) {
+		note, err := app.notes(r).Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		app.indexNote(note)
+	}
+
+	matches := app.related.Related(id)
+
+	ids := make([]int64, len(matches))
+	for i, match := range matches {
+		ids[i] = match.ID
+	}
+
+	visible, err := app.models.Collaborators.VisibleNotes(ids, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	results := []related.Match{}
+
+	for _, match := range matches {
+		if len(results) == limit {
+			break
+		}
+		if visible[match.ID] {
+			results = append(results, match)
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"related": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// suggestTagsHandler suggests tags for a draft note from the tags on the
+// existing notes most similar to it, so the editor can offer them before the
+// note is created. Only notes visible to the current user contribute.
+func (app *application) suggestTagsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title string   `json:"title"`
+		Body  string   `json:"body"`
+		Tags  []string `json:"tags"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	limit := app.readInt(r.URL.Query(), "limit", 5, v)
+
+	v.Check(input.Title != "" || input.Body != "", "body", "a title or body must be provided")
+	v.Check(len(input.Title) <= 500, "title", "must not be more than 500 bytes long")
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 20, "limit", "must be a maximum of 20")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	matches := app.related.Similar(input.Title, input.Body)
+
+	ids := make([]int64, len(matches))
+	for i, match := range matches {
+		ids[i] = match.ID
+	}
+
+	visible, err := app.models.Collaborators.VisibleNotes(ids, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var neighbours []related.Match
+
+	for _, match := range matches {
+		if len(neighbours) == tagSuggestionNeighbours {
+			break
+		}
+		if visible[match.ID] {
+			neighbours = append(neighbours, match)
+		}
+	}
+
+	suggestions := related.SuggestTags(neighbours, input.Tags, limit)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,166 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestListRelatedNotesHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Reader")
+	_, otherToken := createTestUser(t, app, "Private")
+
+	createNote := func(token, body string) int64 {
+		rr := serveAs(app, token, http.MethodPost, "/v1/notes", body)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+
+		var created struct {
+			Note struct {
+				ID int64 `json:"id"`
+			} `json:"note"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+			t.Fatal(err)
+		}
+		return created.Note.ID
+	}
+
+	source := createNote(token, `{"title": "Xylophone tuning", "body": "Tuning xylophone bars with a strobe tuner", "tags": ["xylophone"]}`)
+	similar := createNote(token, `{"title": "Xylophone mallets", "body": "Choosing mallets for xylophone bars", "tags": ["xylophone"]}`)
+	hidden := createNote(otherToken, `{"title": "Xylophone secrets", "body": "Private xylophone tuning notes", "tags": ["xylophone"]}`)
+
+	rr := serveAs(app, token, http.MethodGet, fmt.Sprintf("/v1/notes/%d/related", source), "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Related []struct {
+			ID    int64   `json:"id"`
+			Score float64 `json:"score"`
+		} `json:"related"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Related) == 0 || response.Related[0].ID != similar {
+		t.Fatalf("expected note %d to be the most related, got %+v", similar, response.Related)
+	}
+
+	for _, match := range response.Related {
+		if match.ID == hidden {
+			t.Errorf("expected the other user's private note to be left out")
+		}
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		path           string
+		expectedStatus int
+	}{
+		{"limit too large", token, fmt.Sprintf("/v1/notes/%d/related?limit=21", source), http.StatusUnprocessableEntity},
+		{"other user cannot see note", otherToken, fmt.Sprintf("/v1/notes/%d/related", source), http.StatusNotFound},
+		{"missing note", token, "/v1/notes/999999/related", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rr := serveAs(app, tt.token, http.MethodGet, tt.path, "")
+		if rr.Code != tt.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr = serveAs(app, token, http.MethodDelete, fmt.Sprintf("/v1/notes/%d", similar), "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d deleting note, got %d", http.StatusOK, rr.Code)
+	}
+
+	rr = serveAs(app, token, http.MethodGet, fmt.Sprintf("/v1/notes/%d/related", source), "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	response.Related = nil
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, match := range response.Related {
+		if match.ID == similar {
+			t.Errorf("expected deleted note %d to be dropped from the index", similar)
+		}
+	}
+}
+
+func TestSuggestTagsHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Tagger")
+	_, otherToken := createTestUser(t, app, "Hoarder")
+
+	for _, note := range []struct{ token, body string }{
+		{token, `{"title": "Zither strings", "body": "Restringing a zither", "tags": ["zither", "music"]}`},
+		{token, `{"title": "Zither tuning", "body": "Tuning zither strings by ear", "tags": ["zither"]}`},
+		{otherToken, `{"title": "Zither hoard", "body": "Zither strings zither tuning", "tags": ["zither-secret"]}`},
+	} {
+		rr := serveAs(app, note.token, http.MethodPost, "/v1/notes", note.body)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedTags   []string
+	}{
+		{"ranked by co-occurrence", `{"title": "New zither", "body": "Which zither strings to buy"}`, http.StatusOK, []string{"zither", "music"}},
+		{"existing tags skipped", `{"title": "New zither", "body": "Which zither strings to buy", "tags": ["zither"]}`, http.StatusOK, []string{"music"}},
+		{"empty draft", `{"title": "", "body": ""}`, http.StatusUnprocessableEntity, nil},
+	}
+
+	for _, tt := range tests {
+		rr := serveAs(app, token, http.MethodPost, "/v1/notes/tag-suggestions", tt.body)
+
+		if rr.Code != tt.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.name, tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+
+		if tt.expectedTags == nil {
+			continue
+		}
+
+		var response struct {
+			Suggestions []struct {
+				Tag string `json:"tag"`
+			} `json:"suggestions"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(response.Suggestions) < len(tt.expectedTags) {
+			t.Fatalf("%s: expected at least %d suggestions, got %+v", tt.name, len(tt.expectedTags), response.Suggestions)
+		}
+
+		for i, tag := range tt.expectedTags {
+			if response.Suggestions[i].Tag != tag {
+				t.Errorf("%s: expected suggestion %d to be %q, got %q", tt.name, i, tag, response.Suggestions[i].Tag)
+			}
+		}
+
+		for _, suggestion := range response.Suggestions {
+			if suggestion.Tag == "zither-secret" {
+				t.Errorf("%s: expected tags from another user's private note to be left out", tt.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/notify"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+// reminderBatchSize is the most reminders claimed for delivery at a time.
+const reminderBatchSize = 50
+
+func (app *application) createReminderHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		FireAt     time.Time `json:"fire_at"`
+		Recurrence string    `json:"recurrence"`
+		Channel    string    `json:"channel"`
+		WebhookURL string    `json:"webhook_url"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if !app.authorizeNote(w, r, noteID, data.RoleViewer) {
+		return
+	}
+
+	reminder := &data.Reminder{
+		NoteID:     noteID,
+		UserID:     app.contextGetUser(r).ID,
+		FireAt:     input.FireAt,
+		Recurrence: input.Recurrence,
+		Channel:    input.Channel,
+		WebhookURL: input.WebhookURL,
+	}
+
+	v := validator.New()
+
+	if data.ValidateReminder(v, reminder); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Reminders.WithContext(r.Context()).Insert(reminder)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/notes/%d/reminders/%d", noteID, reminder.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"reminder": reminder}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listRemindersHandler lists the current user's reminders on a note; other
+// collaborators' reminders are private to them.
+func (app *application) listRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !app.authorizeNote(w, r, noteID, data.RoleViewer) {
+		return
+	}
+
+	reminders, err := app.models.Reminders.WithContext(r.Context()).GetAllForNote(noteID, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"reminders": reminders}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showReminderHandler(w http.ResponseWriter, r *http.Request) {
+	reminder, ok := app.readReminder(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"reminder": reminder}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateReminderHandler(w http.ResponseWriter, r *http.Request) {
+	reminder, ok := app.readReminder(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		FireAt     *time.Time `json:"fire_at"`
+		Recurrence *string    `json:"recurrence"`
+		Channel    *string    `json:"channel"`
+		WebhookURL *string    `json:"webhook_url"`
+		Version    *int       `json:"version"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Version != nil && *input.Version != reminder.Version {
+		app.versionConflictResponse(w, r)
+		return
+	}
+
+	if input.FireAt != nil {
+		reminder.FireAt = *input.FireAt
+	}
+	if input.Recurrence != nil {
+		reminder.Recurrence = *input.Recurrence
+	}
+	if input.Channel != nil {
+		reminder.Channel = *input.Channel
+	}
+	if input.WebhookURL != nil {
+		reminder.WebhookURL = *input.WebhookURL
+	}
+
+	v := validator.New()
+
+	if data.ValidateReminder(v, reminder); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Reminders.WithContext(r.Context()).Update(reminder)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.versionConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"reminder": reminder}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteReminderHandler(w http.ResponseWriter, r *http.Request) {
+	reminder, ok := app.readReminder(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Reminders.WithContext(r.Context()).Delete(reminder.NoteID, reminder.ID, reminder.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "reminder successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) readReminder(w http.ResponseWriter, r *http.Request) (*data.Reminder, bool) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	id, err := app.readNamedIDParam(r, "reminder_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	if !app.authorizeNote(w, r, noteID, data.RoleViewer) {
+		return nil, false
+	}
+
+	reminder, err := app.models.Reminders.WithContext(r.Context()).Get(noteID, id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return reminder, true
+}
+
+// runReminderScheduler delivers due reminders every poll interval. Reminders
+// live in the database, so any that came due while no instance was running
+// are picked up on the first poll after a restart.
+func (app *application) runReminderScheduler() {
+	ticker := time.NewTicker(app.config.reminders.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		app.deliverDueReminders()
+		<-ticker.C
+	}
+}
+
+// deliverDueReminders works through due reminders a batch at a time until
+// none are left or a batch fails. Reminders that fail to be delivered wait
+// out their backoff rather than holding up the rest.
+func (app *application) deliverDueReminders() {
+	for {
+		claimed, err := app.models.Reminders.DeliverDue(reminderBatchSize, app.deliverReminder)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+
+		if claimed < reminderBatchSize {
+			return
+		}
+	}
+}
+
+func (app *application) deliverReminder(due *data.DueReminder) error {
+	reminder := notify.Reminder{
+		ID:        due.ID,
+		NoteID:    due.NoteID,
+		NoteTitle: due.NoteTitle,
+		FireAt:    due.FireAt,
+	}
+
+	var err error
+
+	switch due.Channel {
+	case "email":
+		err = app.mailer.Send(due.Email, reminder)
+	case "webhook":
+		err = app.webhook.Post(context.Background(), due.WebhookURL, reminder)
+	default:
+		err = fmt.Errorf("unknown reminder channel %q", due.Channel)
+	}
+
+	if err != nil {
+		app.logger.Error(err.Error(), "reminder_id", due.ID)
+	}
+
+	return err
+}
@@ -0,0 +1,99 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestReminderHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Forgetful")
+	_, otherToken := createTestUser(t, app, "Other")
+
+	rr := serveAs(app, token, http.MethodPost, "/v1/notes", `{"title": "Renew passport", "body": "Forms are in the drawer"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		Note struct {
+			ID int64 `json:"id"`
+		} `json:"note"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/v1/notes/%d/reminders", created.Note.ID)
+
+	tests := []struct {
+		name           string
+		token          string
+		body           string
+		expectedStatus int
+	}{
+		{"anonymous", "", `{"fire_at": "2999-01-01T09:00:00Z", "channel": "email"}`, http.StatusUnauthorized},
+		{"not a collaborator", otherToken, `{"fire_at": "2999-01-01T09:00:00Z", "channel": "email"}`, http.StatusNotFound},
+		{"in the past", token, `{"fire_at": "2000-01-01T09:00:00Z", "channel": "email"}`, http.StatusUnprocessableEntity},
+		{"bad recurrence", token, `{"fire_at": "2999-01-01T09:00:00Z", "recurrence": "FREQ=SOMETIMES", "channel": "email"}`, http.StatusUnprocessableEntity},
+		{"webhook without url", token, `{"fire_at": "2999-01-01T09:00:00Z", "channel": "webhook"}`, http.StatusUnprocessableEntity},
+		{"email", token, `{"fire_at": "2999-01-01T09:00:00Z", "recurrence": "FREQ=WEEKLY", "channel": "email"}`, http.StatusCreated},
+	}
+
+	var location string
+
+	for _, tt := range tests {
+		rr := serveAs(app, tt.token, http.MethodPost, path, tt.body)
+		if rr.Code != tt.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.name, tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+
+		if rr.Code == http.StatusCreated {
+			location = rr.Header().Get("Location")
+		}
+	}
+
+	rr = serveAs(app, token, http.MethodGet, path, "")
+
+	var list struct {
+		Reminders []struct {
+			ID      int64 `json:"id"`
+			Version int   `json:"version"`
+		} `json:"reminders"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list.Reminders) != 1 {
+		t.Fatalf("expected 1 reminder, got %+v", list.Reminders)
+	}
+
+	reminderPath := fmt.Sprintf("%s/%d", path, list.Reminders[0].ID)
+	if location != reminderPath {
+		t.Errorf("expected Location %q, got %q", reminderPath, location)
+	}
+
+	rr = serveAs(app, token, http.MethodPut, reminderPath, `{"channel": "webhook", "webhook_url": "https://example.com/hook", "version": 1}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d updating reminder, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	rr = serveAs(app, token, http.MethodPut, reminderPath, `{"recurrence": "", "version": 1}`)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status %d for a stale version, got %d", http.StatusConflict, rr.Code)
+	}
+
+	rr = serveAs(app, token, http.MethodDelete, reminderPath, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d deleting reminder, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	rr = serveAs(app, token, http.MethodGet, reminderPath, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d after deletion, got %d", http.StatusNotFound, rr.Code)
+	}
+}
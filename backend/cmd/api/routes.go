@@ -2,22 +2,176 @@ package main
 
 import (
 	"net/http"
+	"strings"
 
-	"github.com/julienschmidt/httprouter"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
 )
 
 func (app *application) routes() http.Handler {
-	router := httprouter.New()
+	mux := http.NewServeMux()
 
-	router.NotFound = http.HandlerFunc(app.notFoundResponse)
+	mux.HandleFunc("GET /v1/healthcheck", app.healthcheckHandler)
 
-	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
+	// Metrics are served here unless they've been moved to a port of their
+	// own.
+	if app.config.metrics.port == 0 {
+		mux.Handle("GET /metrics", app.metricsHandler())
+	}
 
-	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/notes", app.createNoteHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/notes/:id", app.showNoteHandler)
-	router.HandlerFunc(http.MethodPut, "/v1/notes/:id", app.updateNoteHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/notes/:id", app.deleteNoteHandler)
+	mux.HandleFunc("GET /v1/notes", app.listNotesHandler)
+	mux.HandleFunc("POST /v1/notes", app.createNoteHandler)
+	mux.HandleFunc("GET /v1/notes/favorites", app.listFavoriteNotesHandler)
+	mux.HandleFunc("GET /v1/notes/suggest", app.suggestNotesHandler)
+	mux.HandleFunc("POST /v1/notes/tag-suggestions", app.suggestTagsHandler)
+	mux.HandleFunc("GET /v1/notes/{id}", app.showNoteHandler)
+	mux.HandleFunc("PUT /v1/notes/{id}", app.updateNoteHandler)
+	mux.HandleFunc("DELETE /v1/notes/{id}", app.deleteNoteHandler)
+	mux.HandleFunc("PUT /v1/notes/{id}/notebook", app.moveNoteHandler)
+	mux.HandleFunc("PUT /v1/notes/{id}/pin", app.setNoteFlagHandler(data.NoteModel.SetPinned, true))
+	mux.HandleFunc("DELETE /v1/notes/{id}/pin", app.setNoteFlagHandler(data.NoteModel.SetPinned, false))
+	mux.HandleFunc("PUT /v1/notes/{id}/favorite", app.setNoteFlagHandler(data.NoteModel.SetFavorite, true))
+	mux.HandleFunc("DELETE /v1/notes/{id}/favorite", app.setNoteFlagHandler(data.NoteModel.SetFavorite, false))
+	mux.HandleFunc("GET /v1/notes/{id}/related", app.listRelatedNotesHandler)
+	mux.HandleFunc("GET /v1/notes/{id}/backlinks", app.showBacklinksHandler)
+	mux.HandleFunc("GET /v1/notes/{id}/outgoing-links", app.showOutgoingLinksHandler)
+	mux.HandleFunc("GET /v1/notes/{id}/attachments", app.listAttachmentsHandler)
+	mux.HandleFunc("POST /v1/notes/{id}/attachments", app.uploadAttachmentHandler)
+	mux.HandleFunc("GET /v1/notes/{id}/attachments/{attachment_id}", app.downloadAttachmentHandler)
+	mux.HandleFunc("DELETE /v1/notes/{id}/attachments/{attachment_id}", app.deleteAttachmentHandler)
+	mux.HandleFunc("GET /v1/notes/{id}/shares", app.listSharesHandler)
+	mux.HandleFunc("POST /v1/notes/{id}/shares", app.createShareHandler)
+	mux.HandleFunc("DELETE /v1/notes/{id}/shares/{share_id}", app.deleteShareHandler)
 
-	return app.recoverPanic(app.enableCORS(router))
+	mux.HandleFunc("GET /v1/notes/{id}/reminders", app.requireAuthenticatedUser(app.listRemindersHandler))
+	mux.HandleFunc("POST /v1/notes/{id}/reminders", app.requireAuthenticatedUser(app.createReminderHandler))
+	mux.HandleFunc("GET /v1/notes/{id}/reminders/{reminder_id}", app.requireAuthenticatedUser(app.showReminderHandler))
+	mux.HandleFunc("PUT /v1/notes/{id}/reminders/{reminder_id}", app.requireAuthenticatedUser(app.updateReminderHandler))
+	mux.HandleFunc("DELETE /v1/notes/{id}/reminders/{reminder_id}", app.requireAuthenticatedUser(app.deleteReminderHandler))
+
+	mux.HandleFunc("GET /v1/notes/{id}/collaborators", app.listCollaboratorsHandler)
+	mux.HandleFunc("POST /v1/notes/{id}/collaborators", app.requireAuthenticatedUser(app.inviteCollaboratorHandler))
+	mux.HandleFunc("POST /v1/notes/{id}/collaborators/accept", app.requireAuthenticatedUser(app.acceptInvitationHandler))
+	mux.HandleFunc("DELETE /v1/notes/{id}/collaborators/{user_id}", app.requireAuthenticatedUser(app.removeCollaboratorHandler))
+
+	mux.HandleFunc("GET /v1/notebooks", app.listNotebooksHandler)
+	mux.HandleFunc("POST /v1/notebooks", app.createNotebookHandler)
+	mux.HandleFunc("GET /v1/notebooks/{id}", app.showNotebookHandler)
+	mux.HandleFunc("PUT /v1/notebooks/{id}", app.updateNotebookHandler)
+	mux.HandleFunc("DELETE /v1/notebooks/{id}", app.deleteNotebookHandler)
+	mux.HandleFunc("GET /v1/notebooks/{id}/notes", app.listNotebookNotesHandler)
+
+	mux.HandleFunc("GET /v1/saved-searches", app.listSavedSearchesHandler)
+	mux.HandleFunc("POST /v1/saved-searches", app.createSavedSearchHandler)
+	mux.HandleFunc("GET /v1/saved-searches/{id}", app.showSavedSearchHandler)
+	mux.HandleFunc("PUT /v1/saved-searches/{id}", app.updateSavedSearchHandler)
+	mux.HandleFunc("DELETE /v1/saved-searches/{id}", app.deleteSavedSearchHandler)
+	mux.HandleFunc("GET /v1/saved-searches/{id}/notes", app.listSavedSearchNotesHandler)
+
+	mux.HandleFunc("GET /v1/templates", app.listNoteTemplatesHandler)
+	mux.HandleFunc("POST /v1/templates", app.createNoteTemplateHandler)
+	mux.HandleFunc("GET /v1/templates/{id}", app.showNoteTemplateHandler)
+	mux.HandleFunc("PUT /v1/templates/{id}", app.updateNoteTemplateHandler)
+	mux.HandleFunc("DELETE /v1/templates/{id}", app.deleteNoteTemplateHandler)
+
+	mux.HandleFunc("GET /v1/shared/{token}", app.showSharedNoteHandler)
+
+	mux.HandleFunc("GET /v1/links/dangling", app.listDanglingLinksHandler)
+
+	mux.HandleFunc("GET /v1/stats", app.showStatsHandler)
+
+	mux.HandleFunc("GET /v1/calendar.ics", app.showCalendarHandler)
+
+	mux.HandleFunc("GET /v1/tasks", app.listTasksHandler)
+	mux.HandleFunc("PATCH /v1/tasks/{id}", app.updateTaskHandler)
+
+	mux.HandleFunc("GET /v1/webhooks", app.requireAuthenticatedUser(app.listWebhooksHandler))
+	mux.HandleFunc("POST /v1/webhooks", app.requireAuthenticatedUser(app.createWebhookHandler))
+	mux.HandleFunc("GET /v1/webhooks/{id}", app.requireAuthenticatedUser(app.showWebhookHandler))
+	mux.HandleFunc("PUT /v1/webhooks/{id}", app.requireAuthenticatedUser(app.updateWebhookHandler))
+	mux.HandleFunc("DELETE /v1/webhooks/{id}", app.requireAuthenticatedUser(app.deleteWebhookHandler))
+	mux.HandleFunc("GET /v1/webhooks/{id}/deliveries", app.requireAuthenticatedUser(app.listWebhookDeliveriesHandler))
+
+	mux.HandleFunc("GET /v1/audit", app.requireAuthenticatedUser(app.listAuditEventsHandler))
+	mux.HandleFunc("GET /v1/audit/verify", app.requireAuthenticatedUser(app.verifyAuditLogHandler))
+
+	mux.HandleFunc("GET /v1/sync", app.showChangesHandler)
+	mux.HandleFunc("POST /v1/sync", app.applyChangesHandler)
+
+	mux.HandleFunc("POST /v1/users", app.registerUserHandler)
+	mux.HandleFunc("PATCH /v1/users/me", app.requireAuthenticatedUser(app.updateCurrentUserHandler))
+	mux.HandleFunc("POST /v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	mux.HandleFunc("POST /v1/tokens/calendar", app.requireAuthenticatedUser(app.createCalendarTokenHandler))
+
+	// Daily notes and notes from templates live under /v1/notes/daily/ and
+	// /v1/notes/from-template/. ServeMux refuses to register them alongside
+	// the /v1/notes/{id}/... routes above: a path such as
+	// /v1/notes/daily/related matches both GET /v1/notes/daily/{date} and
+	// GET /v1/notes/{id}/related, and neither pattern is more specific than
+	// the other, so registration panics. They're routed ahead of mux instead.
+	root := http.NewServeMux()
+
+	root.Handle("/", app.jsonErrors(mux))
+	root.HandleFunc("GET /v1/notes/daily/{date}", app.requireAuthenticatedUser(app.showDailyNoteHandler))
+	root.HandleFunc("/v1/notes/daily/{date}", app.methodNotAllowed(http.MethodGet))
+	root.HandleFunc("POST /v1/notes/from-template/{id}", app.createNoteFromTemplateHandler)
+	root.HandleFunc("/v1/notes/from-template/{id}", app.methodNotAllowed(http.MethodPost))
+
+	// route finds the pattern a request will be served by without serving it,
+	// for logging, labelling metrics and naming spans.
+	route := func(r *http.Request) string {
+		if _, pattern := root.Handler(r); pattern != "/" && pattern != "" {
+			return pattern
+		}
+
+		if _, pattern := mux.Handler(r); pattern != "" {
+			return pattern
+		}
+
+		return unmatchedRoute
+	}
+
+	return app.requestID(app.logRequest(route, app.instrument(route, app.traceRequest(route, app.recoverPanic(app.enableCORS(app.authenticate(root)))))))
+}
+
+// methodNotAllowed answers requests to a root route made with a method other
+// than allowed.
+func (app *application) methodNotAllowed(allowed string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allowed)
+		app.methodNotAllowedResponse(w, r)
+	}
+}
+
+// routeMethods are the methods probed when a request matches no route, to
+// tell a wrong method apart from an unknown path.
+var routeMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// jsonErrors answers requests that match no route with the API's JSON 404
+// and 405 responses instead of the mux's plain text ones.
+func (app *application) jsonErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		var allowed []string
+
+		for _, method := range routeMethods {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+
+			if _, pattern := mux.Handler(probe); pattern != "" {
+				allowed = append(allowed, method)
+			}
+		}
+
+		if len(allowed) == 0 {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		app.methodNotAllowedResponse(w, r)
+	})
 }
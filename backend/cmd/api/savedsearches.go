@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func (app *application) createSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name  string         `json:"name"`
+		Query data.NoteQuery `json:"query"`
+		Sort  string         `json:"sort"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	search := &data.SavedSearch{
+		Name:  input.Name,
+		Query: input.Query,
+		Sort:  input.Sort,
+	}
+
+	if search.Sort == "" {
+		search.Sort = "-updated_at"
+	}
+
+	v := validator.New()
+
+	if data.ValidateSavedSearch(v, search); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.SavedSearches.Insert(search, app.contextGetUser(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/saved-searches/%d", search.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"saved_search": search}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listSavedSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	searches, err := app.models.SavedSearches.GetAll(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"saved_searches": searches}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	search, ok := app.readSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"saved_search": search}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateSavedSearchHandler replaces a saved search's name, query and sort.
+// Fields left out of the request keep their current values.
+func (app *application) updateSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	search, ok := app.readSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name    *string         `json:"name"`
+		Query   *data.NoteQuery `json:"query"`
+		Sort    *string         `json:"sort"`
+		Version *int            `json:"version"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Version != nil && *input.Version != search.Version {
+		app.versionConflictResponse(w, r)
+		return
+	}
+
+	if input.Name != nil {
+		search.Name = *input.Name
+	}
+	if input.Query != nil {
+		search.Query = *input.Query
+	}
+	if input.Sort != nil {
+		search.Sort = *input.Sort
+	}
+
+	v := validator.New()
+
+	if data.ValidateSavedSearch(v, search); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.SavedSearches.Update(search)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.versionConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"saved_search": search}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	search, ok := app.readSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.SavedSearches.Delete(search.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "saved search successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listSavedSearchNotesHandler runs a saved search and lists the matching
+// notes exactly as GET /v1/notes would. The search's own sort order applies
+// unless the request gives another.
+func (app *application) listSavedSearchNotesHandler(w http.ResponseWriter, r *http.Request) {
+	search, ok := app.readSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+
+	filters := app.readNoteFilters(r.URL.Query(), search.Sort, v)
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	app.writeNoteListing(w, r, search.Query, filters)
+}
+
+// readSavedSearch fetches the saved search named in the URL, writing a 404
+// and returning false if it doesn't exist or isn't visible to the user.
+func (app *application) readSavedSearch(w http.ResponseWriter, r *http.Request) (*data.SavedSearch, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	search, err := app.models.SavedSearches.Get(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return search, true
+}
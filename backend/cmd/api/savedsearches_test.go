@@ -0,0 +1,91 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSavedSearchHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Searcher")
+	_, otherToken := createTestUser(t, app, "Other")
+
+	tag := fmt.Sprintf("smart-%d", time.Now().UnixNano())
+
+	for _, note := range []string{
+		fmt.Sprintf(`{"title": "Quarterly report", "body": "Numbers", "tags": [%q]}`, tag),
+		fmt.Sprintf(`{"title": "Shopping", "body": "Milk", "tags": [%q]}`, tag),
+		`{"title": "Annual report", "body": "Untagged", "tags": []}`,
+	} {
+		rr := serveAs(app, token, http.MethodPost, "/v1/notes", note)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr := serveAs(app, token, http.MethodPost, "/v1/saved-searches", fmt.Sprintf(`{"name": "Reports", "query": {"text": "report", "tags": [%q]}}`, tag))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating saved search, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		SavedSearch struct {
+			ID   int64  `json:"id"`
+			Sort string `json:"sort"`
+		} `json:"saved_search"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	if created.SavedSearch.Sort != "-updated_at" {
+		t.Errorf("expected default sort -updated_at, got %q", created.SavedSearch.Sort)
+	}
+
+	path := fmt.Sprintf("/v1/saved-searches/%d", created.SavedSearch.ID)
+
+	tests := []struct {
+		name           string
+		token          string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedNotes  int
+	}{
+		{"run search", token, http.MethodGet, path + "/notes", "", http.StatusOK, 1},
+		{"other user cannot see search", otherToken, http.MethodGet, path, "", http.StatusNotFound, -1},
+		{"other user cannot run search", otherToken, http.MethodGet, path + "/notes", "", http.StatusNotFound, -1},
+		{"invalid sort", token, http.MethodPost, "/v1/saved-searches", `{"name": "Bad", "sort": "body"}`, http.StatusUnprocessableEntity, -1},
+		{"stale version", token, http.MethodPut, path, `{"name": "Reports", "version": 99}`, http.StatusConflict, -1},
+		{"widen query", token, http.MethodPut, path, fmt.Sprintf(`{"query": {"tags": [%q]}}`, tag), http.StatusOK, -1},
+		{"run widened search", token, http.MethodGet, path + "/notes", "", http.StatusOK, 2},
+		{"matches listing", token, http.MethodGet, "/v1/notes?tags=" + tag, "", http.StatusOK, 2},
+		{"delete search", token, http.MethodDelete, path, "", http.StatusOK, -1},
+		{"search gone", token, http.MethodGet, path + "/notes", "", http.StatusNotFound, -1},
+	}
+
+	for _, tt := range tests {
+		rr := serveAs(app, tt.token, tt.method, tt.path, tt.body)
+
+		if rr.Code != tt.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.name, tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+
+		if tt.expectedNotes >= 0 {
+			var response struct {
+				Notes []json.RawMessage `json:"notes"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Notes) != tt.expectedNotes {
+				t.Errorf("%s: expected %d notes, got %d", tt.name, tt.expectedNotes, len(response.Notes))
+			}
+		}
+	}
+}
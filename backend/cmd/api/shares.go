@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func (app *application) createShareHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		ExpiresAt *time.Time `json:"expires_at"`
+		Password  string     `json:"password"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if !app.authorizeNote(w, r, noteID, data.RoleOwner) {
+		return
+	}
+
+	share := &data.Share{
+		NoteID:    noteID,
+		ExpiresAt: input.ExpiresAt,
+	}
+
+	v := validator.New()
+
+	data.ValidateShare(v, share, input.Password)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Shares.Insert(share, input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/shared/%s", share.Token))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"share": share}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listSharesHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !app.authorizeNote(w, r, noteID, data.RoleOwner) {
+		return
+	}
+
+	shares, err := app.models.Shares.GetAllForNote(noteID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"shares": shares}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteShareHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	id, err := app.readNamedIDParam(r, "share_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !app.authorizeNote(w, r, noteID, data.RoleOwner) {
+		return
+	}
+
+	err = app.models.Shares.Delete(noteID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "share successfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showSharedNoteHandler serves a shared note to anyone holding the token.
+// Password-protected shares take the password through HTTP Basic
+// authentication so that browsers prompt for it; the username is ignored.
+func (app *application) showSharedNoteHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+
+	render := r.URL.Query().Get("render")
+
+	v := validator.New()
+
+	v.Check(validator.PermittedValue(render, "", "html"), "render", "must be html if provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	share, err := app.models.Shares.GetByToken(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	_, password, _ := r.BasicAuth()
+
+	match, err := share.PasswordMatches(password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		app.sharePasswordRequiredResponse(w, r)
+		return
+	}
+
+	note, err := app.notes(r).Get(share.NoteID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Shares.RecordAccess(share)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Keep the token out of the Referer header of any link followed from the
+	// note, and out of shared caches.
+	headers := make(http.Header)
+	headers.Set("Referrer-Policy", "no-referrer")
+	headers.Set("Cache-Control", "private, no-store")
+
+	if render == "" && !app.wantsHTML(r) {
+		err = app.writeJSON(w, http.StatusOK, envelope{"note": note}, headers)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	html, err := app.renderer.RenderNote(note.ID, note.Version, note.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if app.wantsHTML(r) {
+		err = app.writeHTML(w, http.StatusOK, note.Title, html, headers)
+	} else {
+		err = app.writeJSON(w, http.StatusOK, envelope{"note": note, "html": html}, headers)
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
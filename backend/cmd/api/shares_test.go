@@ -0,0 +1,202 @@
+package main_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func createTestShare(t *testing.T, app *testApp, noteID int64, body string) (token string, id int64) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/v1/notes/%d/shares", noteID), bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Share struct {
+			ID    int64  `json:"id"`
+			Token string `json:"token"`
+		} `json:"share"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	return response.Share.Token, response.Share.ID
+}
+
+func TestCreateShareHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	note := createTestNote(t, app, "Share Create", "Body", []string{"shares"})
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "open share",
+			path:           fmt.Sprintf("/v1/notes/%d/shares", note.ID),
+			body:           `{}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "expiring password protected share",
+			path:           fmt.Sprintf("/v1/notes/%d/shares", note.ID),
+			body:           `{"expires_at": "2999-01-01T00:00:00Z", "password": "correct horse"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "expiry in the past",
+			path:           fmt.Sprintf("/v1/notes/%d/shares", note.ID),
+			body:           `{"expires_at": "2000-01-01T00:00:00Z"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "non-existent note",
+			path:           "/v1/notes/999999/shares",
+			body:           `{}`,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			router := app.routes()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestShowSharedNoteHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	note := createTestNote(t, app, "Share Show", "# Shared heading", []string{"shares"})
+
+	open, _ := createTestShare(t, app, note.ID, `{}`)
+	protected, _ := createTestShare(t, app, note.ID, `{"password": "correct horse"}`)
+	revoked, revokedID := createTestShare(t, app, note.ID, `{}`)
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/v1/notes/%d/shares/%d", note.ID, revokedID), http.NoBody)
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d revoking share, got %d", http.StatusOK, rr.Code)
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		accept         string
+		password       string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "open share as JSON",
+			token:          open,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"title": "Share Show"`,
+		},
+		{
+			name:           "open share as HTML",
+			token:          open,
+			accept:         "text/html",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "<h1>Shared heading</h1>",
+		},
+		{
+			name:           "protected share without password",
+			token:          protected,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "protected share with wrong password",
+			token:          protected,
+			password:       "wrong horse",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "protected share with password",
+			token:          protected,
+			password:       "correct horse",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "revoked share",
+			token:          revoked,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unknown token",
+			token:          "AAAAAAAAAAAAAAAAAAAAAAAAAA",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/shared/"+tt.token, http.NoBody)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if tt.password != "" {
+				req.SetBasicAuth("", tt.password)
+			}
+			rr := httptest.NewRecorder()
+
+			router := app.routes()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected a WWW-Authenticate challenge")
+			}
+			if tt.expectedStatus == http.StatusOK && rr.Header().Get("Referrer-Policy") != "no-referrer" {
+				t.Error("expected the Referrer-Policy header to be no-referrer")
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("expected body to contain %q, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/notes/%d/shares", note.ID), http.NoBody)
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	var response struct {
+		Shares []struct {
+			AccessCount int64 `json:"access_count"`
+		} `json:"shares"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Shares) != 2 {
+		t.Fatalf("expected 2 active shares, got %d", len(response.Shares))
+	}
+	if response.Shares[0].AccessCount != 2 || response.Shares[1].AccessCount != 1 {
+		t.Errorf("expected access counts [2 1], got %+v", response.Shares)
+	}
+}
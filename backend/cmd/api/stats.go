@@ -0,0 +1,20 @@
+package main
+
+import (
+	"net/http"
+)
+
+// showStatsHandler reports totals across the notes visible to the current
+// user.
+func (app *application) showStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := app.models.Stats.Get(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,105 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Counter")
+	_, otherToken := createTestUser(t, app, "Hidden")
+
+	tag := fmt.Sprintf("stats-%d", time.Now().UnixNano())
+
+	rr := serveAs(app, token, http.MethodPost, "/v1/notes", fmt.Sprintf(`{"title": "Chores", "body": "- [x] laundry\n- [ ] dishes\n\nTODO: hoover", "tags": [%q]}`, tag))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		Note struct {
+			Stats struct {
+				Words          int `json:"words"`
+				ReadingMinutes int `json:"reading_minutes"`
+				Tasks          struct {
+					Total int `json:"total"`
+					Done  int `json:"done"`
+				} `json:"tasks"`
+			} `json:"stats"`
+		} `json:"note"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := created.Note.Stats; stats.Words != 4 || stats.ReadingMinutes != 1 || stats.Tasks.Total != 3 || stats.Tasks.Done != 1 {
+		t.Errorf("unexpected note stats %+v", stats)
+	}
+
+	rr = serveAs(app, token, http.MethodPost, "/v1/notes", fmt.Sprintf(`{"title": "More chores", "body": "Iron", "tags": [%q]}`, tag))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating note, got %d", http.StatusCreated, rr.Code)
+	}
+
+	rr = serveAs(app, otherToken, http.MethodPost, "/v1/notes", fmt.Sprintf(`{"title": "Secret", "body": "Hidden", "tags": [%q]}`, tag+"-hidden"))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating note, got %d", http.StatusCreated, rr.Code)
+	}
+
+	rr = serveAs(app, token, http.MethodGet, "/v1/stats", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Stats struct {
+			Notes         int     `json:"notes"`
+			ArchivedRatio float64 `json:"archived_ratio"`
+			NotesPerTag   []struct {
+				Tag   string `json:"tag"`
+				Notes int    `json:"notes"`
+			} `json:"notes_per_tag"`
+			NotesPerWeek []struct {
+				Week  string `json:"week"`
+				Notes int    `json:"notes"`
+			} `json:"notes_per_week"`
+		} `json:"stats"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Stats.Notes < 2 {
+		t.Errorf("expected at least 2 notes, got %d", response.Stats.Notes)
+	}
+
+	if response.Stats.ArchivedRatio < 0 || response.Stats.ArchivedRatio > 1 {
+		t.Errorf("expected archived ratio between 0 and 1, got %f", response.Stats.ArchivedRatio)
+	}
+
+	tagCounts := make(map[string]int)
+	for _, tc := range response.Stats.NotesPerTag {
+		tagCounts[tc.Tag] = tc.Notes
+	}
+
+	if tagCounts[tag] != 2 {
+		t.Errorf("expected 2 notes tagged %q, got %d", tag, tagCounts[tag])
+	}
+
+	if _, ok := tagCounts[tag+"-hidden"]; ok {
+		t.Errorf("expected another user's private tags to be left out")
+	}
+
+	if len(response.Stats.NotesPerWeek) == 0 {
+		t.Fatal("expected notes per week")
+	}
+
+	if _, err := time.Parse(time.DateOnly, response.Stats.NotesPerWeek[0].Week); err != nil {
+		t.Errorf("expected week as a date, got %q", response.Stats.NotesPerWeek[0].Week)
+	}
+}
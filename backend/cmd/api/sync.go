@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func (app *application) showChangesHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	since := app.readInt(qs, "since", 0, v)
+	limit := app.readInt(qs, "limit", 500, v)
+
+	v.Check(since >= 0, "since", "must not be negative")
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 1000, "limit", "must be a maximum of 1000")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	changes, err := app.models.Sync.WithContext(r.Context()).Changes(int64(since), limit, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"changes": changes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// applyChangesHandler applies a batch of offline edits in order. Each
+// mutation stands alone: a stale update is merged with the server's copy
+// where possible, and one that cannot be applied is reported back as a
+// conflict without stopping the rest of the batch. Mutations of notes the
+// user lacks the role for are reported back as forbidden, and any that fail
+// on the server as failed, so that the client knows which of the others were
+// applied and can retry just those.
+func (app *application) applyChangesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Mutations []*data.SyncMutation `json:"mutations"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(len(input.Mutations) > 0, "mutations", "must contain at least 1 entry")
+	v.Check(len(input.Mutations) <= 500, "mutations", "must not contain more than 500 entries")
+
+	for i, mutation := range input.Mutations {
+		mv := validator.New()
+
+		data.ValidateSyncMutation(mv, mutation)
+
+		for key, message := range mv.Errors {
+			v.AddError(fmt.Sprintf("mutations[%d].%s", i, key), message)
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	applied := []*data.SyncResult{}
+	conflicts := []*data.SyncConflict{}
+	forbidden := []*data.SyncResult{}
+	failed := []*data.SyncResult{}
+
+	// fail reports a mutation as failed, logging the error rather than
+	// sending it to the client.
+	fail := func(result *data.SyncResult, err error) {
+		app.logError(r, err)
+		result.Error = "the server encountered a problem and could not apply this change"
+		failed = append(failed, result)
+	}
+
+	for _, mutation := range input.Mutations {
+		result := &data.SyncResult{ClientID: mutation.ClientID, Op: mutation.Op}
+
+		var rewritten []*data.Note
+
+		if mutation.Op != data.SyncOpCreate {
+			required := data.RoleEditor
+			if mutation.Op == data.SyncOpDelete {
+				required = data.RoleOwner
+			}
+
+			allowed, err := app.models.Collaborators.Allows(mutation.ID, user.ID, required)
+			if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+				fail(result, err)
+				continue
+			}
+
+			if err == nil && !allowed {
+				forbidden = append(forbidden, result)
+				continue
+			}
+		}
+
+		switch mutation.Op {
+		case data.SyncOpCreate:
+			result.Note = mutation.Note()
+			result.Note.ID = 0
+			err = app.notes(r).InsertOwned(result.Note, user)
+		case data.SyncOpUpdate:
+			result.Note = mutation.Note()
+			rewritten, err = app.notes(r).Update(result.Note)
+		case data.SyncOpDelete:
+			err = app.models.Sync.WithContext(app.auditContext(r)).DeleteAtVersion(mutation.ID, mutation.Version)
+		}
+
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict), errors.Is(err, data.ErrRecordNotFound):
+				conflict, err := app.models.Sync.WithContext(r.Context()).Conflict(mutation, user.ID)
+				if err != nil {
+					result.Note = nil
+					fail(result, err)
+					continue
+				}
+				conflicts = append(conflicts, conflict)
+			default:
+				result.Note = nil
+				fail(result, err)
+			}
+			continue
+		}
+
+		if result.Note != nil {
+			app.indexNote(result.Note)
+
+			for _, other := range rewritten {
+				app.indexNote(other)
+			}
+		} else {
+			app.related.Remove(mutation.ID)
+		}
+
+		applied = append(applied, result)
+	}
+
+	for _, result := range applied {
+		if result.Op == data.SyncOpDelete {
+			app.background(app.purgeDeletedBlobs)
+			break
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"applied": applied, "conflicts": conflicts, "forbidden": forbidden, "failed": failed}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
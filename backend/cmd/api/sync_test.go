@@ -0,0 +1,220 @@
+package main_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+)
+
+func TestShowChangesHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	note := createTestNote(t, app, "Synced Note", "Body", []string{"sync"})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{
+			name:           "from the beginning",
+			query:          "",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "explicit cursor",
+			query:          "?since=0&limit=1000",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "non-numeric cursor",
+			query:          "?since=abc",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "negative cursor",
+			query:          "?since=-1",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "limit too large",
+			query:          "?limit=5000",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/sync"+tt.query, http.NoBody)
+			rr := httptest.NewRecorder()
+
+			router := app.routes()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Changes data.ChangeSet `json:"changes"`
+				}
+				if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+					t.Fatal(err)
+				}
+
+				found := false
+				for _, changed := range response.Changes.Notes {
+					if changed.ID == note.ID {
+						found = true
+					}
+				}
+				if !found && !response.Changes.HasMore {
+					t.Errorf("expected note %d in change set", note.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyChangesHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	note := createTestNote(t, app, "Offline Note", "Body", []string{"sync"})
+	stale := createTestNote(t, app, "Stale Note", "Body", []string{"sync"})
+
+	stale.Title = "Edited on server"
+	_, err := app.GetModels().Notes.Update(stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name              string
+		mutations         []map[string]interface{}
+		expectedStatus    int
+		expectedApplied   int
+		expectedConflicts int
+		expectedFailed    int
+	}{
+		{
+			name: "create and update",
+			mutations: []map[string]interface{}{
+				{"client_id": "a", "op": "create", "title": "New", "body": "Body", "tags": []string{}},
+				{"client_id": "b", "op": "update", "id": note.ID, "version": note.Version,
+					"title": "Edited offline", "body": "Body", "tags": []string{"sync"}},
+			},
+			expectedStatus:  http.StatusOK,
+			expectedApplied: 2,
+		},
+		{
+			name: "overlapping stale update is a conflict",
+			mutations: []map[string]interface{}{
+				{"client_id": "c", "op": "update", "id": stale.ID, "version": 1,
+					"title": "Edited offline", "body": "Body", "tags": []string{"sync"}},
+			},
+			expectedStatus:    http.StatusOK,
+			expectedConflicts: 1,
+		},
+		{
+			name: "a mutation failing on the server doesn't hide the rest",
+			mutations: []map[string]interface{}{
+				{"client_id": "e", "op": "create", "title": "Before", "body": "Body", "tags": []string{}},
+				{"client_id": "f", "op": "create", "title": "Unstorable", "body": "NUL \x00 byte", "tags": []string{}},
+				{"client_id": "g", "op": "create", "title": "After", "body": "Body", "tags": []string{}},
+			},
+			expectedStatus:  http.StatusOK,
+			expectedApplied: 2,
+			expectedFailed:  1,
+		},
+		{
+			name: "invalid mutation",
+			mutations: []map[string]interface{}{
+				{"client_id": "d", "op": "rename"},
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "empty batch",
+			mutations:      []map[string]interface{}{},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(map[string]interface{}{"mutations": tt.mutations})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/sync", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			router := app.routes()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Applied   []data.SyncResult   `json:"applied"`
+					Conflicts []data.SyncConflict `json:"conflicts"`
+					Failed    []data.SyncResult   `json:"failed"`
+				}
+				if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+					t.Fatal(err)
+				}
+				if len(response.Applied) != tt.expectedApplied {
+					t.Errorf("expected %d applied mutations, got %d", tt.expectedApplied, len(response.Applied))
+				}
+				if len(response.Conflicts) != tt.expectedConflicts {
+					t.Errorf("expected %d conflicts, got %d", tt.expectedConflicts, len(response.Conflicts))
+				}
+				if len(response.Failed) != tt.expectedFailed {
+					t.Errorf("expected %d failed mutations, got %d", tt.expectedFailed, len(response.Failed))
+				}
+				for _, result := range response.Failed {
+					if result.ClientID != "f" || result.Error == "" || result.Note != nil {
+						t.Errorf("expected the failed mutation to be reported with an error, got %+v", result)
+					}
+				}
+				for _, conflict := range response.Conflicts {
+					if conflict.Server == nil || conflict.Client == nil {
+						t.Error("expected conflict to carry both server and client copies")
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteNoteLeavesTombstone(t *testing.T) {
+	app := newTestApplication(t)
+
+	note := createTestNote(t, app, "Tombstoned", "Body", []string{"sync"})
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/v1/notes/%d", note.ID), http.NoBody)
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	tombstone, err := app.GetModels().Sync.GetTombstone(note.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tombstone.ID != note.ID {
+		t.Errorf("expected tombstone for note %d, got %d", note.ID, tombstone.ID)
+	}
+}
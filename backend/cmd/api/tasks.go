@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+// listTasksHandler lists the tasks found in the notes visible to the current
+// user, optionally narrowed to open or completed tasks, a tag or a note.
+func (app *application) listTasksHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	done := app.readBool(qs, "done", v)
+	tag := app.readString(qs, "tag", "")
+	noteID := app.readInt(qs, "note_id", 0, v)
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 50, v),
+		Sort:         app.readString(qs, "sort", "-updated_at"),
+		SortSafelist: []string{"updated_at", "-updated_at"},
+	}
+
+	v.Check(noteID >= 0, "note_id", "must be a positive integer")
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tasks, metadata, err := app.models.Tasks.WithContext(r.Context()).GetAll(done, tag, int64(noteID), filters, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tasks": tasks, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateTaskHandler marks a task done or not done by rewriting its line in
+// the note body. The note is saved through the usual update path, so it gets
+// a new version and revision, and edits made to it meanwhile are merged. If
+// the task has moved or changed by the time the note is read, the request
+// fails with a conflict rather than rewriting some other line.
+func (app *application) updateTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Done *bool `json:"done"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(input.Done != nil, "done", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	task, err := app.models.Tasks.WithContext(r.Context()).Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.authorizeNote(w, r, task.NoteID, data.RoleEditor) {
+		return
+	}
+
+	note, err := app.notes(r).Get(task.NoteID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The task and its note are read separately, so the note may have been
+	// edited in between. Only rewrite the task if it's still where it was
+	// found; otherwise the client should look it up again.
+	tasks := data.ParseTasks(note.Body)
+	if task.Position >= len(tasks) || tasks[task.Position].Line != task.Line || tasks[task.Position].Text != task.Text {
+		app.taskChangedResponse(w, r)
+		return
+	}
+
+	body, err := data.SetTaskDone(note.Body, task.Position, *input.Done)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if body != note.Body {
+		note.Body = body
+
+		_, err = app.notes(r).Update(note)
+		if err != nil {
+			var conflict *data.MergeConflict
+			switch {
+			case errors.As(err, &conflict):
+				app.editConflictResponse(w, r, conflict)
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		app.indexNote(note)
+
+		task, err = app.models.Tasks.WithContext(r.Context()).Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"task": task, "note": note}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
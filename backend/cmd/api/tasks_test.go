@@ -0,0 +1,98 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTaskHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Doer")
+	_, otherToken := createTestUser(t, app, "Nosy")
+
+	tag := fmt.Sprintf("tasks-%d", time.Now().UnixNano())
+
+	rr := serveAs(app, token, http.MethodPost, "/v1/notes", fmt.Sprintf(`{"title": "Trip", "body": "- [ ] passport\nTODO: book flights", "tags": [%q]}`, tag))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	listTasks := func(token, query string) []struct {
+		ID   int64  `json:"id"`
+		Text string `json:"text"`
+		Done bool   `json:"done"`
+	} {
+		rr := serveAs(app, token, http.MethodGet, "/v1/tasks?tag="+tag+query, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d listing tasks, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		var response struct {
+			Tasks []struct {
+				ID   int64  `json:"id"`
+				Text string `json:"text"`
+				Done bool   `json:"done"`
+			} `json:"tasks"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		return response.Tasks
+	}
+
+	tasks := listTasks(token, "")
+	if len(tasks) != 2 || tasks[1].Text != "book flights" {
+		t.Fatalf("expected the two tasks from the note, got %+v", tasks)
+	}
+
+	if others := listTasks(otherToken, ""); len(others) != 0 {
+		t.Errorf("expected another user to see no tasks, got %+v", others)
+	}
+
+	path := fmt.Sprintf("/v1/tasks/%d", tasks[1].ID)
+
+	tests := []struct {
+		name           string
+		token          string
+		body           string
+		expectedStatus int
+	}{
+		{"missing done", token, `{}`, http.StatusUnprocessableEntity},
+		{"other user", otherToken, `{"done": true}`, http.StatusNotFound},
+		{"complete", token, `{"done": true}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		rr := serveAs(app, tt.token, http.MethodPatch, path, tt.body)
+		if rr.Code != tt.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.name, tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr = serveAs(app, token, http.MethodPatch, path, `{"done": true}`)
+
+	var response struct {
+		Task struct {
+			Done bool `json:"done"`
+		} `json:"task"`
+		Note struct {
+			Body    string `json:"body"`
+			Version int    `json:"version"`
+		} `json:"note"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	if !response.Task.Done || response.Note.Body != "- [ ] passport\nDONE: book flights" || response.Note.Version != 2 {
+		t.Errorf("expected the TODO line to be rewritten once at version 2, got %+v", response)
+	}
+
+	if open := listTasks(token, "&done=false"); len(open) != 1 || open[0].Text != "passport" {
+		t.Errorf("expected only the passport task to be open, got %+v", open)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func (app *application) createNoteTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name  string   `json:"name"`
+		Title string   `json:"title"`
+		Body  string   `json:"body"`
+		Tags  []string `json:"tags"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	tmpl := &data.NoteTemplate{
+		Name:  input.Name,
+		Title: input.Title,
+		Body:  input.Body,
+		Tags:  input.Tags,
+	}
+
+	v := validator.New()
+
+	if data.ValidateNoteTemplate(v, tmpl); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.NoteTemplates.Insert(tmpl, app.contextGetUser(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/templates/%d", tmpl.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"template": tmpl}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listNoteTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	templates, err := app.models.NoteTemplates.GetAll(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"templates": templates}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showNoteTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl, ok := app.readNoteTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"template": tmpl}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateNoteTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl, ok := app.readNoteTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name    *string  `json:"name"`
+		Title   *string  `json:"title"`
+		Body    *string  `json:"body"`
+		Tags    []string `json:"tags"`
+		Version *int     `json:"version"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Version != nil && *input.Version != tmpl.Version {
+		app.versionConflictResponse(w, r)
+		return
+	}
+
+	if input.Name != nil {
+		tmpl.Name = *input.Name
+	}
+	if input.Title != nil {
+		tmpl.Title = *input.Title
+	}
+	if input.Body != nil {
+		tmpl.Body = *input.Body
+	}
+	if input.Tags != nil {
+		tmpl.Tags = input.Tags
+	}
+
+	v := validator.New()
+
+	if data.ValidateNoteTemplate(v, tmpl); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.NoteTemplates.Update(tmpl)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.versionConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"template": tmpl}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteNoteTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl, ok := app.readNoteTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.NoteTemplates.Delete(tmpl.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "template successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createNoteFromTemplateHandler creates a note by expanding a template with
+// the given title and variables. Dates and times are those of the current
+// user's time zone. Problems with the template itself, such as calling a
+// function with the wrong arguments, are reported against "template".
+func (app *application) createNoteFromTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl, ok := app.readNoteTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title      string            `json:"title"`
+		Variables  map[string]string `json:"variables"`
+		NotebookID *int64            `json:"notebook_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	v := validator.New()
+
+	note, err := data.ExpandNoteTemplate(tmpl, data.TemplateContext{
+		Title:     input.Title,
+		Now:       time.Now().In(user.Location()),
+		Variables: input.Variables,
+	})
+	if err != nil {
+		v.AddError("template", err.Error())
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	note.NotebookID = input.NotebookID
+
+	data.ValidateNote(v, note)
+
+	err = app.checkNoteNotebook(v, note.NotebookID, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.notes(r).InsertOwned(note, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.indexNote(note)
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/notes/%d", note.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"note": note}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) readNoteTemplate(w http.ResponseWriter, r *http.Request) (*data.NoteTemplate, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	tmpl, err := app.models.NoteTemplates.Get(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return tmpl, true
+}
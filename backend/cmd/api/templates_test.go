@@ -0,0 +1,90 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNoteTemplateHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Planner")
+	_, otherToken := createTestUser(t, app, "Stranger")
+
+	rr := serveAs(app, token, http.MethodPost, "/v1/templates", `{"name": "Trip plan", "title": "{{title}} trip", "body": "# {{.destination}}\nCreated {{date}}\n- [ ] Book flights", "tags": ["travel"]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating template, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		Template struct {
+			ID int64 `json:"id"`
+		} `json:"template"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/v1/templates/%d", created.Template.ID)
+	fromTemplate := fmt.Sprintf("/v1/notes/from-template/%d", created.Template.ID)
+
+	tests := []struct {
+		name           string
+		token          string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"invalid template", token, http.MethodPost, "/v1/templates", `{"name": "Bad", "body": "{{printf \"%d\" 1}}", "tags": []}`, http.StatusUnprocessableEntity},
+		{"other user", otherToken, http.MethodGet, path, "", http.StatusNotFound},
+		{"other user expanding", otherToken, http.MethodPost, fromTemplate, `{"title": "Japan"}`, http.StatusNotFound},
+		{"template without its variables", token, http.MethodPost, fromTemplate, `{"title": "Weekend"}`, http.StatusCreated},
+		{"wrong method", token, http.MethodGet, fromTemplate, "", http.StatusMethodNotAllowed},
+		{"stale update", token, http.MethodPut, path, `{"name": "Trips", "version": 2}`, http.StatusConflict},
+		{"update", token, http.MethodPut, path, `{"name": "Trips", "version": 1}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		rr := serveAs(app, tt.token, tt.method, tt.path, tt.body)
+		if rr.Code != tt.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.name, tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr = serveAs(app, token, http.MethodPost, fromTemplate, `{"title": "Japan", "variables": {"destination": "Tokyo"}}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Note struct {
+			Title string   `json:"title"`
+			Body  string   `json:"body"`
+			Tags  []string `json:"tags"`
+		} `json:"note"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedBody := "# Tokyo\nCreated " + time.Now().UTC().Format(time.DateOnly) + "\n- [ ] Book flights"
+
+	if response.Note.Title != "Japan trip" || response.Note.Body != expectedBody || strings.Join(response.Note.Tags, ",") != "travel" {
+		t.Errorf("expected the expanded template, got %+v", response.Note)
+	}
+
+	rr = serveAs(app, token, http.MethodDelete, path, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d deleting template, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	rr = serveAs(app, token, http.MethodPost, fromTemplate, `{"title": "Japan"}`)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for a deleted template, got %d", http.StatusNotFound, rr.Code)
+	}
+}
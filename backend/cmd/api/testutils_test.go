@@ -2,10 +2,13 @@ package main_test
 
 import (
 	"database/sql"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	api "github.com/johndennehy101/note-taking-web-app/backend/cmd/api"
 	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
@@ -59,3 +62,31 @@ func createTestNote(t *testing.T, app *testApp, title, body string, tags []strin
 
 	return note
 }
+
+// createTestUser registers a user with a unique email address and returns
+// them with an authentication token.
+func createTestUser(t *testing.T, app *testApp, name string) (*data.User, string) {
+	t.Helper()
+
+	user := &data.User{
+		Name:  name,
+		Email: fmt.Sprintf("%s-%d@example.com", strings.ToLower(name), time.Now().UnixNano()),
+	}
+
+	err := user.Password.Set("pa55word1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.GetModels().Users.Insert(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := app.GetModels().Tokens.New(user.ID, time.Hour, data.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user, token.Plaintext
+}
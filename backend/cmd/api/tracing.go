@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/johndennehy101/note-taking-web-app/backend/cmd/api"
+
+// traceContext reads and writes W3C traceparent and tracestate headers.
+var traceContext = propagation.TraceContext{}
+
+// setupTracing installs the global tracer provider, which the API and the
+// data models start their spans from, exporting to wherever cfg says. It
+// returns a function that flushes any spans not yet exported and stops
+// the provider. With no exporter spans aren't recorded, but incoming
+// trace context is still passed along.
+func setupTracing(cfg config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(traceContext)
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch cfg.tracing.exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New()
+	case "otlp":
+		exporter, err = otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(cfg.tracing.endpoint))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.tracing.exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(context.Background(),
+		resource.WithFromEnv(),
+		resource.WithAttributes(
+			semconv.ServiceName("notes-api"),
+			semconv.ServiceVersion(version),
+			semconv.DeploymentEnvironment(cfg.env),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.tracing.sampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// traceRequest serves each request in a server span named after the route
+// pattern that handles it, continuing the caller's trace if the request has
+// a traceparent header. Spans started further down, such as for decoding
+// the body or querying notes, are its children. Only the pattern is
+// recorded, not the path, so share tokens aren't sent to the trace backend.
+func (app *application) traceRequest(route func(*http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := traceContext.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		name := r.Method
+
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.UserAgentOriginal(r.UserAgent()),
+			attribute.String("request_id", app.contextGetRequestID(r)),
+		}
+
+		if pattern := route(r); pattern != unmatchedRoute {
+			name = pattern
+
+			// http.route is the path template alone, without the method
+			// the mux pattern may start with.
+			_, path, found := strings.Cut(pattern, " ")
+			if !found {
+				path = pattern
+			}
+
+			attrs = append(attrs, semconv.HTTPRoute(path))
+		}
+
+		ctx, span := app.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+		defer span.End()
+
+		rw := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rw.status))
+
+		if rw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rw.status))
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	app := newTestApplication(t)
+
+	note := createTestNote(t, app, "Traced", "Follow me", []string{})
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/notes/%d", note.ID), http.NoBody)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	rr = serveAs(app, "", http.MethodPost, "/v1/notes", `{"title": "Also traced", "body": "New trace", "tags": []}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	_, token := createTestUser(t, app, "Tracer")
+
+	rr = serveAs(app, token, http.MethodGet, "/v1/tasks", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	child := func(name string, parent sdktrace.ReadOnlySpan) {
+		t.Helper()
+
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("expected a %s span", name)
+			return
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("expected %s to be a child of %s", name, parent.Name())
+		}
+	}
+
+	show, ok := spans["GET /v1/notes/{id}"]
+	if !ok {
+		t.Fatalf("expected a span for the show route, got %v", spans)
+	}
+
+	if show.SpanKind() != trace.SpanKindServer {
+		t.Errorf("expected a server span, got %s", show.SpanKind())
+	}
+	if got := show.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the trace from traceparent to be continued, got %s", got)
+	}
+	if got := show.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("expected the caller's span as parent, got %s", got)
+	}
+
+	attrs := map[string]string{}
+	for _, attr := range show.Attributes() {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	if attrs["http.route"] != "/v1/notes/{id}" || attrs["http.response.status_code"] != "200" {
+		t.Errorf("unexpected span attributes %v", attrs)
+	}
+	if _, ok := attrs["url.path"]; ok {
+		t.Errorf("expected the path not to be recorded, got %v", attrs)
+	}
+
+	child("notes.get", show)
+
+	create, ok := spans["POST /v1/notes"]
+	if !ok {
+		t.Fatalf("expected a span for the create route, got %v", spans)
+	}
+
+	if create.SpanContext().TraceID() == show.SpanContext().TraceID() || create.Parent().IsValid() {
+		t.Error("expected a request without traceparent to start a new trace")
+	}
+
+	child("json.decode", create)
+	child("notes.insert", create)
+
+	tasks, ok := spans["GET /v1/tasks"]
+	if !ok {
+		t.Fatalf("expected a span for the tasks route, got %v", spans)
+	}
+
+	child("users.get_for_token", tasks)
+	child("tasks.get_all", tasks)
+
+	for name, span := range spans {
+		if (strings.HasPrefix(name, "notes.") || strings.HasPrefix(name, "tasks.")) && span.SpanKind() != trace.SpanKindClient {
+			t.Errorf("expected %s to be a client span, got %s", name, span.SpanKind())
+		}
+	}
+}
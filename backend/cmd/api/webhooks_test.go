@@ -0,0 +1,113 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWebhookHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Integrator")
+	_, otherToken := createTestUser(t, app, "Stranger")
+
+	rr := serveAs(app, token, http.MethodPost, "/v1/webhooks", `{"url": "https://example.com/hooks/notes", "events": ["note.created", "note.deleted"]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating webhook, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		Webhook struct {
+			ID     int64 `json:"id"`
+			Active bool  `json:"active"`
+		} `json:"webhook"`
+		Secret string `json:"secret"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	if !created.Webhook.Active || created.Secret == "" {
+		t.Errorf("expected an active webhook and its secret, got %+v", created)
+	}
+
+	path := fmt.Sprintf("/v1/webhooks/%d", created.Webhook.ID)
+
+	tests := []struct {
+		name           string
+		token          string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"anonymous", "", http.MethodGet, "/v1/webhooks", "", http.StatusUnauthorized},
+		{"unknown event", token, http.MethodPost, "/v1/webhooks", `{"url": "https://example.com/hook", "events": ["note.viewed"]}`, http.StatusUnprocessableEntity},
+		{"insecure scheme", token, http.MethodPost, "/v1/webhooks", `{"url": "ftp://example.com/hook", "events": ["note.created"]}`, http.StatusUnprocessableEntity},
+		{"other user", otherToken, http.MethodGet, path, "", http.StatusNotFound},
+		{"other user deleting", otherToken, http.MethodDelete, path, "", http.StatusNotFound},
+		{"show", token, http.MethodGet, path, "", http.StatusOK},
+		{"stale update", token, http.MethodPut, path, `{"active": false, "version": 2}`, http.StatusConflict},
+		{"short secret", token, http.MethodPut, path, `{"secret": "abc"}`, http.StatusUnprocessableEntity},
+		{"update", token, http.MethodPut, path, `{"events": ["note.created"], "version": 1}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		rr := serveAs(app, tt.token, tt.method, tt.path, tt.body)
+		if rr.Code != tt.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.name, tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr = serveAs(app, token, http.MethodGet, path, "")
+	if strings.Contains(rr.Body.String(), created.Secret) {
+		t.Error("expected the secret not to be shown again")
+	}
+
+	rr = serveAs(app, token, http.MethodPost, "/v1/notes", `{"title": "Quarterly goals", "body": "Grow", "tags": []}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating note, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	rr = serveAs(app, token, http.MethodGet, path+"/deliveries", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d listing deliveries, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Deliveries []struct {
+			Event   string `json:"event"`
+			Status  string `json:"status"`
+			Payload struct {
+				Note struct {
+					Title string `json:"title"`
+				} `json:"note"`
+			} `json:"payload"`
+		} `json:"deliveries"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Deliveries) != 1 {
+		t.Fatalf("expected one queued delivery, got %d", len(response.Deliveries))
+	}
+
+	delivery := response.Deliveries[0]
+	if delivery.Event != "note.created" || delivery.Status != "pending" || delivery.Payload.Note.Title != "Quarterly goals" {
+		t.Errorf("unexpected delivery %+v", delivery)
+	}
+
+	rr = serveAs(app, token, http.MethodDelete, path, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d deleting webhook, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	rr = serveAs(app, token, http.MethodGet, path+"/deliveries", "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for a deleted webhook, got %d", http.StatusNotFound, rr.Code)
+	}
+}
@@ -0,0 +1,218 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+type Attachment struct {
+	ID          int64     `json:"id"`
+	NoteID      int64     `json:"note_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"`
+	StorageKey  string    `json:"-"`
+}
+
+type AttachmentModel struct {
+	DB *sql.DB
+}
+
+func (m AttachmentModel) Insert(attachment *Attachment) error {
+	query := `
+        INSERT INTO attachments (note_id, filename, content_type, size, checksum, storage_key)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at`
+
+	args := []any{
+		attachment.NoteID,
+		attachment.Filename,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.Checksum,
+		attachment.StorageKey,
+	}
+
+	return m.DB.QueryRow(query, args...).Scan(&attachment.ID, &attachment.CreatedAt)
+}
+
+func (m AttachmentModel) Get(noteID, id int64) (*Attachment, error) {
+	if noteID < 1 || id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, note_id, created_at, filename, content_type, size, checksum, storage_key
+        FROM attachments
+        WHERE note_id = $1 AND id = $2`
+
+	var attachment Attachment
+
+	err := m.DB.QueryRow(query, noteID, id).Scan(
+		&attachment.ID,
+		&attachment.NoteID,
+		&attachment.CreatedAt,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.Checksum,
+		&attachment.StorageKey,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &attachment, nil
+}
+
+func (m AttachmentModel) GetAllForNote(noteID int64) ([]*Attachment, error) {
+	query := `
+        SELECT id, note_id, created_at, filename, content_type, size, checksum, storage_key
+        FROM attachments
+        WHERE note_id = $1
+        ORDER BY id`
+
+	rows, err := m.DB.Query(query, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*Attachment{}
+
+	for rows.Next() {
+		var attachment Attachment
+
+		err := rows.Scan(
+			&attachment.ID,
+			&attachment.NoteID,
+			&attachment.CreatedAt,
+			&attachment.Filename,
+			&attachment.ContentType,
+			&attachment.Size,
+			&attachment.Checksum,
+			&attachment.StorageKey,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, &attachment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// Delete removes an attachment and queues its blob for removal from the
+// blob store.
+func (m AttachmentModel) Delete(noteID, id int64) error {
+	if noteID < 1 || id < 1 {
+		return ErrRecordNotFound
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO blob_deletions (storage_key)
+        SELECT storage_key FROM attachments WHERE note_id = $1 AND id = $2
+        ON CONFLICT DO NOTHING`
+
+	_, err = tx.Exec(query, noteID, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM attachments WHERE note_id = $1 AND id = $2`, noteID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return tx.Commit()
+}
+
+// PendingBlobDeletions returns storage keys of blobs whose attachments or
+// notes have been deleted but which are still in the blob store.
+func (m AttachmentModel) PendingBlobDeletions(limit int) ([]string, error) {
+	query := `
+        SELECT storage_key
+        FROM blob_deletions
+        ORDER BY queued_at
+        LIMIT $1`
+
+	rows, err := m.DB.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+
+	for rows.Next() {
+		var key string
+
+		err := rows.Scan(&key)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (m AttachmentModel) ConfirmBlobDeletion(key string) error {
+	_, err := m.DB.Exec(`DELETE FROM blob_deletions WHERE storage_key = $1`, key)
+	return err
+}
+
+// queueBlobDeletions queues the blobs of every attachment on a note that is
+// about to be deleted; the attachment rows themselves go with the note.
+func queueBlobDeletions(tx *sql.Tx, noteID int64) error {
+	query := `
+        INSERT INTO blob_deletions (storage_key)
+        SELECT storage_key FROM attachments WHERE note_id = $1
+        ON CONFLICT DO NOTHING`
+
+	_, err := tx.Exec(query, noteID)
+	return err
+}
+
+func ValidateAttachment(v *validator.Validator, attachment *Attachment, maxSize int64) {
+	v.Check(attachment.Filename != "", "filename", "must be provided")
+	v.Check(len(attachment.Filename) <= 255, "filename", "must not be more than 255 bytes long")
+
+	v.Check(attachment.Size > 0, "file", "must not be empty")
+	v.Check(attachment.Size <= maxSize, "file", "must not be larger than the attachment size limit")
+}
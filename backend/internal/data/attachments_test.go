@@ -0,0 +1,158 @@
+package data_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func TestValidateAttachment(t *testing.T) {
+	tests := []struct {
+		name       string
+		attachment data.Attachment
+		valid      bool
+	}{
+		{
+			name:       "valid attachment",
+			attachment: data.Attachment{Filename: "diagram.png", Size: 1024},
+			valid:      true,
+		},
+		{
+			name:       "missing filename",
+			attachment: data.Attachment{Size: 1024},
+		},
+		{
+			name:       "filename too long",
+			attachment: data.Attachment{Filename: strings.Repeat("a", 256), Size: 1024},
+		},
+		{
+			name:       "empty file",
+			attachment: data.Attachment{Filename: "empty.txt"},
+		},
+		{
+			name:       "file over the size limit",
+			attachment: data.Attachment{Filename: "huge.bin", Size: 2049},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			data.ValidateAttachment(v, &tt.attachment, 2048)
+
+			if v.Valid() != tt.valid {
+				t.Errorf("expected valid = %t, got errors %v", tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestAttachmentModel(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	notes := data.NoteModel{DB: db}
+	attachments := data.AttachmentModel{DB: db}
+
+	note := &data.Note{Title: "Attachment Note", Body: "Body", Tags: []string{}}
+	if err := notes.Insert(note); err != nil {
+		t.Fatal(err)
+	}
+
+	insert := func(key string) *data.Attachment {
+		attachment := &data.Attachment{
+			NoteID:      note.ID,
+			Filename:    key + ".txt",
+			ContentType: "text/plain; charset=utf-8",
+			Size:        5,
+			Checksum:    "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			StorageKey:  "notes/test/" + key,
+		}
+		if err := attachments.Insert(attachment); err != nil {
+			t.Fatal(err)
+		}
+		return attachment
+	}
+
+	first := insert("first")
+	second := insert("second")
+
+	pending := func() map[string]bool {
+		keys, err := attachments.PendingBlobDeletions(100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		set := map[string]bool{}
+		for _, key := range keys {
+			set[key] = true
+		}
+		return set
+	}
+
+	t.Run("get and list", func(t *testing.T) {
+		got, err := attachments.Get(note.ID, first.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.StorageKey != first.StorageKey {
+			t.Errorf("expected storage key %q, got %q", first.StorageKey, got.StorageKey)
+		}
+
+		_, err = attachments.Get(note.ID+1, first.ID)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("expected ErrRecordNotFound for another note's attachment, got %v", err)
+		}
+
+		all, err := attachments.GetAllForNote(note.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 2 {
+			t.Errorf("expected 2 attachments, got %d", len(all))
+		}
+	})
+
+	t.Run("deleting an attachment queues its blob", func(t *testing.T) {
+		if err := attachments.Delete(note.ID, first.ID); err != nil {
+			t.Fatal(err)
+		}
+		if !pending()[first.StorageKey] {
+			t.Errorf("expected %q to be queued for deletion", first.StorageKey)
+		}
+
+		err := attachments.Delete(note.ID, first.ID)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("expected ErrRecordNotFound on second delete, got %v", err)
+		}
+
+		if err := attachments.ConfirmBlobDeletion(first.StorageKey); err != nil {
+			t.Fatal(err)
+		}
+		if pending()[first.StorageKey] {
+			t.Errorf("expected %q to be removed from the queue", first.StorageKey)
+		}
+	})
+
+	t.Run("deleting the note queues remaining blobs", func(t *testing.T) {
+		if err := notes.Delete(note.ID); err != nil {
+			t.Fatal(err)
+		}
+		if !pending()[second.StorageKey] {
+			t.Errorf("expected %q to be queued for deletion", second.StorageKey)
+		}
+
+		all, err := attachments.GetAllForNote(note.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 0 {
+			t.Errorf("expected attachments to be removed with the note, got %d", len(all))
+		}
+	})
+}
@@ -0,0 +1,386 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+const (
+	AuditNoteCreate     = "note.create"
+	AuditNoteUpdate     = "note.update"
+	AuditNoteArchive    = "note.archive"
+	AuditNoteRestore    = "note.restore"
+	AuditNoteDelete     = "note.delete"
+	AuditNoteMove       = "note.move"
+	AuditNotePin        = "note.pin"
+	AuditNoteUnpin      = "note.unpin"
+	AuditNoteFavorite   = "note.favorite"
+	AuditNoteUnfavorite = "note.unfavorite"
+	AuditUserRegister   = "user.register"
+	AuditUserUpdate     = "user.update"
+)
+
+var AuditActions = []string{
+	AuditNoteCreate,
+	AuditNoteUpdate,
+	AuditNoteArchive,
+	AuditNoteRestore,
+	AuditNoteDelete,
+	AuditNoteMove,
+	AuditNotePin,
+	AuditNoteUnpin,
+	AuditNoteFavorite,
+	AuditNoteUnfavorite,
+	AuditUserRegister,
+	AuditUserUpdate,
+}
+
+var AuditSortSafelist = []string{"id", "-id"}
+
+// auditLockKey is the transaction-level advisory lock that serializes
+// appends, so that each event is chained to the one committed before it.
+const auditLockKey = 0x61756469
+
+// AuditEvent records who changed what and when. ActorID is nil for the
+// anonymous user and NoteID is nil for account changes. The versions are
+// those of the note, or of the user for account changes, either side of
+// the change.
+//
+// Each event's Hash covers its own fields and the Hash of the event before
+// it, which is kept in PrevHash, so editing, removing or reordering an
+// entry breaks the chain from that point on.
+type AuditEvent struct {
+	ID            int64     `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	ActorID       *int64    `json:"actor_id"`
+	Action        string    `json:"action"`
+	NoteID        *int64    `json:"note_id"`
+	BeforeVersion *int      `json:"before_version"`
+	AfterVersion  *int      `json:"after_version"`
+	RequestID     string    `json:"request_id"`
+	ClientIP      string    `json:"client_ip"`
+	UserAgent     string    `json:"user_agent"`
+	PrevHash      string    `json:"prev_hash"`
+	Hash          string    `json:"hash"`
+}
+
+// AuditSource is who is making changes and where their request came from,
+// as recorded on the audit events for those changes. ActorID is nil for the
+// anonymous user.
+type AuditSource struct {
+	ActorID   *int64
+	RequestID string
+	ClientIP  string
+	UserAgent string
+}
+
+type auditSourceContextKey struct{}
+
+// ContextWithAuditSource returns a copy of ctx carrying source. Models given
+// the context with WithContext audit their changes as made by source.
+func ContextWithAuditSource(ctx context.Context, source AuditSource) context.Context {
+	return context.WithValue(ctx, auditSourceContextKey{}, source)
+}
+
+// auditSourceFrom returns the source carried by ctx, or an anonymous one
+// if there isn't one.
+func auditSourceFrom(ctx context.Context) AuditSource {
+	if ctx == nil {
+		return AuditSource{}
+	}
+
+	source, _ := ctx.Value(auditSourceContextKey{}).(AuditSource)
+	return source
+}
+
+// event returns an event for action made by the source. Zero versions are
+// left out.
+func (s AuditSource) event(action string, noteID *int64, before, after int) *AuditEvent {
+	event := &AuditEvent{
+		ActorID:   s.ActorID,
+		Action:    action,
+		NoteID:    noteID,
+		RequestID: s.RequestID,
+		ClientIP:  s.ClientIP,
+		UserAgent: s.UserAgent,
+	}
+
+	if before > 0 {
+		event.BeforeVersion = &before
+	}
+	if after > 0 {
+		event.AfterVersion = &after
+	}
+
+	return event
+}
+
+// auditNote appends an event for action on a note to the log as part of tx.
+func auditNote(tx *sql.Tx, source AuditSource, action string, noteID int64, before, after int) error {
+	return appendAuditEvent(tx, source.event(action, &noteID, before, after))
+}
+
+// AuditQuery narrows down a listing of audit events. Zero values match
+// everything.
+type AuditQuery struct {
+	Action  string
+	NoteID  int64
+	ActorID int64
+	Since   *time.Time
+	Until   *time.Time
+}
+
+// AuditVerification is the result of checking the hash chain. BrokenAt is
+// the ID of the first event that doesn't match its hash or its predecessor.
+type AuditVerification struct {
+	Valid      bool      `json:"valid"`
+	Checked    int       `json:"checked"`
+	BrokenAt   *int64    `json:"broken_at,omitempty"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+// ComputeHash returns the hex SHA-256 hash of the event's fields chained to
+// PrevHash. ID isn't covered as it's only assigned on insert; the chain
+// fixes the order instead.
+func (e *AuditEvent) ComputeHash() string {
+	fields, _ := json.Marshal([]any{
+		e.PrevHash,
+		e.CreatedAt.UTC().Format(time.RFC3339),
+		e.ActorID,
+		e.Action,
+		e.NoteID,
+		e.BeforeVersion,
+		e.AfterVersion,
+		e.RequestID,
+		e.ClientIP,
+		e.UserAgent,
+	})
+
+	sum := sha256.Sum256(fields)
+
+	return hex.EncodeToString(sum[:])
+}
+
+type AuditModel struct {
+	DB  *sql.DB
+	ctx context.Context
+}
+
+// WithContext returns a copy of the model whose queries are traced as part
+// of ctx.
+func (m AuditModel) WithContext(ctx context.Context) AuditModel {
+	m.ctx = context.WithoutCancel(ctx)
+	return m
+}
+
+// Insert appends an event to the log in a transaction of its own. Changes
+// to notes and users are instead audited as part of the transaction that
+// makes them, so that neither is committed without the other.
+func (m AuditModel) Insert(event *AuditEvent) error {
+	ctx, span := startSpan(m.ctx, "audit.insert", "audit_events")
+	defer span.End()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = appendAuditEvent(tx, event)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// appendAuditEvent appends an event to the log as part of tx, filling in
+// its time and chaining it to the latest event. Appends are serialized by a
+// lock that's held until tx ends, so it should be the last thing tx does
+// before committing.
+func appendAuditEvent(tx *sql.Tx, event *AuditEvent) error {
+	_, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, auditLockKey)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1`).Scan(&event.PrevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	event.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	event.Hash = event.ComputeHash()
+
+	query := `
+        INSERT INTO audit_events (created_at, actor_id, action, note_id, before_version, after_version,
+            request_id, client_ip, user_agent, prev_hash, hash)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        RETURNING id`
+
+	args := []any{
+		event.CreatedAt,
+		event.ActorID,
+		event.Action,
+		event.NoteID,
+		event.BeforeVersion,
+		event.AfterVersion,
+		event.RequestID,
+		event.ClientIP,
+		event.UserAgent,
+		event.PrevHash,
+		event.Hash,
+	}
+
+	return tx.QueryRow(query, args...).Scan(&event.ID)
+}
+
+// GetAll returns a page of the events a user may see: those they performed
+// and those on notes they can currently see. Where a request came from is
+// only given for the user's own events; for anyone else's, the request ID,
+// client IP and user agent are left blank.
+func (m AuditModel) GetAll(auditQuery AuditQuery, filters Filters, userID int64) ([]*AuditEvent, Metadata, error) {
+	ctx, span := startSpan(m.ctx, "audit.get_all", "audit_events")
+	defer span.End()
+
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), e.id, e.created_at, e.actor_id, e.action, e.note_id, e.before_version, e.after_version,
+            CASE WHEN e.actor_id = $1 THEN e.request_id ELSE '' END,
+            CASE WHEN e.actor_id = $1 THEN e.client_ip ELSE '' END,
+            CASE WHEN e.actor_id = $1 THEN e.user_agent ELSE '' END,
+            e.prev_hash, e.hash
+        FROM audit_events e
+        LEFT JOIN notes n ON n.id = e.note_id
+        WHERE (e.actor_id = $1 OR (n.id IS NOT NULL AND `+visibleTo("n", 1)+`))
+        AND (e.action = $2 OR $2 = '')
+        AND (e.note_id = $3 OR $3 = 0)
+        AND (e.actor_id = $4 OR $4 = 0)
+        AND (e.created_at >= $5 OR $5::timestamptz IS NULL)
+        AND (e.created_at < $6 OR $6::timestamptz IS NULL)
+        ORDER BY e.%s %s
+        LIMIT $7 OFFSET $8`, filters.sortColumn(), filters.sortDirection())
+
+	args := []any{
+		userID,
+		auditQuery.Action,
+		auditQuery.NoteID,
+		auditQuery.ActorID,
+		auditQuery.Since,
+		auditQuery.Until,
+		filters.limit(),
+		filters.offset(),
+	}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	events := []*AuditEvent{}
+
+	for rows.Next() {
+		var event AuditEvent
+
+		err := rows.Scan(append([]any{&totalRecords}, auditEventFields(&event)...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		events = append(events, &event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return events, metadata, nil
+}
+
+// Verify walks the whole log in order, checking every event's hash and its
+// link to the event before it.
+func (m AuditModel) Verify() (*AuditVerification, error) {
+	ctx, span := startSpan(m.ctx, "audit.verify", "audit_events")
+	defer span.End()
+
+	query := `
+        SELECT id, created_at, actor_id, action, note_id, before_version, after_version,
+            request_id, client_ip, user_agent, prev_hash, hash
+        FROM audit_events
+        ORDER BY id`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &AuditVerification{Valid: true, VerifiedAt: time.Now().UTC().Truncate(time.Second)}
+	prevHash := ""
+
+	for rows.Next() {
+		var event AuditEvent
+
+		err := rows.Scan(auditEventFields(&event)...)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Checked++
+
+		if event.PrevHash != prevHash || event.ComputeHash() != event.Hash {
+			result.Valid = false
+			result.BrokenAt = &event.ID
+			return result, nil
+		}
+
+		prevHash = event.Hash
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func auditEventFields(event *AuditEvent) []any {
+	return []any{
+		&event.ID,
+		&event.CreatedAt,
+		&event.ActorID,
+		&event.Action,
+		&event.NoteID,
+		&event.BeforeVersion,
+		&event.AfterVersion,
+		&event.RequestID,
+		&event.ClientIP,
+		&event.UserAgent,
+		&event.PrevHash,
+		&event.Hash,
+	}
+}
+
+func ValidateAuditQuery(v *validator.Validator, query AuditQuery) {
+	if query.Action != "" {
+		v.Check(validator.PermittedValue(query.Action, AuditActions...), "action", "invalid action")
+	}
+
+	v.Check(query.NoteID >= 0, "note_id", "must be a positive integer")
+	v.Check(query.ActorID >= 0, "actor_id", "must be a positive integer")
+
+	if query.Since != nil && query.Until != nil {
+		v.Check(query.Until.After(*query.Since), "until", "must be after since")
+	}
+}
@@ -0,0 +1,247 @@
+package data_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
+)
+
+func TestAuditEventComputeHash(t *testing.T) {
+	actor, noteID, version := int64(3), int64(9), 2
+
+	base := func() data.AuditEvent {
+		return data.AuditEvent{
+			CreatedAt:    time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+			ActorID:      &actor,
+			Action:       data.AuditNoteUpdate,
+			NoteID:       &noteID,
+			AfterVersion: &version,
+			RequestID:    "abc",
+			ClientIP:     "192.0.2.1",
+			UserAgent:    "curl/8.0",
+			PrevHash:     "00",
+		}
+	}
+
+	event := base()
+	hash := event.ComputeHash()
+
+	if len(hash) != 64 {
+		t.Fatalf("expected a hex SHA-256 hash, got %q", hash)
+	}
+
+	local := base()
+	local.CreatedAt = local.CreatedAt.In(time.FixedZone("UTC+2", 2*60*60))
+	if local.ComputeHash() != hash {
+		t.Error("expected the hash not to depend on the time zone")
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(e *data.AuditEvent)
+	}{
+		{"previous hash", func(e *data.AuditEvent) { e.PrevHash = "01" }},
+		{"time", func(e *data.AuditEvent) { e.CreatedAt = e.CreatedAt.Add(time.Second) }},
+		{"actor", func(e *data.AuditEvent) { e.ActorID = nil }},
+		{"action", func(e *data.AuditEvent) { e.Action = data.AuditNoteDelete }},
+		{"note", func(e *data.AuditEvent) { e.NoteID = nil }},
+		{"before version", func(e *data.AuditEvent) { e.BeforeVersion = &version }},
+		{"after version", func(e *data.AuditEvent) { e.AfterVersion = nil }},
+		{"request ID", func(e *data.AuditEvent) { e.RequestID = "abd" }},
+		{"client IP", func(e *data.AuditEvent) { e.ClientIP = "192.0.2.2" }},
+		{"user agent", func(e *data.AuditEvent) { e.UserAgent = "" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := base()
+			tt.tamper(&event)
+
+			if event.ComputeHash() == hash {
+				t.Error("expected the change to alter the hash")
+			}
+		})
+	}
+}
+
+func TestAuditModel(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	models := data.NewModels(db)
+
+	user := &data.User{Name: "audited", Email: fmt.Sprintf("audited-%d@example.com", time.Now().UnixNano())}
+	if err := user.Password.Set("pa55word1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Users.Insert(user); err != nil {
+		t.Fatal(err)
+	}
+
+	note := &data.Note{Title: "Ledger", Body: "Entries", Tags: []string{}}
+	if err := models.Notes.InsertOwned(note, user); err != nil {
+		t.Fatal(err)
+	}
+
+	one, two := 1, 2
+
+	events := []*data.AuditEvent{
+		{ActorID: &user.ID, Action: data.AuditNoteCreate, NoteID: &note.ID, AfterVersion: &one},
+		{ActorID: &user.ID, Action: data.AuditNoteUpdate, NoteID: &note.ID, BeforeVersion: &one, AfterVersion: &two},
+		{ActorID: &user.ID, Action: data.AuditUserUpdate, BeforeVersion: &one, AfterVersion: &two},
+	}
+
+	for _, event := range events {
+		if err := models.Audit.Insert(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if events[1].PrevHash != events[0].Hash || events[2].PrevHash != events[1].Hash {
+		t.Error("expected each event to be chained to the one before it")
+	}
+
+	filters := data.Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: data.AuditSortSafelist}
+
+	got, metadata, err := models.Audit.GetAll(data.AuditQuery{ActorID: user.ID}, filters, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 || metadata.TotalRecords != 4 || got[0].Action != data.AuditUserRegister || got[3].Hash != events[2].Hash {
+		t.Errorf("expected the user's registration and 3 events, got %d", len(got))
+	}
+
+	got, _, err = models.Audit.GetAll(data.AuditQuery{NoteID: note.ID, Action: data.AuditNoteUpdate}, filters, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != events[1].ID {
+		t.Errorf("expected only the note's update, got %d events", len(got))
+	}
+
+	got, _, err = models.Audit.GetAll(data.AuditQuery{ActorID: user.ID}, filters, user.ID+1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected other users not to see the events, got %d", len(got))
+	}
+
+	public := &data.Note{Title: "Notice board", Body: "Open to all", Tags: []string{}}
+	if err := models.Notes.Insert(public); err != nil {
+		t.Fatal(err)
+	}
+
+	other := user.ID + 1000
+	edit := &data.AuditEvent{ActorID: &other, Action: data.AuditNoteUpdate, NoteID: &public.ID, RequestID: "req", ClientIP: "192.0.2.7", UserAgent: "curl/8.0"}
+	if err := models.Audit.Insert(edit); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _, err = models.Audit.GetAll(data.AuditQuery{NoteID: public.ID, Action: data.AuditNoteUpdate}, filters, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != edit.ID {
+		t.Fatalf("expected the edit to the public note, got %d events", len(got))
+	}
+	if got[0].RequestID != "" || got[0].ClientIP != "" || got[0].UserAgent != "" {
+		t.Errorf("expected where another user's request came from to be blank, got %+v", got[0])
+	}
+
+	t.Run("changes are audited as they're made", func(t *testing.T) {
+		source := data.AuditSource{ActorID: &user.ID, RequestID: "req-1", ClientIP: "192.0.2.1", UserAgent: "curl/8.0"}
+		notes := models.Notes.WithContext(data.ContextWithAuditSource(context.Background(), source))
+
+		note := &data.Note{Title: "Journal", Body: "Day one", Tags: []string{}}
+		if err := notes.InsertOwned(note, user); err != nil {
+			t.Fatal(err)
+		}
+
+		note.Body = "Day two"
+		if _, err := notes.Update(note); err != nil {
+			t.Fatal(err)
+		}
+		if err := notes.SetPinned(note, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := notes.SetFavorite(note, false); err != nil {
+			t.Fatal(err)
+		}
+		if err := notes.Move(note, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := notes.Delete(note.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		got, _, err := models.Audit.GetAll(data.AuditQuery{NoteID: note.ID}, filters, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actions := []string{}
+		for _, event := range got {
+			actions = append(actions, event.Action)
+
+			if event.ActorID == nil || *event.ActorID != user.ID || event.RequestID != "req-1" || event.ClientIP != "192.0.2.1" {
+				t.Errorf("expected the event to be recorded as made by the source, got %+v", event)
+			}
+		}
+
+		expected := "note.create,note.update,note.pin,note.unfavorite,note.move,note.delete"
+		if strings.Join(actions, ",") != expected {
+			t.Errorf("expected actions %s, got %v", expected, actions)
+		}
+
+		if last := got[len(got)-1]; last.BeforeVersion == nil || *last.BeforeVersion != 2 {
+			t.Errorf("expected the deletion to record the version deleted, got %+v", last)
+		}
+	})
+
+	if _, err := db.Exec(`UPDATE audit_events SET action = 'note.delete' WHERE id = $1`, events[1].ID); err == nil {
+		t.Error("expected the log to refuse updates")
+	}
+	if _, err := db.Exec(`DELETE FROM audit_events WHERE id = $1`, events[1].ID); err == nil {
+		t.Error("expected the log to refuse deletes")
+	}
+
+	verification, err := models.Audit.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verification.Valid || verification.Checked < 3 {
+		t.Fatalf("expected the log to verify, got %+v", verification)
+	}
+
+	tamper := func(action string) {
+		t.Helper()
+
+		query := `
+            ALTER TABLE audit_events DISABLE TRIGGER audit_events_no_update;
+            UPDATE audit_events SET action = '%s' WHERE id = %d;
+            ALTER TABLE audit_events ENABLE TRIGGER audit_events_no_update;`
+
+		if _, err := db.Exec(fmt.Sprintf(query, action, events[1].ID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tamper(data.AuditNoteDelete)
+	defer tamper(data.AuditNoteUpdate)
+
+	verification, err = models.Audit.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verification.Valid || verification.BrokenAt == nil || *verification.BrokenAt != events[1].ID {
+		t.Errorf("expected tampering to be detected at event %d, got %+v", events[1].ID, verification)
+	}
+}
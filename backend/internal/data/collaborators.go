@@ -0,0 +1,299 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+	"github.com/lib/pq"
+)
+
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+var ErrDuplicateCollaborator = errors.New("duplicate collaborator")
+
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// RoleAllows reports whether role grants everything required does. Each role
+// includes the ones below it: owner > editor > viewer.
+func RoleAllows(role, required string) bool {
+	return roleRanks[role] > 0 && roleRanks[role] >= roleRanks[required]
+}
+
+// Collaborator is a user's membership of a note. Invitations are pending
+// until the invited user accepts them and grant no access until then.
+type Collaborator struct {
+	NoteID     int64      `json:"note_id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	InvitedAt  time.Time  `json:"invited_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+}
+
+type CollaboratorModel struct {
+	DB *sql.DB
+}
+
+// GetRole returns the role a user holds on a note, or "" if they hold none,
+// and whether the note has an owner at all. Notes created before
+// collaboration existed, or by anonymous clients, have no owner.
+func (m CollaboratorModel) GetRole(noteID, userID int64) (string, bool, error) {
+	if noteID < 1 {
+		return "", false, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT
+            COALESCE((
+                SELECT role FROM note_collaborators
+                WHERE note_id = n.id AND user_id = $2 AND accepted_at IS NOT NULL
+            ), ''),
+            EXISTS(SELECT 1 FROM note_collaborators WHERE note_id = n.id AND role = 'owner')
+        FROM notes n
+        WHERE n.id = $1`
+
+	var (
+		role  string
+		owned bool
+	)
+
+	err := m.DB.QueryRow(query, noteID, userID).Scan(&role, &owned)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", false, ErrRecordNotFound
+		default:
+			return "", false, err
+		}
+	}
+
+	return role, owned, nil
+}
+
+// Allows reports whether a user may act on a note with the required role.
+// Notes without an owner allow everything.
+func (m CollaboratorModel) Allows(noteID, userID int64, required string) (bool, error) {
+	role, owned, err := m.GetRole(noteID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return !owned || RoleAllows(role, required), nil
+}
+
+func (m CollaboratorModel) GetAllForNote(noteID int64) ([]*Collaborator, error) {
+	query := `
+        SELECT c.note_id, c.user_id, u.name, u.email, c.role, c.invited_at, c.accepted_at
+        FROM note_collaborators c
+        INNER JOIN users u ON u.id = c.user_id
+        WHERE c.note_id = $1
+        ORDER BY c.invited_at, c.user_id`
+
+	rows, err := m.DB.Query(query, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collaborators := []*Collaborator{}
+
+	for rows.Next() {
+		var collaborator Collaborator
+
+		err := rows.Scan(
+			&collaborator.NoteID,
+			&collaborator.UserID,
+			&collaborator.Name,
+			&collaborator.Email,
+			&collaborator.Role,
+			&collaborator.InvitedAt,
+			&collaborator.AcceptedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		collaborators = append(collaborators, &collaborator)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collaborators, nil
+}
+
+// Invite adds a pending collaborator to a note.
+func (m CollaboratorModel) Invite(collaborator *Collaborator, invitedBy int64) error {
+	query := `
+        INSERT INTO note_collaborators (note_id, user_id, role, invited_by)
+        VALUES ($1, $2, $3, $4)
+        RETURNING invited_at`
+
+	args := []any{collaborator.NoteID, collaborator.UserID, collaborator.Role, invitedBy}
+
+	err := m.DB.QueryRow(query, args...).Scan(&collaborator.InvitedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "note_collaborators_pkey"`:
+			return ErrDuplicateCollaborator
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Accept accepts a user's pending invitation to a note. The note is moved
+// to the head of the change feed so that it reaches the new collaborator on
+// their next sync.
+func (m CollaboratorModel) Accept(noteID, userID int64) (*Collaborator, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+        UPDATE note_collaborators
+        SET accepted_at = NOW()
+        WHERE note_id = $1 AND user_id = $2 AND accepted_at IS NULL
+        RETURNING role, invited_at, accepted_at`
+
+	collaborator := &Collaborator{NoteID: noteID, UserID: userID}
+
+	err = tx.QueryRow(query, noteID, userID).Scan(&collaborator.Role, &collaborator.InvitedAt, &collaborator.AcceptedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE notes SET change_seq = nextval('note_change_seq') WHERE id = $1`, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return collaborator, nil
+}
+
+// Remove removes a collaborator or withdraws their invitation. The owner
+// cannot be removed.
+func (m CollaboratorModel) Remove(noteID, userID int64) error {
+	if noteID < 1 || userID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+        DELETE FROM note_collaborators
+        WHERE note_id = $1 AND user_id = $2 AND role <> 'owner'`
+
+	result, err := m.DB.Exec(query, noteID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func insertOwner(tx *sql.Tx, noteID, userID int64) error {
+	query := `
+        INSERT INTO note_collaborators (note_id, user_id, role, invited_by, accepted_at)
+        VALUES ($1, $2, 'owner', $2, NOW())`
+
+	_, err := tx.Exec(query, noteID, userID)
+	return err
+}
+
+// visibleTo returns an SQL condition that holds for rows of the notes table
+// aliased alias which the user whose ID is bound to parameter param may
+// read: notes without an owner, and notes the user collaborates on.
+func visibleTo(alias string, param int) string {
+	return visibleToUser(alias, fmt.Sprintf("$%d", param))
+}
+
+// visibleToUser is visibleTo for a user ID given by an SQL expression, such
+// as a column of another table in the query.
+func visibleToUser(alias string, userID string) string {
+	return fmt.Sprintf(`(
+            NOT EXISTS (SELECT 1 FROM note_collaborators WHERE note_id = %[1]s.id AND role = 'owner')
+            OR EXISTS (
+                SELECT 1 FROM note_collaborators
+                WHERE note_id = %[1]s.id AND user_id = %[2]s AND accepted_at IS NOT NULL
+            )
+        )`, alias, userID)
+}
+
+// VisibleNotes reports which of the given notes the user can see. Notes that
+// don't exist are left out of the result.
+func (m CollaboratorModel) VisibleNotes(ids []int64, userID int64) (map[int64]bool, error) {
+	visible := make(map[int64]bool)
+
+	if len(ids) == 0 {
+		return visible, nil
+	}
+
+	query := `
+        SELECT n.id
+        FROM notes n
+        WHERE n.id = ANY($1) AND ` + visibleTo("n", 2)
+
+	rows, err := m.DB.Query(query, pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		visible[id] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return visible, nil
+}
+
+func ValidateCollaborator(v *validator.Validator, email, role string) {
+	ValidateEmail(v, email)
+
+	v.Check(role != "", "role", "must be provided")
+	v.Check(validator.PermittedValue(role, RoleViewer, RoleEditor), "role", "must be viewer or editor")
+}
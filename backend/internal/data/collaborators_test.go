@@ -0,0 +1,167 @@
+package data_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
+)
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role     string
+		required string
+		expected bool
+	}{
+		{data.RoleOwner, data.RoleOwner, true},
+		{data.RoleOwner, data.RoleViewer, true},
+		{data.RoleEditor, data.RoleEditor, true},
+		{data.RoleEditor, data.RoleOwner, false},
+		{data.RoleViewer, data.RoleViewer, true},
+		{data.RoleViewer, data.RoleEditor, false},
+		{"", data.RoleViewer, false},
+		{"admin", data.RoleViewer, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s requires %s", tt.role, tt.required), func(t *testing.T) {
+			if got := data.RoleAllows(tt.role, tt.required); got != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCollaboratorModel(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	models := data.NewModels(db)
+
+	newUser := func(name string) *data.User {
+		user := &data.User{Name: name, Email: fmt.Sprintf("%s-%d@example.com", name, time.Now().UnixNano())}
+		if err := user.Password.Set("pa55word1234"); err != nil {
+			t.Fatal(err)
+		}
+		if err := models.Users.Insert(user); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+
+	owner := newUser("owner")
+	member := newUser("member")
+
+	note := &data.Note{Title: "Owned Note", Body: "See [[Nobody Wrote This]]", Tags: []string{}}
+	if err := models.Notes.InsertOwned(note, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	visible := func(userID int64) bool {
+		links, err := models.Links.GetDangling(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, link := range links {
+			if link.SourceID == note.ID {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("owner", func(t *testing.T) {
+		role, owned, err := models.Collaborators.GetRole(note.ID, owner.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if role != data.RoleOwner || !owned {
+			t.Errorf("expected owner role on an owned note, got %q, %t", role, owned)
+		}
+		if !visible(owner.ID) || visible(member.ID) || visible(0) {
+			t.Error("expected the note to be visible to its owner only")
+		}
+	})
+
+	t.Run("pending invitation grants nothing", func(t *testing.T) {
+		err := models.Collaborators.Invite(&data.Collaborator{NoteID: note.ID, UserID: member.ID, Role: data.RoleEditor}, owner.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = models.Collaborators.Invite(&data.Collaborator{NoteID: note.ID, UserID: member.ID, Role: data.RoleViewer}, owner.ID)
+		if !errors.Is(err, data.ErrDuplicateCollaborator) {
+			t.Errorf("expected ErrDuplicateCollaborator, got %v", err)
+		}
+
+		allowed, err := models.Collaborators.Allows(note.ID, member.ID, data.RoleViewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed {
+			t.Error("expected a pending invitation to grant no access")
+		}
+	})
+
+	t.Run("accepted invitation", func(t *testing.T) {
+		before, err := models.Sync.Changes(0, 1000000, member.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		collaborator, err := models.Collaborators.Accept(note.ID, member.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if collaborator.Role != data.RoleEditor || collaborator.AcceptedAt == nil {
+			t.Errorf("unexpected collaborator %+v", collaborator)
+		}
+
+		allowed, err := models.Collaborators.Allows(note.ID, member.ID, data.RoleEditor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !allowed || !visible(member.ID) {
+			t.Error("expected an accepted editor to see and edit the note")
+		}
+
+		changes, err := models.Sync.Changes(before.Cursor, 1000, member.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, changed := range changes.Notes {
+			found = found || changed.ID == note.ID
+		}
+		if !found {
+			t.Error("expected the shared note in the new collaborator's change feed")
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		err := models.Collaborators.Remove(note.ID, owner.ID)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("expected the owner not to be removable, got %v", err)
+		}
+
+		if err := models.Collaborators.Remove(note.ID, member.ID); err != nil {
+			t.Fatal(err)
+		}
+		if visible(member.ID) {
+			t.Error("expected a removed collaborator to lose access")
+		}
+
+		collaborators, err := models.Collaborators.GetAllForNote(note.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(collaborators) != 1 {
+			t.Errorf("expected only the owner to remain, got %d collaborators", len(collaborators))
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// DailyTag is the tag every daily note is created with.
+const DailyTag = "daily"
+
+// NewDailyNote builds the daily note for day from a template. The note is
+// titled with the date, and "{{date}}" and "{{weekday}}" in the template are
+// replaced with the date and the name of the day.
+func NewDailyNote(day time.Time, template string) *Note {
+	date := day.Format(time.DateOnly)
+
+	replacer := strings.NewReplacer("{{date}}", date, "{{weekday}}", day.Weekday().String())
+
+	return &Note{
+		Title: date,
+		Body:  replacer.Replace(template),
+		Tags:  []string{DailyTag},
+	}
+}
+
+// GetOrCreateDaily returns the owner's daily note for day, creating it from
+// their template if there isn't one yet; created reports which happened.
+// Each user has at most one daily note per day: when concurrent requests
+// both try to create it, the primary key on daily_notes makes the later one
+// wait for the earlier to commit, roll back its own note and return the
+// earlier one instead.
+func (m NoteModel) GetOrCreateDaily(owner *User, day time.Time) (note *Note, created bool, err error) {
+	ctx, span := startSpan(m.ctx, "notes.get_or_create_daily", "notes")
+	defer span.End()
+
+	date := day.Format(time.DateOnly)
+
+	note, err = m.getDaily(ctx, owner.ID, date)
+	if !errors.Is(err, ErrRecordNotFound) {
+		return note, false, err
+	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	note = NewDailyNote(day, owner.DailyTemplate)
+
+	err = insertNote(tx, note, owner, auditSourceFrom(m.ctx))
+	if err != nil {
+		return nil, false, err
+	}
+
+	query := `
+        INSERT INTO daily_notes (user_id, day, note_id)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id, day) DO NOTHING`
+
+	result, err := tx.Exec(query, owner.ID, date, note.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if rowsAffected == 0 {
+		err = tx.Rollback()
+		if err != nil {
+			return nil, false, err
+		}
+
+		note, err = m.getDaily(ctx, owner.ID, date)
+		return note, false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, false, err
+	}
+
+	return note, true, nil
+}
+
+func (m NoteModel) getDaily(ctx context.Context, userID int64, date string) (*Note, error) {
+	var id int64
+
+	err := m.DB.QueryRowContext(ctx, `SELECT note_id FROM daily_notes WHERE user_id = $1 AND day = $2`, userID, date).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return m.WithContext(ctx).Get(id)
+}
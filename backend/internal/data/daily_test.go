@@ -0,0 +1,111 @@
+package data_test
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
+)
+
+func TestNewDailyNote(t *testing.T) {
+	day := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+
+	note := data.NewDailyNote(day, "# {{weekday}} {{date}}\n\n- [ ] ")
+
+	if note.Title != "2024-02-29" {
+		t.Errorf("expected the date as the title, got %q", note.Title)
+	}
+
+	if note.Body != "# Thursday 2024-02-29\n\n- [ ] " {
+		t.Errorf("expected the template to be filled in, got %q", note.Body)
+	}
+
+	if !slices.Equal(note.Tags, []string{data.DailyTag}) {
+		t.Errorf("expected the daily tag, got %v", note.Tags)
+	}
+}
+
+func TestNoteModel_GetOrCreateDaily(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	models := data.NewModels(db)
+
+	user := &data.User{Name: "journaler", Email: fmt.Sprintf("journaler-%d@example.com", time.Now().UnixNano()), DailyTemplate: "Today is {{weekday}}."}
+	if err := user.Password.Set("pa55word1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Users.Insert(user); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	const requests = 8
+
+	var (
+		wg      sync.WaitGroup
+		ids     [requests]int64
+		created [requests]bool
+		errs    [requests]error
+	)
+
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			note, ok, err := models.Notes.GetOrCreateDaily(user, day)
+			if err == nil {
+				ids[i], created[i] = note.ID, ok
+			}
+			errs[i] = err
+		}()
+	}
+
+	wg.Wait()
+
+	createdCount := 0
+
+	for i := range requests {
+		if errs[i] != nil {
+			t.Fatalf("request %d failed: %v", i, errs[i])
+		}
+
+		if ids[i] != ids[0] {
+			t.Errorf("expected every request to get note %d, request %d got %d", ids[0], i, ids[i])
+		}
+
+		if created[i] {
+			createdCount++
+		}
+	}
+
+	if createdCount != 1 {
+		t.Errorf("expected the note to be created once, got %d", createdCount)
+	}
+
+	note, err := models.Notes.Get(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if note.Body != "Today is Friday." || note.Title != "2024-03-01" {
+		t.Errorf("expected the note to come from the template, got %+v", note)
+	}
+
+	role, _, err := models.Collaborators.GetRole(note.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if role != data.RoleOwner {
+		t.Errorf("expected the user to own their daily note, got role %q", role)
+	}
+}
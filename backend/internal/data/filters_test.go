@@ -0,0 +1,42 @@
+package data_test
+
+import (
+	"testing"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func TestValidateFilters(t *testing.T) {
+	safelist := []string{"title", "-title"}
+
+	tests := []struct {
+		name           string
+		filters        data.Filters
+		expectedErrors []string
+	}{
+		{"valid", data.Filters{Page: 1, PageSize: 20, Sort: "-title", SortSafelist: safelist}, nil},
+		{"zero page", data.Filters{Page: 0, PageSize: 20, Sort: "title", SortSafelist: safelist}, []string{"page"}},
+		{"page too large", data.Filters{Page: 10_000_001, PageSize: 20, Sort: "title", SortSafelist: safelist}, []string{"page"}},
+		{"zero page size", data.Filters{Page: 1, PageSize: 0, Sort: "title", SortSafelist: safelist}, []string{"page_size"}},
+		{"page size too large", data.Filters{Page: 1, PageSize: 101, Sort: "title", SortSafelist: safelist}, []string{"page_size"}},
+		{"unsafe sort", data.Filters{Page: 1, PageSize: 20, Sort: "body; DROP TABLE notes", SortSafelist: safelist}, []string{"sort"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			data.ValidateFilters(v, tt.filters)
+
+			if len(v.Errors) != len(tt.expectedErrors) {
+				t.Fatalf("expected %d errors, got %v", len(tt.expectedErrors), v.Errors)
+			}
+
+			for _, key := range tt.expectedErrors {
+				if _, ok := v.Errors[key]; !ok {
+					t.Errorf("expected error for %q, got %v", key, v.Errors)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	wikiLinkRX = regexp.MustCompile(`\[\[([^\[\]\n]+)\]\]`)
+	noteRefRX  = regexp.MustCompile(`^note:(\d+)$`)
+)
+
+// Link is a wiki-style reference found in a note body, either by title as
+// [[Note Title]] or by ID as [[note:123]].
+type Link struct {
+	Text   string
+	Title  string
+	NoteID int64
+}
+
+type OutgoingLink struct {
+	Position    int    `json:"position"`
+	Text        string `json:"text"`
+	TargetID    *int64 `json:"target_id"`
+	TargetTitle string `json:"target_title,omitempty"`
+	Dangling    bool   `json:"dangling"`
+}
+
+type Backlink struct {
+	NoteID    int64     `json:"note_id"`
+	Title     string    `json:"title"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type DanglingLink struct {
+	SourceID    int64  `json:"source_id"`
+	SourceTitle string `json:"source_title"`
+	Position    int    `json:"position"`
+	Text        string `json:"text"`
+}
+
+type LinkModel struct {
+	DB *sql.DB
+}
+
+// ParseLinks returns the wiki links in body in the order they appear.
+func ParseLinks(body string) []Link {
+	links := []Link{}
+
+	for _, match := range wikiLinkRX.FindAllStringSubmatch(body, -1) {
+		text := strings.TrimSpace(match[1])
+		if text == "" {
+			continue
+		}
+
+		link := Link{Text: text}
+
+		if ref := noteRefRX.FindStringSubmatch(text); ref != nil {
+			id, err := strconv.ParseInt(ref[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			link.NoteID = id
+		} else {
+			link.Title = text
+		}
+
+		links = append(links, link)
+	}
+
+	return links
+}
+
+// RenameLinks rewrites every [[oldTitle]] link in body, ignoring case and
+// surrounding whitespace, to point at newTitle.
+func RenameLinks(body, oldTitle, newTitle string) string {
+	return wikiLinkRX.ReplaceAllStringFunc(body, func(match string) string {
+		text := strings.TrimSpace(match[2 : len(match)-2])
+		if !strings.EqualFold(text, oldTitle) {
+			return match
+		}
+		return "[[" + newTitle + "]]"
+	})
+}
+
+// GetOutgoing returns the links in a note in the order they appear. Links to
+// notes the user cannot read are reported as dangling, so that their titles
+// aren't given away.
+func (m LinkModel) GetOutgoing(id, userID int64) ([]*OutgoingLink, error) {
+	query := `
+        SELECT l.position, l.text, n.id, COALESCE(n.title, '')
+        FROM note_links l
+        LEFT JOIN notes n ON n.id = l.target_id AND ` + visibleTo("n", 2) + `
+        WHERE l.source_id = $1
+        ORDER BY l.position`
+
+	rows, err := m.DB.Query(query, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*OutgoingLink{}
+
+	for rows.Next() {
+		var link OutgoingLink
+
+		err := rows.Scan(&link.Position, &link.Text, &link.TargetID, &link.TargetTitle)
+		if err != nil {
+			return nil, err
+		}
+
+		link.Dangling = link.TargetID == nil
+		links = append(links, &link)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// GetBacklinks returns the notes linking to a note, leaving out any the user
+// cannot read.
+func (m LinkModel) GetBacklinks(id, userID int64) ([]*Backlink, error) {
+	query := `
+        SELECT DISTINCT n.id, n.title, n.updated_at
+        FROM note_links l
+        INNER JOIN notes n ON n.id = l.source_id
+        WHERE l.target_id = $1 AND ` + visibleTo("n", 2) + `
+        ORDER BY n.updated_at DESC, n.id`
+
+	rows, err := m.DB.Query(query, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	backlinks := []*Backlink{}
+
+	for rows.Next() {
+		var backlink Backlink
+
+		err := rows.Scan(&backlink.NoteID, &backlink.Title, &backlink.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		backlinks = append(backlinks, &backlink)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return backlinks, nil
+}
+
+// GetDangling returns the unresolved links in notes the user can read.
+func (m LinkModel) GetDangling(userID int64) ([]*DanglingLink, error) {
+	query := `
+        SELECT l.source_id, n.title, l.position, l.text
+        FROM note_links l
+        INNER JOIN notes n ON n.id = l.source_id
+        WHERE l.target_id IS NULL AND ` + visibleTo("n", 1) + `
+        ORDER BY l.source_id, l.position`
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*DanglingLink{}
+
+	for rows.Next() {
+		var link DanglingLink
+
+		err := rows.Scan(&link.SourceID, &link.SourceTitle, &link.Position, &link.Text)
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, &link)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// noteOwner is an SQL expression for the ID of the user owning the note
+// whose ID is given by noteID, which is NULL for notes open to everyone.
+func noteOwner(noteID string) string {
+	return fmt.Sprintf(`(SELECT user_id FROM note_collaborators WHERE note_id = %s AND role = 'owner')`, noteID)
+}
+
+// syncLinks replaces the stored links of note with those parsed from its
+// body, resolving each to a target note where one exists that the note's
+// owner can read.
+func syncLinks(tx *sql.Tx, note *Note) error {
+	_, err := tx.Exec(`DELETE FROM note_links WHERE source_id = $1`, note.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO note_links (source_id, position, text, target_title, target_ref, target_id)
+        VALUES ($1, $2, $3, $4, $5,
+            CASE
+                WHEN $5::bigint IS NOT NULL THEN (
+                    SELECT t.id FROM notes t
+                    WHERE t.id = $5 AND ` + visibleToUser("t", noteOwner("$1")) + `
+                )
+                ELSE (
+                    SELECT t.id FROM notes t
+                    WHERE lower(t.title) = lower($4) AND ` + visibleToUser("t", noteOwner("$1")) + `
+                    ORDER BY t.id LIMIT 1
+                )
+            END)`
+
+	for i, link := range ParseLinks(note.Body) {
+		var title, ref any
+		if link.NoteID != 0 {
+			ref = link.NoteID
+		} else {
+			title = link.Title
+		}
+
+		_, err = tx.Exec(query, note.ID, i, link.Text, title, ref)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveDanglingLinks points title links that had no target at note, now
+// that a note with that title exists, in the notes whose owners can read it.
+func resolveDanglingLinks(tx *sql.Tx, note *Note) error {
+	query := `
+        UPDATE note_links l
+        SET target_id = $1
+        WHERE l.target_id IS NULL AND lower(l.target_title) = lower($2)
+        AND EXISTS (
+            SELECT 1 FROM notes t
+            WHERE t.id = $1 AND ` + visibleToUser("t", noteOwner("l.source_id")) + `
+        )`
+
+	_, err := tx.Exec(query, note.ID, note.Title)
+	return err
+}
+
+// renameLinks follows a note's change of title by rewriting the [[Title]]
+// links to it in other notes' bodies, saving each as a new version and
+// queueing it for webhooks as an update. Notes whose owners can no longer
+// read it are left alone, so the new title isn't written into them. The
+// notes that were rewritten are returned.
+func renameLinks(tx *sql.Tx, note *Note, oldTitle string) ([]*Note, error) {
+	query := `
+        SELECT s.id, s.body
+        FROM notes s
+        WHERE s.id IN (
+            SELECT source_id
+            FROM note_links
+            WHERE target_id = $1 AND target_title IS NOT NULL AND source_id <> $1
+        )
+        AND EXISTS (
+            SELECT 1 FROM notes t
+            WHERE t.id = $1 AND ` + visibleToUser("t", noteOwner("s.id")) + `
+        )
+        ORDER BY s.id
+        FOR UPDATE OF s`
+
+	rows, err := tx.Query(query, note.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources, rewritten []*Note
+
+	for rows.Next() {
+		var source Note
+
+		err := rows.Scan(&source.ID, &source.Body)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		sources = append(sources, &source)
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, source := range sources {
+		body := RenameLinks(source.Body, oldTitle, note.Title)
+		if body == source.Body {
+			continue
+		}
+
+		query = `
+            UPDATE notes
+            SET body = $1, version = version + 1,
+                updated_at = NOW(), change_seq = nextval('note_change_seq')
+            WHERE id = $2
+            RETURNING created_at, updated_at, title, tags, archived, pinned, favorite, notebook_id, due_at, version`
+
+		source.Body = body
+
+		err = tx.QueryRow(query, source.Body, source.ID).Scan(
+			&source.CreatedAt,
+			&source.UpdatedAt,
+			&source.Title,
+			pq.Array(&source.Tags),
+			&source.Archived,
+			&source.Pinned,
+			&source.Favorite,
+			&source.NotebookID,
+			&source.DueAt,
+			&source.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		err = insertRevision(tx, source)
+		if err != nil {
+			return nil, err
+		}
+
+		err = syncTasks(tx, source)
+		if err != nil {
+			return nil, err
+		}
+
+		err = enqueueNoteEvent(tx, WebhookEventNoteUpdated, source.ID, source)
+		if err != nil {
+			return nil, err
+		}
+
+		rewritten = append(rewritten, source)
+	}
+
+	query = `
+        UPDATE note_links l
+        SET target_title = $1, text = $1
+        WHERE l.target_id = $2 AND l.target_title IS NOT NULL AND l.source_id <> $2
+        AND EXISTS (
+            SELECT 1 FROM notes t
+            WHERE t.id = $2 AND ` + visibleToUser("t", noteOwner("l.source_id")) + `
+        )`
+
+	_, err = tx.Exec(query, note.Title, note.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return rewritten, nil
+}
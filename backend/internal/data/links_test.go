@@ -0,0 +1,264 @@
+package data_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
+)
+
+func TestParseLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []data.Link
+	}{
+		{
+			name:     "no links",
+			body:     "Just some text with [single] brackets",
+			expected: []data.Link{},
+		},
+		{
+			name: "title link",
+			body: "See [[React Performance Optimization]] for details",
+			expected: []data.Link{
+				{Text: "React Performance Optimization", Title: "React Performance Optimization"},
+			},
+		},
+		{
+			name: "id link",
+			body: "Follows on from [[note:42]]",
+			expected: []data.Link{
+				{Text: "note:42", NoteID: 42},
+			},
+		},
+		{
+			name: "mixed links with whitespace",
+			body: "[[ Japan Travel Planning ]] and [[note:7]]\n[[]] [[  ]]",
+			expected: []data.Link{
+				{Text: "Japan Travel Planning", Title: "Japan Travel Planning"},
+				{Text: "note:7", NoteID: 7},
+			},
+		},
+		{
+			name:     "links do not span lines",
+			body:     "[[Broken\nLink]]",
+			expected: []data.Link{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links := data.ParseLinks(tt.body)
+
+			if len(links) != len(tt.expected) {
+				t.Fatalf("expected %d links, got %d: %+v", len(tt.expected), len(links), links)
+			}
+			for i := range links {
+				if links[i] != tt.expected[i] {
+					t.Errorf("expected link %+v, got %+v", tt.expected[i], links[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRenameLinks(t *testing.T) {
+	body := "See [[Old Title]], [[ old title ]] and [[Other]] or [[note:1]]"
+	expected := "See [[New Title]], [[New Title]] and [[Other]] or [[note:1]]"
+
+	got := data.RenameLinks(body, "Old Title", "New Title")
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestLinkModel(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	notes := data.NoteModel{DB: db}
+	links := data.LinkModel{DB: db}
+
+	target := &data.Note{Title: "Link Target", Body: "Body", Tags: []string{}}
+	if err := notes.Insert(target); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &data.Note{
+		Title: "Link Source",
+		Body:  fmt.Sprintf("See [[link target]], [[note:%d]] and [[Not Written Yet]]", target.ID),
+		Tags:  []string{},
+	}
+	if err := notes.Insert(source); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("outgoing links report dangling targets", func(t *testing.T) {
+		outgoing, err := links.GetOutgoing(source.ID, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(outgoing) != 3 {
+			t.Fatalf("expected 3 outgoing links, got %d", len(outgoing))
+		}
+		for i, link := range outgoing[:2] {
+			if link.Dangling || *link.TargetID != target.ID {
+				t.Errorf("expected link %d to resolve to note %d, got %+v", i, target.ID, link)
+			}
+		}
+		if !outgoing[2].Dangling {
+			t.Errorf("expected link to missing note to be dangling, got %+v", outgoing[2])
+		}
+	})
+
+	t.Run("backlinks", func(t *testing.T) {
+		backlinks, err := links.GetBacklinks(target.ID, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(backlinks) != 1 || backlinks[0].NoteID != source.ID {
+			t.Errorf("expected a single backlink from note %d, got %+v", source.ID, backlinks)
+		}
+	})
+
+	t.Run("creating the missing note resolves the dangling link", func(t *testing.T) {
+		missing := &data.Note{Title: "Not Written Yet", Body: "Body", Tags: []string{}}
+		if err := notes.Insert(missing); err != nil {
+			t.Fatal(err)
+		}
+
+		backlinks, err := links.GetBacklinks(missing.ID, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(backlinks) != 1 {
+			t.Errorf("expected dangling link to be resolved, got %+v", backlinks)
+		}
+	})
+
+	t.Run("renaming the target rewrites links", func(t *testing.T) {
+		target.Title = "Renamed Target"
+		rewritten, err := notes.Update(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rewritten) != 1 || rewritten[0].ID != source.ID {
+			t.Errorf("expected note %d to be returned as rewritten, got %+v", source.ID, rewritten)
+		}
+
+		updated, err := notes.Get(source.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := fmt.Sprintf("See [[Renamed Target]], [[note:%d]] and [[Not Written Yet]]", target.ID)
+		if updated.Body != expected {
+			t.Errorf("expected body %q, got %q", expected, updated.Body)
+		}
+		if updated.Version != source.Version+1 {
+			t.Errorf("expected rewritten note version %d, got %d", source.Version+1, updated.Version)
+		}
+
+		backlinks, err := links.GetBacklinks(target.ID, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(backlinks) != 1 {
+			t.Errorf("expected links to follow the rename, got %+v", backlinks)
+		}
+	})
+
+	t.Run("deleting the target leaves dangling links", func(t *testing.T) {
+		if err := notes.Delete(target.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		dangling, err := links.GetDangling(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		count := 0
+		for _, link := range dangling {
+			if link.SourceID == source.ID {
+				count++
+			}
+		}
+		if count != 2 {
+			t.Errorf("expected 2 dangling links from note %d, got %d", source.ID, count)
+		}
+	})
+}
+
+func TestLinksToPrivateNotes(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	models := data.NewModels(db)
+
+	newUser := func(name string) *data.User {
+		user := &data.User{Name: name, Email: fmt.Sprintf("%s-%d@example.com", name, time.Now().UnixNano())}
+		if err := user.Password.Set("pa55word1234"); err != nil {
+			t.Fatal(err)
+		}
+		if err := models.Users.Insert(user); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+
+	alice := newUser("alice")
+	bob := newUser("bob")
+
+	secret := &data.Note{Title: "Alice Secret Plans", Body: "Private", Tags: []string{}}
+	if err := models.Notes.InsertOwned(secret, alice); err != nil {
+		t.Fatal(err)
+	}
+
+	probe := &data.Note{
+		Title: "Bob Probe",
+		Body:  fmt.Sprintf("[[note:%d]] and [[Alice Secret Plans]] and [[Alice Later]]", secret.ID),
+		Tags:  []string{},
+	}
+	if err := models.Notes.InsertOwned(probe, bob); err != nil {
+		t.Fatal(err)
+	}
+
+	later := &data.Note{Title: "Alice Later", Body: "Also private", Tags: []string{}}
+	if err := models.Notes.InsertOwned(later, alice); err != nil {
+		t.Fatal(err)
+	}
+
+	outgoing, err := models.Links.GetOutgoing(probe.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outgoing) != 3 {
+		t.Fatalf("expected 3 outgoing links, got %d", len(outgoing))
+	}
+	for _, link := range outgoing {
+		if !link.Dangling || link.TargetID != nil || link.TargetTitle != "" {
+			t.Errorf("expected a link to another user's private note to be dangling, got %+v", link)
+		}
+	}
+
+	secret.Title = "Alice Renamed Plans"
+	if _, err := models.Notes.Update(secret); err != nil {
+		t.Fatal(err)
+	}
+
+	unchanged, err := models.Notes.Get(probe.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unchanged.Body != probe.Body {
+		t.Errorf("expected renaming a private note not to rewrite other users' notes, got %q", unchanged.Body)
+	}
+}
@@ -7,14 +7,45 @@ import (
 
 var (
 	ErrRecordNotFound = errors.New("record not found")
+	ErrEditConflict   = errors.New("edit conflict")
 )
 
 type Models struct {
-	Notes NoteModel
+	Attachments   AttachmentModel
+	Audit         AuditModel
+	Collaborators CollaboratorModel
+	Links         LinkModel
+	Notebooks     NotebookModel
+	NoteTemplates NoteTemplateModel
+	Notes         NoteModel
+	Reminders     ReminderModel
+	SavedSearches SavedSearchModel
+	Shares        ShareModel
+	Stats         StatsModel
+	Sync          SyncModel
+	Tasks         TaskModel
+	Tokens        TokenModel
+	Users         UserModel
+	Webhooks      WebhookModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Notes: NoteModel{DB: db},
+		Attachments:   AttachmentModel{DB: db},
+		Audit:         AuditModel{DB: db},
+		Collaborators: CollaboratorModel{DB: db},
+		Links:         LinkModel{DB: db},
+		Notebooks:     NotebookModel{DB: db},
+		NoteTemplates: NoteTemplateModel{DB: db},
+		Notes:         NoteModel{DB: db},
+		Reminders:     ReminderModel{DB: db},
+		SavedSearches: SavedSearchModel{DB: db},
+		Shares:        ShareModel{DB: db},
+		Stats:         StatsModel{DB: db},
+		Sync:          SyncModel{DB: db},
+		Tasks:         TaskModel{DB: db},
+		Tokens:        TokenModel{DB: db},
+		Users:         UserModel{DB: db},
+		Webhooks:      WebhookModel{DB: db},
 	}
 }
@@ -0,0 +1,291 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+	"github.com/lib/pq"
+)
+
+var ErrNotebookCycle = errors.New("notebook cycle")
+
+// Notebook is a folder of notes. Notebooks nest through ParentID; a nil
+// ParentID puts the notebook at the top level. Notebooks created by a
+// signed-in user are private to them, while those created anonymously are
+// open to everyone, as with notes.
+type Notebook struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	ParentID  *int64    `json:"parent_id"`
+	OwnerID   *int64    `json:"-"`
+	Version   int       `json:"version"`
+}
+
+type NotebookModel struct {
+	DB *sql.DB
+}
+
+func (m NotebookModel) Insert(notebook *Notebook, owner *User) error {
+	if !owner.IsAnonymous() {
+		notebook.OwnerID = &owner.ID
+	}
+
+	query := `
+        INSERT INTO notebooks (name, parent_id, owner_id)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at, updated_at, version`
+
+	args := []any{notebook.Name, notebook.ParentID, notebook.OwnerID}
+
+	return m.DB.QueryRow(query, args...).Scan(&notebook.ID, &notebook.CreatedAt, &notebook.UpdatedAt, &notebook.Version)
+}
+
+// Get returns a notebook if it is visible to the user.
+func (m NotebookModel) Get(id, userID int64) (*Notebook, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, created_at, updated_at, name, parent_id, owner_id, version
+        FROM notebooks
+        WHERE id = $1 AND (owner_id IS NULL OR owner_id = $2)`
+
+	var notebook Notebook
+
+	err := m.DB.QueryRow(query, id, userID).Scan(
+		&notebook.ID,
+		&notebook.CreatedAt,
+		&notebook.UpdatedAt,
+		&notebook.Name,
+		&notebook.ParentID,
+		&notebook.OwnerID,
+		&notebook.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &notebook, nil
+}
+
+// GetAll returns every notebook visible to the user as a flat list ordered
+// by name; clients rebuild the tree from each notebook's ParentID.
+func (m NotebookModel) GetAll(userID int64) ([]*Notebook, error) {
+	query := `
+        SELECT id, created_at, updated_at, name, parent_id, owner_id, version
+        FROM notebooks
+        WHERE owner_id IS NULL OR owner_id = $1
+        ORDER BY lower(name), id`
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notebooks := []*Notebook{}
+
+	for rows.Next() {
+		var notebook Notebook
+
+		err := rows.Scan(
+			&notebook.ID,
+			&notebook.CreatedAt,
+			&notebook.UpdatedAt,
+			&notebook.Name,
+			&notebook.ParentID,
+			&notebook.OwnerID,
+			&notebook.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		notebooks = append(notebooks, &notebook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notebooks, nil
+}
+
+// Update renames a notebook and moves it under a new parent. Moves are
+// serialized with a table lock so that two concurrent moves cannot together
+// form a cycle; moving a notebook under itself or one of its descendants
+// fails with ErrNotebookCycle.
+func (m NotebookModel) Update(notebook *Notebook) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if notebook.ParentID != nil {
+		_, err = tx.Exec(`LOCK TABLE notebooks IN SHARE ROW EXCLUSIVE MODE`)
+		if err != nil {
+			return err
+		}
+
+		query := `
+            WITH RECURSIVE ancestors AS (
+                SELECT id, parent_id FROM notebooks WHERE id = $1
+                UNION
+                SELECT nb.id, nb.parent_id
+                FROM notebooks nb
+                INNER JOIN ancestors a ON nb.id = a.parent_id
+            )
+            SELECT EXISTS(SELECT 1 FROM ancestors WHERE id = $2)`
+
+		var cycle bool
+
+		err = tx.QueryRow(query, *notebook.ParentID, notebook.ID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+
+		if cycle {
+			return ErrNotebookCycle
+		}
+	}
+
+	query := `
+        UPDATE notebooks
+        SET name = $1, parent_id = $2, updated_at = NOW(), version = version + 1
+        WHERE id = $3 AND version = $4
+        RETURNING updated_at, version`
+
+	args := []any{notebook.Name, notebook.ParentID, notebook.ID, notebook.Version}
+
+	err = tx.QueryRow(query, args...).Scan(&notebook.UpdatedAt, &notebook.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a notebook along with every notebook nested inside it. The
+// notes filed in them are kept but no longer belong to a notebook.
+func (m NotebookModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        WITH RECURSIVE tree AS (
+            SELECT id FROM notebooks WHERE id = $1
+            UNION ALL
+            SELECT nb.id FROM notebooks nb INNER JOIN tree t ON nb.parent_id = t.id
+        )
+        UPDATE notes
+        SET notebook_id = NULL, updated_at = NOW(), change_seq = nextval('note_change_seq')
+        WHERE notebook_id IN (SELECT id FROM tree)`
+
+	_, err = tx.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM notebooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return tx.Commit()
+}
+
+// GetNotes returns the notes in a notebook that are visible to the user.
+// With recursive set, notes in nested notebooks are included too.
+func (m NotebookModel) GetNotes(id, userID int64, recursive bool) ([]*Note, error) {
+	query := `
+        WITH RECURSIVE tree AS (
+            SELECT id FROM notebooks WHERE id = $1
+            UNION ALL
+            SELECT nb.id FROM notebooks nb INNER JOIN tree t ON nb.parent_id = t.id
+            WHERE $3
+        )
+        SELECT n.id, n.created_at, n.updated_at, n.title, n.body, n.tags, n.archived, n.pinned, n.favorite, n.notebook_id, n.due_at, n.version
+        FROM notes n
+        WHERE n.notebook_id IN (SELECT id FROM tree) AND ` + visibleTo("n", 2) + `
+        ORDER BY n.pinned DESC, n.updated_at DESC, n.id`
+
+	rows, err := m.DB.Query(query, id, userID, recursive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []*Note{}
+
+	for rows.Next() {
+		var note Note
+
+		err := rows.Scan(
+			&note.ID,
+			&note.CreatedAt,
+			&note.UpdatedAt,
+			&note.Title,
+			&note.Body,
+			pq.Array(&note.Tags),
+			&note.Archived,
+			&note.Pinned,
+			&note.Favorite,
+			&note.NotebookID,
+			&note.DueAt,
+			&note.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		notes = append(notes, &note)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+func ValidateNotebook(v *validator.Validator, notebook *Notebook) {
+	v.Check(notebook.Name != "", "name", "must be provided")
+	v.Check(len(notebook.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	if notebook.ParentID != nil {
+		v.Check(*notebook.ParentID > 0, "parent_id", "must be a positive integer")
+		v.Check(*notebook.ParentID != notebook.ID, "parent_id", "must not be the notebook itself")
+	}
+}
@@ -0,0 +1,183 @@
+package data_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func TestValidateNotebook(t *testing.T) {
+	zero := int64(0)
+	self := int64(7)
+
+	tests := []struct {
+		name     string
+		notebook data.Notebook
+		valid    bool
+	}{
+		{
+			name:     "top level notebook",
+			notebook: data.Notebook{Name: "Work"},
+			valid:    true,
+		},
+		{
+			name:     "missing name",
+			notebook: data.Notebook{},
+		},
+		{
+			name:     "name too long",
+			notebook: data.Notebook{Name: strings.Repeat("a", 501)},
+		},
+		{
+			name:     "invalid parent",
+			notebook: data.Notebook{Name: "Work", ParentID: &zero},
+		},
+		{
+			name:     "own parent",
+			notebook: data.Notebook{ID: 7, Name: "Work", ParentID: &self},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			data.ValidateNotebook(v, &tt.notebook)
+
+			if v.Valid() != tt.valid {
+				t.Errorf("expected valid = %t, got errors %v", tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestNotebookModel(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	models := data.NewModels(db)
+
+	newNotebook := func(name string, parent *data.Notebook) *data.Notebook {
+		notebook := &data.Notebook{Name: name}
+		if parent != nil {
+			notebook.ParentID = &parent.ID
+		}
+		if err := models.Notebooks.Insert(notebook, data.AnonymousUser); err != nil {
+			t.Fatal(err)
+		}
+		return notebook
+	}
+
+	newNote := func(title string, notebook *data.Notebook) *data.Note {
+		note := &data.Note{Title: title, Body: "Body", Tags: []string{}, NotebookID: &notebook.ID}
+		if err := models.Notes.Insert(note); err != nil {
+			t.Fatal(err)
+		}
+		return note
+	}
+
+	root := newNotebook("Root", nil)
+	child := newNotebook("Child", root)
+	grandchild := newNotebook("Grandchild", child)
+
+	newNote("In Root", root)
+	newNote("In Child", child)
+	deep := newNote("In Grandchild", grandchild)
+
+	t.Run("recursive listing", func(t *testing.T) {
+		direct, err := models.Notebooks.GetNotes(root.ID, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(direct) != 1 {
+			t.Errorf("expected 1 note directly in root, got %d", len(direct))
+		}
+
+		all, err := models.Notebooks.GetNotes(root.ID, 0, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 3 {
+			t.Errorf("expected 3 notes under root, got %d", len(all))
+		}
+	})
+
+	t.Run("moving under a descendant is a cycle", func(t *testing.T) {
+		root.ParentID = &grandchild.ID
+		err := models.Notebooks.Update(root)
+		if !errors.Is(err, data.ErrNotebookCycle) {
+			t.Errorf("expected ErrNotebookCycle, got %v", err)
+		}
+		root.ParentID = nil
+	})
+
+	t.Run("moving to another branch", func(t *testing.T) {
+		other := newNotebook("Other", nil)
+
+		grandchild.ParentID = &other.ID
+		if err := models.Notebooks.Update(grandchild); err != nil {
+			t.Fatal(err)
+		}
+
+		all, err := models.Notebooks.GetNotes(root.ID, 0, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 2 {
+			t.Errorf("expected 2 notes under root after the move, got %d", len(all))
+		}
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		stale := *child
+		stale.Name = "Renamed"
+		if err := models.Notebooks.Update(&stale); err != nil {
+			t.Fatal(err)
+		}
+
+		err := models.Notebooks.Update(child)
+		if !errors.Is(err, data.ErrEditConflict) {
+			t.Errorf("expected ErrEditConflict, got %v", err)
+		}
+	})
+
+	t.Run("move note", func(t *testing.T) {
+		before := deep.Version
+
+		if err := models.Notes.Move(deep, &root.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := models.Notes.Get(deep.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.NotebookID == nil || *got.NotebookID != root.ID || got.Version != before {
+			t.Errorf("expected note in notebook %d at version %d, got %+v", root.ID, before, got)
+		}
+	})
+
+	t.Run("delete unfiles notes", func(t *testing.T) {
+		if err := models.Notebooks.Delete(root.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := models.Notebooks.Get(child.ID, 0)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("expected nested notebook to be deleted, got %v", err)
+		}
+
+		got, err := models.Notes.Get(deep.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.NotebookID != nil {
+			t.Errorf("expected note to be unfiled, got notebook %d", *got.NotebookID)
+		}
+	})
+}
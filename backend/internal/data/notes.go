@@ -1,38 +1,128 @@
 package data
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
+	search "github.com/johndennehy101/note-taking-web-app/backend/internal/query"
 	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
 	"github.com/lib/pq"
 )
 
 type NoteModel struct {
-	DB *sql.DB
+	DB  *sql.DB
+	ctx context.Context
+}
+
+// WithContext returns a copy of the model whose queries are traced as part
+// of ctx, typically the context of the request being served, and whose
+// changes are audited as made by the AuditSource it carries. Cancelling ctx
+// doesn't cancel the queries.
+func (m NoteModel) WithContext(ctx context.Context) NoteModel {
+	m.ctx = context.WithoutCancel(ctx)
+	return m
 }
 
 type Note struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"-"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Title     string    `json:"title"`
-	Body      string    `json:"body"`
-	Tags      []string  `json:"tags"`
-	Archived  bool      `json:"archived"`
-	Version   int       `json:"version"`
+	ID         int64      `json:"id"`
+	CreatedAt  time.Time  `json:"-"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Title      string     `json:"title"`
+	Body       string     `json:"body"`
+	Tags       []string   `json:"tags"`
+	Archived   bool       `json:"archived"`
+	Pinned     bool       `json:"pinned"`
+	Favorite   bool       `json:"favorite"`
+	NotebookID *int64     `json:"notebook_id"`
+	DueAt      *time.Time `json:"due_at"`
+	Version    int        `json:"version"`
+}
+
+// MarshalJSON writes a note out along with its content statistics.
+func (n Note) MarshalJSON() ([]byte, error) {
+	type note Note
+
+	return json.Marshal(struct {
+		note
+		Stats NoteStats `json:"stats"`
+	}{note(n), ComputeNoteStats(n.Body)})
 }
 
 func (m NoteModel) Insert(note *Note) error {
+	return m.InsertOwned(note, AnonymousUser)
+}
+
+// InsertOwned inserts a note owned by owner. Notes inserted for the
+// anonymous user have no owner and are open to everyone.
+func (m NoteModel) InsertOwned(note *Note, owner *User) error {
+	ctx, span := startSpan(m.ctx, "notes.insert", "notes")
+	defer span.End()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertNote(tx, note, owner, auditSourceFrom(m.ctx))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertNote(tx *sql.Tx, note *Note, owner *User, source AuditSource) error {
 	query := `
-        INSERT INTO notes (title, body, tags) 
-        VALUES ($1, $2, $3)
+        INSERT INTO notes (title, body, tags, notebook_id, due_at)
+        VALUES ($1, $2, $3, $4, $5)
         RETURNING id, created_at, updated_at, version, archived`
 
-	args := []any{note.Title, note.Body, pq.Array(note.Tags)}
+	args := []any{note.Title, note.Body, pq.Array(note.Tags), note.NotebookID, note.DueAt}
+
+	err := tx.QueryRow(query, args...).Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt, &note.Version, &note.Archived)
+	if err != nil {
+		return err
+	}
+
+	if !owner.IsAnonymous() {
+		err = insertOwner(tx, note.ID, owner.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = insertRevision(tx, note)
+	if err != nil {
+		return err
+	}
+
+	err = syncLinks(tx, note)
+	if err != nil {
+		return err
+	}
+
+	err = syncTasks(tx, note)
+	if err != nil {
+		return err
+	}
+
+	err = resolveDanglingLinks(tx, note)
+	if err != nil {
+		return err
+	}
+
+	err = enqueueNoteEvent(tx, WebhookEventNoteCreated, note.ID, note)
+	if err != nil {
+		return err
+	}
 
-	return m.DB.QueryRow(query, args...).Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt, &note.Version, &note.Archived)
+	return auditNote(tx, source, AuditNoteCreate, note.ID, 0, note.Version)
 }
 
 func (m NoteModel) Get(id int64) (*Note, error) {
@@ -40,14 +130,17 @@ func (m NoteModel) Get(id int64) (*Note, error) {
 		return nil, ErrRecordNotFound
 	}
 
+	ctx, span := startSpan(m.ctx, "notes.get", "notes")
+	defer span.End()
+
 	query := `
-        SELECT id, created_at, updated_at, title, body, tags, archived, version
+        SELECT id, created_at, updated_at, title, body, tags, archived, pinned, favorite, notebook_id, due_at, version
         FROM notes
         WHERE id = $1`
 
 	var note Note
 
-	err := m.DB.QueryRow(query, id).Scan(
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
 		&note.ID,
 		&note.CreatedAt,
 		&note.UpdatedAt,
@@ -55,6 +148,10 @@ func (m NoteModel) Get(id int64) (*Note, error) {
 		&note.Body,
 		pq.Array(&note.Tags),
 		&note.Archived,
+		&note.Pinned,
+		&note.Favorite,
+		&note.NotebookID,
+		&note.DueAt,
 		&note.Version,
 	)
 
@@ -70,22 +167,443 @@ func (m NoteModel) Get(id int64) (*Note, error) {
 	return &note, nil
 }
 
-func (m NoteModel) Update(note *Note) error {
+// Update saves an edit to note, which must carry the version the edit was
+// based on. If the note has been saved by someone else since then, the edit
+// is three-way merged with theirs using the stored revision at that version
+// as the common ancestor. A clean merge is saved and copied back into note;
+// overlapping changes fail with a *MergeConflict. A change of title is
+// followed into the [[Title]] links of other notes, which are returned and
+// audited as edits too. Edits that change the archived flag are audited as
+// archiving or restoring the note.
+func (m NoteModel) Update(note *Note) ([]*Note, error) {
+	ctx, span := startSpan(m.ctx, "notes.update", "notes")
+	defer span.End()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := `
+        SELECT id, created_at, updated_at, title, body, tags, archived, due_at, version
+        FROM notes
+        WHERE id = $1
+        FOR UPDATE`
+
+	var current Note
+
+	err = tx.QueryRow(query, note.ID).Scan(
+		&current.ID,
+		&current.CreatedAt,
+		&current.UpdatedAt,
+		&current.Title,
+		&current.Body,
+		pq.Array(&current.Tags),
+		&current.Archived,
+		&current.DueAt,
+		&current.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if note.Version != current.Version {
+		base, err := getRevision(tx, note.ID, note.Version)
+		if err != nil && !errors.Is(err, ErrRecordNotFound) {
+			return nil, err
+		}
+
+		err = mergeNote(base, &current, note)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query = `
         UPDATE notes
-        SET title = $1, body = $2, tags = $3, archived = $4, version = version + 1
-        WHERE id = $5
-        RETURNING version`
+        SET title = $1, body = $2, tags = $3, archived = $4, due_at = $5, version = version + 1,
+            updated_at = NOW(), change_seq = nextval('note_change_seq')
+        WHERE id = $6
+        RETURNING created_at, updated_at, pinned, favorite, notebook_id, version`
 
 	args := []any{
 		note.Title,
 		note.Body,
 		pq.Array(note.Tags),
 		note.Archived,
+		note.DueAt,
 		note.ID,
 	}
 
-	return m.DB.QueryRow(query, args...).Scan(&note.Version)
+	err = tx.QueryRow(query, args...).Scan(&note.CreatedAt, &note.UpdatedAt, &note.Pinned, &note.Favorite, &note.NotebookID, &note.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	err = insertRevision(tx, note)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syncLinks(tx, note)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syncTasks(tx, note)
+	if err != nil {
+		return nil, err
+	}
+
+	var rewritten []*Note
+
+	if note.Title != current.Title {
+		rewritten, err = renameLinks(tx, note, current.Title)
+		if err != nil {
+			return nil, err
+		}
+
+		err = resolveDanglingLinks(tx, note)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = enqueueNoteEvent(tx, WebhookEventNoteUpdated, note.ID, note)
+	if err != nil {
+		return nil, err
+	}
+
+	action := AuditNoteUpdate
+
+	switch {
+	case note.Archived && !current.Archived:
+		action = AuditNoteArchive
+	case !note.Archived && current.Archived:
+		action = AuditNoteRestore
+	}
+
+	source := auditSourceFrom(m.ctx)
+
+	err = auditNote(tx, source, action, note.ID, current.Version, note.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, other := range rewritten {
+		err = auditNote(tx, source, AuditNoteUpdate, other.ID, other.Version-1, other.Version)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return rewritten, nil
+}
+
+// Move files a note in a notebook, or takes it out of one when notebookID is
+// nil. Moving doesn't change the note's content so its version is left
+// alone, but it is pushed to the head of the change feed for sync clients
+// and queued for webhooks as an update.
+func (m NoteModel) Move(note *Note, notebookID *int64) error {
+	ctx, span := startSpan(m.ctx, "notes.move", "notes")
+	defer span.End()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        UPDATE notes
+        SET notebook_id = $1, updated_at = NOW(), change_seq = nextval('note_change_seq')
+        WHERE id = $2
+        RETURNING notebook_id, updated_at, version`
+
+	err = tx.QueryRowContext(ctx, query, notebookID, note.ID).Scan(&note.NotebookID, &note.UpdatedAt, &note.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	err = enqueueNoteEvent(tx, WebhookEventNoteUpdated, note.ID, note)
+	if err != nil {
+		return err
+	}
+
+	err = auditNote(tx, auditSourceFrom(m.ctx), AuditNoteMove, note.ID, note.Version, note.Version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetPinned pins or unpins a note. Pinned notes are listed ahead of all
+// others. Like Move, this leaves the note's version alone.
+func (m NoteModel) SetPinned(note *Note, pinned bool) error {
+	query := `
+        UPDATE notes
+        SET pinned = $1, updated_at = NOW(), change_seq = nextval('note_change_seq')
+        WHERE id = $2
+        RETURNING pinned, updated_at, version`
+
+	action := AuditNoteUnpin
+	if pinned {
+		action = AuditNotePin
+	}
+
+	return m.setFlag("notes.set_pinned", query, action, note, pinned, &note.Pinned)
+}
+
+// SetFavorite marks or unmarks a note as a favorite.
+func (m NoteModel) SetFavorite(note *Note, favorite bool) error {
+	query := `
+        UPDATE notes
+        SET favorite = $1, updated_at = NOW(), change_seq = nextval('note_change_seq')
+        WHERE id = $2
+        RETURNING favorite, updated_at, version`
+
+	action := AuditNoteUnfavorite
+	if favorite {
+		action = AuditNoteFavorite
+	}
+
+	return m.setFlag("notes.set_favorite", query, action, note, favorite, &note.Favorite)
+}
+
+// setFlag runs query to set one of a note's flags, reading it back into dst,
+// queues the change for webhooks as an update and audits it as action.
+func (m NoteModel) setFlag(statement, query, action string, note *Note, value bool, dst *bool) error {
+	ctx, span := startSpan(m.ctx, statement, "notes")
+	defer span.End()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, value, note.ID).Scan(dst, &note.UpdatedAt, &note.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	err = enqueueNoteEvent(tx, WebhookEventNoteUpdated, note.ID, note)
+	if err != nil {
+		return err
+	}
+
+	err = auditNote(tx, auditSourceFrom(m.ctx), action, note.ID, note.Version, note.Version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// NoteSortSafelist holds the sort values accepted when listing notes.
+var NoteSortSafelist = []string{"id", "title", "created_at", "updated_at", "-id", "-title", "-created_at", "-updated_at"}
+
+// GetAllDue returns the notes visible to the user that have a due date,
+// soonest first.
+func (m NoteModel) GetAllDue(userID int64) ([]*Note, error) {
+	ctx, span := startSpan(m.ctx, "notes.get_all_due", "notes")
+	defer span.End()
+
+	query := `
+        SELECT n.id, n.created_at, n.updated_at, n.title, n.body, n.tags, n.archived, n.pinned, n.favorite, n.notebook_id, n.due_at, n.version
+        FROM notes n
+        WHERE n.due_at IS NOT NULL AND ` + visibleTo("n", 1) + `
+        ORDER BY n.due_at, n.id`
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []*Note{}
+
+	for rows.Next() {
+		var note Note
+
+		err := rows.Scan(
+			&note.ID,
+			&note.CreatedAt,
+			&note.UpdatedAt,
+			&note.Title,
+			&note.Body,
+			pq.Array(&note.Tags),
+			&note.Archived,
+			&note.Pinned,
+			&note.Favorite,
+			&note.NotebookID,
+			&note.DueAt,
+			&note.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		notes = append(notes, &note)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+// NoteQuery narrows a note listing. Text is matched against the title and
+// body and Title against the title alone, every one of Tags must be
+// present, and a nil Archived matches notes whether or not they are
+// archived. Q holds an expression in the search language of the query
+// package and is applied on top of the other fields.
+type NoteQuery struct {
+	Q             string   `json:"q"`
+	Text          string   `json:"text"`
+	Title         string   `json:"title"`
+	Tags          []string `json:"tags"`
+	Archived      *bool    `json:"archived"`
+	FavoritesOnly bool     `json:"favorites_only"`
+}
+
+// GetAll returns a page of the notes visible to the user that match the
+// query. Pinned notes always come first, whatever the requested sort.
+func (m NoteModel) GetAll(noteQuery NoteQuery, filters Filters, userID int64) ([]*Note, Metadata, error) {
+	ctx, span := startSpan(m.ctx, "notes.get_all", "notes")
+	defer span.End()
+
+	query := `
+        SELECT count(*) OVER(), n.id, n.created_at, n.updated_at, n.title, n.body, n.tags, n.archived, n.pinned, n.favorite, n.notebook_id, n.due_at, n.version
+        FROM notes n
+        WHERE (to_tsvector('simple', n.title || ' ' || n.body) @@ plainto_tsquery('simple', $1) OR $1 = '')
+        AND (to_tsvector('simple', n.title) @@ plainto_tsquery('simple', $2) OR $2 = '')
+        AND (n.tags @> $3 OR $3 = '{}')
+        AND (n.archived = $4 OR $4::boolean IS NULL)
+        AND (n.favorite OR NOT $5)
+        AND ` + visibleTo("n", 6) + `
+        AND %s
+        ORDER BY n.pinned DESC, n.%s %s, n.id ASC
+        LIMIT $7 OFFSET $8`
+
+	tags := noteQuery.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	args := []any{noteQuery.Text, noteQuery.Title, pq.Array(tags), noteQuery.Archived, noteQuery.FavoritesOnly, userID, filters.limit(), filters.offset()}
+
+	clause := "TRUE"
+
+	if noteQuery.Q != "" {
+		node, err := search.Parse(noteQuery.Q)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		var searchArgs []any
+		clause, searchArgs = search.Compile(node, "n", len(args)+1)
+		args = append(args, searchArgs...)
+	}
+
+	query = fmt.Sprintf(query, clause, filters.sortColumn(), filters.sortDirection())
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	notes := []*Note{}
+
+	for rows.Next() {
+		var note Note
+
+		err := rows.Scan(
+			&totalRecords,
+			&note.ID,
+			&note.CreatedAt,
+			&note.UpdatedAt,
+			&note.Title,
+			&note.Body,
+			pq.Array(&note.Tags),
+			&note.Archived,
+			&note.Pinned,
+			&note.Favorite,
+			&note.NotebookID,
+			&note.DueAt,
+			&note.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		notes = append(notes, &note)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return notes, metadata, nil
+}
+
+// GetAllContent returns the title, body and tags of every note, regardless of
+// ownership, for building in-memory indexes.
+func (m NoteModel) GetAllContent() ([]*Note, error) {
+	ctx, span := startSpan(m.ctx, "notes.get_all_content", "notes")
+	defer span.End()
+
+	rows, err := m.DB.QueryContext(ctx, `SELECT id, title, body, tags FROM notes ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []*Note{}
+
+	for rows.Next() {
+		var note Note
+
+		err := rows.Scan(&note.ID, &note.Title, &note.Body, pq.Array(&note.Tags))
+		if err != nil {
+			return nil, err
+		}
+
+		notes = append(notes, &note)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notes, nil
 }
 
 func (m NoteModel) Delete(id int64) error {
@@ -93,25 +611,148 @@ func (m NoteModel) Delete(id int64) error {
 		return ErrRecordNotFound
 	}
 
-	query := `
-        DELETE FROM notes
-        WHERE id = $1`
+	ctx, span := startSpan(m.ctx, "notes.delete", "notes")
+	defer span.End()
 
-	result, err := m.DB.Exec(query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	err = deleteNote(tx, id, 0, auditSourceFrom(m.ctx))
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	return tx.Commit()
+}
+
+// deleteNote removes a note and leaves a tombstone behind so that sync
+// clients learn about the deletion. A non-zero version makes the delete
+// conditional on the note still being at that version. The blobs of the
+// note's attachments are queued for removal from the blob store, the
+// deletion is queued for webhooks while the note can still be seen, and it
+// is audited as made by source.
+func deleteNote(tx *sql.Tx, id int64, version int, source AuditSource) error {
+	err := queueBlobDeletions(tx, id)
+	if err != nil {
+		return err
+	}
+
+	err = enqueueNoteEvent(tx, WebhookEventNoteDeleted, id, map[string]int64{"id": id})
+	if err != nil {
+		return err
+	}
+
+	// The collaborators are read before the delete cascades to them, and
+	// recorded on the tombstone so only they are told of the deletion.
+	query := `
+        DELETE FROM notes n
+        WHERE n.id = $1 AND ($2 = 0 OR n.version = $2)
+        RETURNING n.version,
+            CASE WHEN EXISTS (SELECT 1 FROM note_collaborators WHERE note_id = n.id AND role = 'owner') THEN
+                ARRAY(SELECT user_id FROM note_collaborators WHERE note_id = n.id AND accepted_at IS NOT NULL)
+            END`
+
+	var (
+		deletedVersion int
+		readerIDs      pq.Int64Array
+	)
+
+	err = tx.QueryRow(query, id, version).Scan(&deletedVersion, &readerIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
 	}
 
-	return nil
+	query = `
+        INSERT INTO note_tombstones (note_id, version, reader_ids)
+        VALUES ($1, $2, $3)`
+
+	_, err = tx.Exec(query, id, deletedVersion, readerIDs)
+	if err != nil {
+		return err
+	}
+
+	return auditNote(tx, source, AuditNoteDelete, id, deletedVersion, 0)
+}
+
+// Suggestion is a note title offered while the user is typing a search.
+type Suggestion struct {
+	ID         int64   `json:"id"`
+	Title      string  `json:"title"`
+	Similarity float64 `json:"similarity"`
+}
+
+// Suggest returns up to limit notes visible to the user whose titles are
+// similar to q, best match first. Similarity is measured against the closest
+// run of words in the title, so partial words and small typos still match.
+// Titles scoring below threshold, between 0 and 1, are left out.
+func (m NoteModel) Suggest(q string, threshold float64, limit int, userID int64) ([]*Suggestion, error) {
+	ctx, span := startSpan(m.ctx, "notes.suggest", "notes")
+	defer span.End()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`, strconv.FormatFloat(threshold, 'f', -1, 64))
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+        SELECT n.id, n.title, word_similarity($1, n.title) AS similarity
+        FROM notes n
+        WHERE $1 <% n.title AND ` + visibleTo("n", 2) + `
+        ORDER BY similarity DESC, n.title, n.id
+        LIMIT $3`
+
+	rows, err := tx.QueryContext(ctx, query, q, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*Suggestion{}
+
+	for rows.Next() {
+		var suggestion Suggestion
+
+		err := rows.Scan(&suggestion.ID, &suggestion.Title, &suggestion.Similarity)
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, tx.Commit()
+}
+
+func ValidateNoteQuery(v *validator.Validator, query NoteQuery) {
+	v.Check(len(query.Text) <= 500, "text", "must not be more than 500 bytes long")
+	v.Check(len(query.Title) <= 500, "title", "must not be more than 500 bytes long")
+	v.Check(validator.Unique(query.Tags), "tags", "must not contain duplicate values")
+
+	v.Check(len(query.Q) <= 1000, "q", "must not be more than 1000 bytes long")
+
+	if query.Q != "" && len(query.Q) <= 1000 {
+		if _, err := search.Parse(query.Q); err != nil {
+			v.AddError("q", err.Error())
+		}
+	}
 }
 
 func ValidateNote(v *validator.Validator, note *Note) {
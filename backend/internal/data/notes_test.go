@@ -1,7 +1,11 @@
 package data_test
 
 import (
+	"errors"
+	"fmt"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
 	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
@@ -235,7 +239,9 @@ func TestNoteModel_Update(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := model.Update(tt.updateNote)
+			tt.updateNote.Version = originalVersion
+
+			_, err := model.Update(tt.updateNote)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -271,6 +277,159 @@ func TestNoteModel_Update(t *testing.T) {
 	}
 }
 
+func TestNoteModel_UpdateStaleVersion(t *testing.T) {
+	model := newTestModel(t)
+
+	serverDue := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	clientDue := time.Date(2030, 2, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		serverEdit     func(n *data.Note)
+		clientEdit     func(n *data.Note)
+		clientVersion  int
+		wantConflict   bool
+		expectedFields []string
+		expectedBody   string
+		expectedTags   []string
+		expectedDueAt  *time.Time
+	}{
+		{
+			name: "non-overlapping body edits merge cleanly",
+			serverEdit: func(n *data.Note) {
+				n.Body = "server line\ntwo\nthree"
+				n.Tags = []string{"shared", "server"}
+			},
+			clientEdit: func(n *data.Note) {
+				n.Body = "one\ntwo\nclient line"
+				n.Tags = []string{"shared", "client"}
+			},
+			clientVersion: 1,
+			expectedBody:  "server line\ntwo\nclient line",
+			expectedTags:  []string{"shared", "server", "client"},
+		},
+		{
+			name: "overlapping body edits conflict",
+			serverEdit: func(n *data.Note) {
+				n.Body = "one\nserver\nthree"
+			},
+			clientEdit: func(n *data.Note) {
+				n.Body = "one\nclient\nthree"
+			},
+			clientVersion:  1,
+			wantConflict:   true,
+			expectedFields: []string{"body"},
+		},
+		{
+			name: "conflicting titles",
+			serverEdit: func(n *data.Note) {
+				n.Title = "Server Title"
+			},
+			clientEdit: func(n *data.Note) {
+				n.Title = "Client Title"
+			},
+			clientVersion:  1,
+			wantConflict:   true,
+			expectedFields: []string{"title"},
+		},
+		{
+			name: "due date set on the server survives an edit without one",
+			serverEdit: func(n *data.Note) {
+				n.DueAt = &serverDue
+			},
+			clientEdit: func(n *data.Note) {
+				n.Tags = []string{"shared", "client"}
+			},
+			clientVersion: 1,
+			expectedBody:  "one\ntwo\nthree",
+			expectedTags:  []string{"shared", "client"},
+			expectedDueAt: &serverDue,
+		},
+		{
+			name: "conflicting due dates",
+			serverEdit: func(n *data.Note) {
+				n.DueAt = &serverDue
+			},
+			clientEdit: func(n *data.Note) {
+				n.DueAt = &clientDue
+			},
+			clientVersion:  1,
+			wantConflict:   true,
+			expectedFields: []string{"due_at"},
+		},
+		{
+			name:          "unknown base version",
+			serverEdit:    func(n *data.Note) {},
+			clientEdit:    func(n *data.Note) {},
+			clientVersion: 99,
+			wantConflict:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			note := &data.Note{
+				Title: "Title",
+				Body:  "one\ntwo\nthree",
+				Tags:  []string{"shared"},
+			}
+			if err := model.Insert(note); err != nil {
+				t.Fatal(err)
+			}
+
+			server := *note
+			tt.serverEdit(&server)
+			if _, err := model.Update(&server); err != nil {
+				t.Fatal(err)
+			}
+
+			client := *note
+			client.Version = tt.clientVersion
+			tt.clientEdit(&client)
+
+			_, err := model.Update(&client)
+
+			var conflict *data.MergeConflict
+			if tt.wantConflict {
+				if !errors.As(err, &conflict) {
+					t.Fatalf("expected *MergeConflict, got %v", err)
+				}
+				if !errors.Is(err, data.ErrEditConflict) {
+					t.Error("expected conflict to match ErrEditConflict")
+				}
+				if !slices.Equal(conflict.Fields, tt.expectedFields) && tt.expectedFields != nil {
+					t.Errorf("expected conflicting fields %v, got %v", tt.expectedFields, conflict.Fields)
+				}
+				if conflict.Server == nil || conflict.Client == nil {
+					t.Error("expected conflict to carry server and client copies")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if client.Version != 3 {
+				t.Errorf("expected version 3 after merge, got %d", client.Version)
+			}
+
+			saved, err := model.Get(note.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved.Body != tt.expectedBody {
+				t.Errorf("expected merged body %q, got %q", tt.expectedBody, saved.Body)
+			}
+			if !slices.Equal(saved.Tags, tt.expectedTags) {
+				t.Errorf("expected merged tags %v, got %v", tt.expectedTags, saved.Tags)
+			}
+			if (saved.DueAt == nil) != (tt.expectedDueAt == nil) || saved.DueAt != nil && !saved.DueAt.Equal(*tt.expectedDueAt) {
+				t.Errorf("expected merged due date %v, got %v", tt.expectedDueAt, saved.DueAt)
+			}
+		})
+	}
+}
+
 func TestNoteModel_Delete(t *testing.T) {
 	model := newTestModel(t)
 
@@ -334,6 +493,120 @@ func TestNoteModel_Delete(t *testing.T) {
 	}
 }
 
+func TestNoteModel_GetAllPinnedFirst(t *testing.T) {
+	model := newTestModel(t)
+
+	tag := fmt.Sprintf("pinned-%d", time.Now().UnixNano())
+
+	var notes []*data.Note
+	for _, title := range []string{"Alpha", "Bravo", "Charlie"} {
+		note := &data.Note{Title: title, Body: "Body", Tags: []string{tag}}
+		if err := model.Insert(note); err != nil {
+			t.Fatal(err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := model.SetPinned(notes[2], true); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.SetFavorite(notes[1], true); err != nil {
+		t.Fatal(err)
+	}
+
+	filters := data.Filters{Page: 1, PageSize: 20, Sort: "title", SortSafelist: []string{"title"}}
+
+	tests := []struct {
+		name           string
+		favoritesOnly  bool
+		expectedTitles []string
+	}{
+		{"pinned before sort order", false, []string{"Charlie", "Alpha", "Bravo"}},
+		{"favorites only", true, []string{"Bravo"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, metadata, err := model.GetAll(data.NoteQuery{Tags: []string{tag}, FavoritesOnly: tt.favoritesOnly}, filters, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var titles []string
+			for _, note := range got {
+				titles = append(titles, note.Title)
+			}
+
+			if !slices.Equal(titles, tt.expectedTitles) {
+				t.Errorf("expected %v, got %v", tt.expectedTitles, titles)
+			}
+
+			if metadata.TotalRecords != len(tt.expectedTitles) {
+				t.Errorf("expected %d total records, got %d", len(tt.expectedTitles), metadata.TotalRecords)
+			}
+		})
+	}
+
+	stored, err := model.Get(notes[2].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !stored.Pinned || stored.Version != notes[2].Version {
+		t.Errorf("expected pinned note at unchanged version %d, got pinned=%v version=%d", notes[2].Version, stored.Pinned, stored.Version)
+	}
+}
+
+func TestNoteModel_Suggest(t *testing.T) {
+	model := newTestModel(t)
+
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	for _, title := range []string{"React hooks " + suffix, "Reactive streams " + suffix, "Groceries " + suffix} {
+		if err := model.Insert(&data.Note{Title: title, Body: "Body", Tags: []string{}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		q           string
+		threshold   float64
+		expectTitle string
+		expectNone  bool
+	}{
+		{"typo", "recat " + suffix, 0.3, "React hooks " + suffix, false},
+		{"partial word", "reac " + suffix, 0.3, "React hooks " + suffix, false},
+		{"strict threshold", "recat", 0.9, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions, err := model.Suggest(tt.q, tt.threshold, 5, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.expectNone {
+				if len(suggestions) != 0 {
+					t.Errorf("expected no suggestions, got %d", len(suggestions))
+				}
+				return
+			}
+
+			found := false
+			for _, s := range suggestions {
+				if s.Title == tt.expectTitle {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected %q among suggestions", tt.expectTitle)
+			}
+		})
+	}
+}
+
 func TestValidateNote(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -0,0 +1,503 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+// maxReminderAttempts is how many times delivery of a reminder is tried
+// before it's given up on. Failed attempts are retried after
+// reminderBackoff.
+const maxReminderAttempts = 5
+
+// reminderClaimTimeout is how long DeliverDue has to deliver the reminders
+// it claims before other instances may claim them again. It allows for a
+// full batch of deliveries that each run until their client times out.
+const reminderClaimTimeout = 15 * time.Minute
+
+var (
+	ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+	errNoteNotVisible    = errors.New("the user can no longer see the note")
+)
+
+// Reminder nudges a user about a note at FireAt, and again at every
+// occurrence of its recurrence rule if it has one. DeliveredAt records when
+// the reminder was last handled, whether delivered or given up on after
+// repeated failures; LastError holds the reason for the latest failure.
+type Reminder struct {
+	ID          int64      `json:"id"`
+	NoteID      int64      `json:"note_id"`
+	NoteTitle   string     `json:"note_title,omitempty"`
+	UserID      int64      `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FireAt      time.Time  `json:"fire_at"`
+	Recurrence  string     `json:"recurrence"`
+	Channel     string     `json:"channel"`
+	WebhookURL  string     `json:"webhook_url,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at"`
+	Attempts    int        `json:"attempts"`
+	LastError   string     `json:"last_error,omitempty"`
+	Version     int        `json:"version"`
+}
+
+// DueReminder is a reminder that has come due, along with what's needed to
+// deliver it.
+type DueReminder struct {
+	Reminder
+	Email string
+}
+
+// Recurrence is a parsed recurrence rule, a subset of the iCalendar RRULE
+// syntax such as "FREQ=WEEKLY;INTERVAL=2". The zero value never recurs.
+type Recurrence struct {
+	Freq     string
+	Interval int
+}
+
+// ParseRecurrence parses a recurrence rule. FREQ may be HOURLY, DAILY,
+// WEEKLY, MONTHLY or YEARLY and INTERVAL defaults to 1. An empty rule
+// parses to the zero Recurrence.
+func ParseRecurrence(rule string) (Recurrence, error) {
+	var rec Recurrence
+
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return rec, nil
+	}
+
+	rec.Interval = 1
+
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Recurrence{}, ErrInvalidRecurrence
+		}
+
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rec.Freq = strings.ToUpper(value)
+			if !validator.PermittedValue(rec.Freq, "HOURLY", "DAILY", "WEEKLY", "MONTHLY", "YEARLY") {
+				return Recurrence{}, ErrInvalidRecurrence
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 || interval > 1000 {
+				return Recurrence{}, ErrInvalidRecurrence
+			}
+			rec.Interval = interval
+		default:
+			return Recurrence{}, ErrInvalidRecurrence
+		}
+	}
+
+	if rec.Freq == "" {
+		return Recurrence{}, ErrInvalidRecurrence
+	}
+
+	return rec, nil
+}
+
+// String formats the rule as FREQ and INTERVAL parts, or returns "" for the
+// zero Recurrence.
+func (rec Recurrence) String() string {
+	if rec.Freq == "" {
+		return ""
+	}
+
+	return "FREQ=" + rec.Freq + ";INTERVAL=" + strconv.Itoa(rec.Interval)
+}
+
+// Next returns the first occurrence after now of the series starting at
+// fireAt. Occurrences missed while nothing was polling are skipped rather
+// than delivered in a burst. Monthly and yearly occurrences are counted
+// from fireAt so that a reminder on the 31st doesn't drift earlier, though
+// as with time.AddDate a month without that day rolls over into the next.
+func (rec Recurrence) Next(fireAt, now time.Time) time.Time {
+	if rec.Freq == "" {
+		return fireAt
+	}
+
+	for n := 1; ; n++ {
+		step := n * rec.Interval
+
+		var next time.Time
+
+		switch rec.Freq {
+		case "HOURLY":
+			next = fireAt.Add(time.Duration(step) * time.Hour)
+		case "DAILY":
+			next = fireAt.AddDate(0, 0, step)
+		case "WEEKLY":
+			next = fireAt.AddDate(0, 0, 7*step)
+		case "MONTHLY":
+			next = fireAt.AddDate(0, step, 0)
+		case "YEARLY":
+			next = fireAt.AddDate(step, 0, 0)
+		}
+
+		if next.After(now) {
+			return next
+		}
+	}
+}
+
+type ReminderModel struct {
+	DB  *sql.DB
+	ctx context.Context
+}
+
+// WithContext returns a copy of the model whose queries are traced as part
+// of ctx.
+func (m ReminderModel) WithContext(ctx context.Context) ReminderModel {
+	m.ctx = context.WithoutCancel(ctx)
+	return m
+}
+
+func (m ReminderModel) Insert(reminder *Reminder) error {
+	ctx, span := startSpan(m.ctx, "reminders.insert", "reminders")
+	defer span.End()
+
+	query := `
+        INSERT INTO reminders (note_id, user_id, fire_at, recurrence, channel, webhook_url)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at, version`
+
+	args := []any{reminder.NoteID, reminder.UserID, reminder.FireAt, reminder.Recurrence, reminder.Channel, reminder.WebhookURL}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&reminder.ID, &reminder.CreatedAt, &reminder.Version)
+}
+
+// Get returns one of the user's reminders on a note. Other users' reminders
+// are reported as not found.
+func (m ReminderModel) Get(noteID, id, userID int64) (*Reminder, error) {
+	if noteID < 1 || id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	ctx, span := startSpan(m.ctx, "reminders.get", "reminders")
+	defer span.End()
+
+	query := `
+        SELECT id, note_id, user_id, created_at, fire_at, recurrence, channel, webhook_url, delivered_at, attempts, last_error, version
+        FROM reminders
+        WHERE note_id = $1 AND id = $2 AND user_id = $3`
+
+	var reminder Reminder
+
+	err := m.DB.QueryRowContext(ctx, query, noteID, id, userID).Scan(reminderFields(&reminder)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &reminder, nil
+}
+
+func (m ReminderModel) GetAllForNote(noteID, userID int64) ([]*Reminder, error) {
+	ctx, span := startSpan(m.ctx, "reminders.get_all_for_note", "reminders")
+	defer span.End()
+
+	query := `
+        SELECT id, note_id, user_id, created_at, fire_at, recurrence, channel, webhook_url, delivered_at, attempts, last_error, version
+        FROM reminders
+        WHERE note_id = $1 AND user_id = $2
+        ORDER BY fire_at, id`
+
+	rows, err := m.DB.QueryContext(ctx, query, noteID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []*Reminder{}
+
+	for rows.Next() {
+		var reminder Reminder
+
+		err := rows.Scan(reminderFields(&reminder)...)
+		if err != nil {
+			return nil, err
+		}
+
+		reminders = append(reminders, &reminder)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
+
+// GetAllForUser returns the user's reminders on every note they can still
+// see, with the notes' titles.
+func (m ReminderModel) GetAllForUser(userID int64) ([]*Reminder, error) {
+	ctx, span := startSpan(m.ctx, "reminders.get_all_for_user", "reminders")
+	defer span.End()
+
+	query := `
+        SELECT r.id, r.note_id, r.user_id, r.created_at, r.fire_at, r.recurrence, r.channel, r.webhook_url,
+            r.delivered_at, r.attempts, r.last_error, r.version, n.title
+        FROM reminders r
+        INNER JOIN notes n ON n.id = r.note_id
+        WHERE r.user_id = $1 AND ` + visibleTo("n", 1) + `
+        ORDER BY r.fire_at, r.id`
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []*Reminder{}
+
+	for rows.Next() {
+		var reminder Reminder
+
+		err := rows.Scan(append(reminderFields(&reminder), &reminder.NoteTitle)...)
+		if err != nil {
+			return nil, err
+		}
+
+		reminders = append(reminders, &reminder)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
+
+// Update saves changes to a reminder and re-arms it, clearing its delivery
+// state so that it fires at its new time.
+func (m ReminderModel) Update(reminder *Reminder) error {
+	ctx, span := startSpan(m.ctx, "reminders.update", "reminders")
+	defer span.End()
+
+	query := `
+        UPDATE reminders
+        SET fire_at = $1, recurrence = $2, channel = $3, webhook_url = $4,
+            delivered_at = NULL, attempts = 0, last_error = '', version = version + 1
+        WHERE id = $5 AND version = $6
+        RETURNING delivered_at, attempts, last_error, version`
+
+	args := []any{reminder.FireAt, reminder.Recurrence, reminder.Channel, reminder.WebhookURL, reminder.ID, reminder.Version}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&reminder.DeliveredAt, &reminder.Attempts, &reminder.LastError, &reminder.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m ReminderModel) Delete(noteID, id, userID int64) error {
+	if noteID < 1 || id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, span := startSpan(m.ctx, "reminders.delete", "reminders")
+	defer span.End()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM reminders WHERE note_id = $1 AND id = $2 AND user_id = $3`, noteID, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeliverDue hands up to limit due reminders to deliver and records the
+// outcome, returning how many were claimed. The reminders are first
+// claimed for reminderClaimTimeout in a transaction of their own, using
+// FOR UPDATE SKIP LOCKED, so other API instances polling at the same time
+// pass over them; no locks are held while they are being delivered. A crash
+// before the outcome is recorded leaves them to be claimed again once the
+// claim runs out, so delivery is at least once. A reminder whose delivery
+// fails stays claimed until reminderBackoff has passed, so it is retried
+// later rather than straight away.
+//
+// Reminders on notes their user can no longer see, such as after being
+// removed as a collaborator, are handled without being delivered.
+func (m ReminderModel) DeliverDue(limit int, deliver func(*DueReminder) error) (int, error) {
+	claimedUntil := time.Now().Add(reminderClaimTimeout).Truncate(time.Second)
+
+	due, allowed, err := m.claimDue(limit, claimedUntil)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, reminder := range due {
+		attempts, lastError := 0, ""
+
+		err := errNoteNotVisible
+		if allowed[reminder.ID] {
+			err = deliver(reminder)
+		}
+
+		switch {
+		case err == nil:
+		case errors.Is(err, errNoteNotVisible):
+			attempts, lastError = reminder.Attempts, err.Error()
+		default:
+			attempts, lastError = reminder.Attempts+1, err.Error()
+		}
+
+		handled := err == nil || errors.Is(err, errNoteNotVisible) || attempts >= maxReminderAttempts
+
+		fireAt := reminder.FireAt
+		if handled {
+			// The rule was validated when the reminder was saved.
+			rec, _ := ParseRecurrence(reminder.Recurrence)
+			fireAt = rec.Next(reminder.FireAt, time.Now())
+
+			// The next occurrence of a recurring reminder gets a fresh set
+			// of attempts.
+			if !fireAt.Equal(reminder.FireAt) {
+				attempts = 0
+			}
+		}
+
+		var retryAt *time.Time
+		if !handled {
+			t := time.Now().Add(reminderBackoff(attempts)).Truncate(time.Second)
+			retryAt = &t
+		}
+
+		// The claim is checked so that nothing is recorded if it ran out
+		// and the reminder was claimed again meanwhile.
+		query := `
+            UPDATE reminders
+            SET fire_at = $1, attempts = $2, last_error = $3, claimed_until = $7,
+                delivered_at = CASE WHEN $4 THEN clock_timestamp() ELSE delivered_at END
+            WHERE id = $5 AND claimed_until = $6`
+
+		ctx, span := startSpan(m.ctx, "reminders.record_delivery", "reminders")
+		_, err = m.DB.ExecContext(ctx, query, fireAt, attempts, lastError, handled, reminder.ID, claimedUntil, retryAt)
+		span.End()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(due), nil
+}
+
+// reminderBackoff is how long a reminder waits after its nth failed attempt
+// before being tried again: a minute, doubling with each attempt.
+func reminderBackoff(attempts int) time.Duration {
+	return time.Minute << (attempts - 1)
+}
+
+// claimDue claims up to limit due reminders until claimedUntil, reporting
+// for each whether its user can still see the note.
+func (m ReminderModel) claimDue(limit int, claimedUntil time.Time) ([]*DueReminder, map[int64]bool, error) {
+	ctx, span := startSpan(m.ctx, "reminders.claim_due", "reminders")
+	defer span.End()
+
+	query := `
+        UPDATE reminders r
+        SET claimed_until = $2
+        FROM notes n, users u
+        WHERE r.id IN (
+            SELECT id
+            FROM reminders
+            WHERE fire_at <= NOW()
+            AND (delivered_at IS NULL OR delivered_at < fire_at)
+            AND (claimed_until IS NULL OR claimed_until <= NOW())
+            ORDER BY fire_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        AND n.id = r.note_id AND u.id = r.user_id
+        RETURNING r.id, r.note_id, r.user_id, r.created_at, r.fire_at, r.recurrence, r.channel, r.webhook_url,
+            r.delivered_at, r.attempts, r.last_error, r.version, n.title, u.email, ` + visibleToUser("n", "r.user_id")
+
+	rows, err := m.DB.QueryContext(ctx, query, limit, claimedUntil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	due := []*DueReminder{}
+	allowed := make(map[int64]bool)
+
+	for rows.Next() {
+		var (
+			reminder DueReminder
+			visible  bool
+		)
+
+		err := rows.Scan(append(reminderFields(&reminder.Reminder), &reminder.NoteTitle, &reminder.Email, &visible)...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		due = append(due, &reminder)
+		allowed[reminder.ID] = visible
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return due, allowed, nil
+}
+
+func reminderFields(reminder *Reminder) []any {
+	return []any{
+		&reminder.ID,
+		&reminder.NoteID,
+		&reminder.UserID,
+		&reminder.CreatedAt,
+		&reminder.FireAt,
+		&reminder.Recurrence,
+		&reminder.Channel,
+		&reminder.WebhookURL,
+		&reminder.DeliveredAt,
+		&reminder.Attempts,
+		&reminder.LastError,
+		&reminder.Version,
+	}
+}
+
+func ValidateReminder(v *validator.Validator, reminder *Reminder) {
+	v.Check(!reminder.FireAt.IsZero(), "fire_at", "must be provided")
+	v.Check(reminder.FireAt.IsZero() || reminder.FireAt.After(time.Now()), "fire_at", "must be in the future")
+
+	_, err := ParseRecurrence(reminder.Recurrence)
+	v.Check(err == nil, "recurrence", "must be a rule such as FREQ=DAILY;INTERVAL=2")
+
+	v.Check(validator.PermittedValue(reminder.Channel, "email", "webhook"), "channel", "must be email or webhook")
+
+	if reminder.Channel == "webhook" {
+		validateWebhookURL(v, "webhook_url", reminder.WebhookURL)
+	} else {
+		v.Check(reminder.WebhookURL == "", "webhook_url", "must only be provided for webhook reminders")
+	}
+}
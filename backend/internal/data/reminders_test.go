@@ -0,0 +1,215 @@
+package data_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected data.Recurrence
+		wantErr  bool
+	}{
+		{"", data.Recurrence{}, false},
+		{"FREQ=DAILY", data.Recurrence{Freq: "DAILY", Interval: 1}, false},
+		{"RRULE:freq=weekly;interval=2", data.Recurrence{Freq: "WEEKLY", Interval: 2}, false},
+		{"INTERVAL=3", data.Recurrence{}, true},
+		{"FREQ=SECONDLY", data.Recurrence{}, true},
+		{"FREQ=DAILY;INTERVAL=0", data.Recurrence{}, true},
+		{"FREQ=DAILY;COUNT=3", data.Recurrence{}, true},
+		{"daily", data.Recurrence{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rec, err := data.ParseRecurrence(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if rec != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, rec)
+			}
+		})
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	fireAt := time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		rec      data.Recurrence
+		now      time.Time
+		expected time.Time
+	}{
+		{"no recurrence", data.Recurrence{}, fireAt, fireAt},
+		{"next day", data.Recurrence{Freq: "DAILY", Interval: 1}, fireAt, time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"missed occurrences are skipped", data.Recurrence{Freq: "WEEKLY", Interval: 2}, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)},
+		{"months count from the start", data.Recurrence{Freq: "MONTHLY", Interval: 1}, time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 31, 9, 0, 0, 0, time.UTC)},
+		{"hourly", data.Recurrence{Freq: "HOURLY", Interval: 6}, fireAt.Add(7 * time.Hour), fireAt.Add(12 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rec.Next(fireAt, tt.now); !got.Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReminderModel_DeliverDue(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	models := data.NewModels(db)
+
+	user := &data.User{Name: "reminded", Email: fmt.Sprintf("reminded-%d@example.com", time.Now().UnixNano())}
+	if err := user.Password.Set("pa55word1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Users.Insert(user); err != nil {
+		t.Fatal(err)
+	}
+
+	note := &data.Note{Title: "Dentist", Body: "Book a check-up", Tags: []string{}}
+	if err := models.Notes.Insert(note); err != nil {
+		t.Fatal(err)
+	}
+
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	once := &data.Reminder{NoteID: note.ID, UserID: user.ID, FireAt: past, Channel: "email"}
+	daily := &data.Reminder{NoteID: note.ID, UserID: user.ID, FireAt: past, Recurrence: "FREQ=DAILY", Channel: "webhook", WebhookURL: "https://example.com/hook"}
+	failing := &data.Reminder{NoteID: note.ID, UserID: user.ID, FireAt: past, Channel: "webhook", WebhookURL: "https://example.com/broken"}
+	failingDaily := &data.Reminder{NoteID: note.ID, UserID: user.ID, FireAt: past, Recurrence: "FREQ=DAILY", Channel: "webhook", WebhookURL: "https://example.com/broken"}
+	future := &data.Reminder{NoteID: note.ID, UserID: user.ID, FireAt: time.Now().Add(time.Hour), Channel: "email"}
+
+	owner := &data.User{Name: "owner", Email: fmt.Sprintf("owner-%d@example.com", time.Now().UnixNano())}
+	if err := owner.Password.Set("pa55word1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Users.Insert(owner); err != nil {
+		t.Fatal(err)
+	}
+
+	private := &data.Note{Title: "Private", Body: "Not shared", Tags: []string{}}
+	if err := models.Notes.InsertOwned(private, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	unshared := &data.Reminder{NoteID: private.ID, UserID: user.ID, FireAt: past, Channel: "email"}
+
+	for _, reminder := range []*data.Reminder{once, daily, failing, failingDaily, future, unshared} {
+		if err := models.Reminders.Insert(reminder); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mine := map[int64]bool{once.ID: true, daily.ID: true, failing.ID: true, failingDaily.ID: true, future.ID: true, unshared.ID: true}
+
+	deliveries := map[int64]int{}
+
+	deliver := func(due *data.DueReminder) error {
+		if !mine[due.ID] {
+			return nil
+		}
+
+		if due.ID == unshared.ID {
+			t.Error("expected no delivery of a reminder on a note the user can't see")
+			return nil
+		}
+
+		if due.NoteTitle != "Dentist" || due.Email != user.Email {
+			t.Errorf("expected the note title and user email, got %q and %q", due.NoteTitle, due.Email)
+		}
+
+		deliveries[due.ID]++
+
+		if due.ID == failing.ID || due.ID == failingDaily.ID {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	if _, err := models.Reminders.DeliverDue(100, deliver); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.Reminders.DeliverDue(100, deliver); err != nil {
+		t.Fatal(err)
+	}
+
+	if deliveries[failing.ID] != 1 {
+		t.Errorf("expected a failed reminder to wait before being retried, got %d attempts", deliveries[failing.ID])
+	}
+
+	for range 5 {
+		if _, err := db.Exec(`UPDATE reminders SET claimed_until = NOW() WHERE user_id = $1 AND claimed_until > NOW()`, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := models.Reminders.DeliverDue(100, deliver); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[int64]int{once.ID: 1, daily.ID: 1, failing.ID: 5, failingDaily.ID: 5}
+
+	for id, count := range expected {
+		if deliveries[id] != count {
+			t.Errorf("expected reminder %d to be tried %d times, got %d", id, count, deliveries[id])
+		}
+	}
+
+	if deliveries[future.ID] != 0 {
+		t.Error("expected the future reminder not to be delivered")
+	}
+
+	got, err := models.Reminders.Get(note.ID, daily.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.FireAt.Equal(past.AddDate(0, 0, 1)) || got.DeliveredAt == nil {
+		t.Errorf("expected the daily reminder to move to the next day, got %+v", got)
+	}
+
+	got, err = models.Reminders.Get(note.ID, failing.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Attempts != 5 || got.LastError != "connection refused" || got.DeliveredAt == nil {
+		t.Errorf("expected the failing reminder to be given up on, got %+v", got)
+	}
+
+	got, err = models.Reminders.Get(note.ID, failingDaily.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Attempts != 0 || !got.FireAt.Equal(past.AddDate(0, 0, 1)) {
+		t.Errorf("expected the failing daily reminder to start afresh the next day, got %+v", got)
+	}
+
+	got, err = models.Reminders.Get(private.ID, unshared.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.DeliveredAt == nil || got.LastError == "" {
+		t.Errorf("expected the reminder on the unshared note to be passed over, got %+v", got)
+	}
+
+	if _, err := models.Reminders.Get(note.ID, once.ID, user.ID+1); !errors.Is(err, data.ErrRecordNotFound) {
+		t.Errorf("expected other users not to see the reminder, got %v", err)
+	}
+}
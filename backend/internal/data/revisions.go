@@ -0,0 +1,162 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/merge"
+	"github.com/lib/pq"
+)
+
+// MergeConflict is returned by NoteModel.Update when a stale edit overlaps
+// with changes saved since. Merged holds the best-effort merge, with conflict
+// markers in the body, and Fields names the fields that could not be merged.
+// Base is nil when the revision the edit was based on is unknown.
+type MergeConflict struct {
+	Fields []string `json:"fields"`
+	Merged *Note    `json:"merged,omitempty"`
+	Base   *Note    `json:"base"`
+	Server *Note    `json:"server"`
+	Client *Note    `json:"client"`
+}
+
+func (c *MergeConflict) Error() string {
+	if c.Base == nil {
+		return fmt.Sprintf("edit conflict: no revision %d of note %d", c.Client.Version, c.Client.ID)
+	}
+	return fmt.Sprintf("edit conflict: note %d fields %s", c.Client.ID, strings.Join(c.Fields, ", "))
+}
+
+func (c *MergeConflict) Unwrap() error {
+	return ErrEditConflict
+}
+
+func insertRevision(tx *sql.Tx, note *Note) error {
+	query := `
+        INSERT INTO note_revisions (note_id, version, created_at, title, body, tags, archived, due_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	args := []any{
+		note.ID,
+		note.Version,
+		note.UpdatedAt,
+		note.Title,
+		note.Body,
+		pq.Array(note.Tags),
+		note.Archived,
+		note.DueAt,
+	}
+
+	_, err := tx.Exec(query, args...)
+	return err
+}
+
+func getRevision(tx *sql.Tx, id int64, version int) (*Note, error) {
+	query := `
+        SELECT note_id, created_at, title, body, tags, archived, due_at, version
+        FROM note_revisions
+        WHERE note_id = $1 AND version = $2`
+
+	var note Note
+
+	err := tx.QueryRow(query, id, version).Scan(
+		&note.ID,
+		&note.UpdatedAt,
+		&note.Title,
+		&note.Body,
+		pq.Array(&note.Tags),
+		&note.Archived,
+		&note.DueAt,
+		&note.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &note, nil
+}
+
+// mergeNote three-way merges the client's edit of base with the server's
+// current copy. On success the merged fields are written into client and its
+// version moved up to the server's.
+func mergeNote(base, server, client *Note) error {
+	clientCopy := *client
+	clientCopy.Tags = slices.Clone(client.Tags)
+
+	if base == nil {
+		return &MergeConflict{Server: server, Client: &clientCopy}
+	}
+
+	merged := *server
+	fields := []string{}
+
+	var ok bool
+	var conflicts int
+
+	merged.Title, ok = merge.Value(base.Title, server.Title, client.Title)
+	if !ok {
+		fields = append(fields, "title")
+	}
+
+	merged.Body, conflicts = merge.Lines(base.Body, server.Body, client.Body)
+	if conflicts > 0 {
+		fields = append(fields, "body")
+	}
+
+	merged.Archived, _ = merge.Value(base.Archived, server.Archived, client.Archived)
+
+	merged.DueAt, ok = mergeDueAt(base.DueAt, server.DueAt, client.DueAt)
+	if !ok {
+		fields = append(fields, "due_at")
+	}
+
+	merged.Tags = merge.Set(base.Tags, server.Tags, client.Tags)
+
+	if len(fields) > 0 {
+		return &MergeConflict{
+			Fields: fields,
+			Merged: &merged,
+			Base:   base,
+			Server: server,
+			Client: &clientCopy,
+		}
+	}
+
+	client.Title = merged.Title
+	client.Body = merged.Body
+	client.Tags = merged.Tags
+	client.Archived = merged.Archived
+	client.DueAt = merged.DueAt
+	client.Version = server.Version
+
+	return nil
+}
+
+// mergeDueAt merges due dates with merge.Value, comparing them as instants
+// so that the same time read back in another location still matches. A nil
+// due date, for a note without one, is a value like any other.
+func mergeDueAt(base, server, client *time.Time) (*time.Time, bool) {
+	instant := func(t *time.Time) int64 {
+		if t == nil {
+			return math.MinInt64
+		}
+		return t.UnixNano()
+	}
+
+	merged, ok := merge.Value(instant(base), instant(server), instant(client))
+	if merged == instant(client) {
+		return client, ok
+	}
+
+	return server, ok
+}
@@ -0,0 +1,188 @@
+package data
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+// SavedSearch is a named note query that clients show as a smart folder.
+// Running it goes through NoteModel.GetAll, so its results always match what
+// the notes listing would return for the same parameters. Visibility follows
+// notebooks: saved searches of a signed-in user are private to them.
+type SavedSearch struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	Query     NoteQuery `json:"query"`
+	Sort      string    `json:"sort"`
+	OwnerID   *int64    `json:"-"`
+	Version   int       `json:"version"`
+}
+
+type SavedSearchModel struct {
+	DB *sql.DB
+}
+
+func (m SavedSearchModel) Insert(search *SavedSearch, owner *User) error {
+	if !owner.IsAnonymous() {
+		search.OwnerID = &owner.ID
+	}
+
+	noteQuery, err := json.Marshal(search.Query)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO saved_searches (name, query, sort, owner_id)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at, updated_at, version`
+
+	args := []any{search.Name, noteQuery, search.Sort, search.OwnerID}
+
+	return m.DB.QueryRow(query, args...).Scan(&search.ID, &search.CreatedAt, &search.UpdatedAt, &search.Version)
+}
+
+// Get returns a saved search if it is visible to the user.
+func (m SavedSearchModel) Get(id, userID int64) (*SavedSearch, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, created_at, updated_at, name, query, sort, owner_id, version
+        FROM saved_searches
+        WHERE id = $1 AND (owner_id IS NULL OR owner_id = $2)`
+
+	search, err := scanSavedSearch(m.DB.QueryRow(query, id, userID))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return search, nil
+}
+
+func (m SavedSearchModel) GetAll(userID int64) ([]*SavedSearch, error) {
+	query := `
+        SELECT id, created_at, updated_at, name, query, sort, owner_id, version
+        FROM saved_searches
+        WHERE owner_id IS NULL OR owner_id = $1
+        ORDER BY lower(name), id`
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []*SavedSearch{}
+
+	for rows.Next() {
+		search, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		searches = append(searches, search)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return searches, nil
+}
+
+func (m SavedSearchModel) Update(search *SavedSearch) error {
+	noteQuery, err := json.Marshal(search.Query)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE saved_searches
+        SET name = $1, query = $2, sort = $3, updated_at = NOW(), version = version + 1
+        WHERE id = $4 AND version = $5
+        RETURNING updated_at, version`
+
+	args := []any{search.Name, noteQuery, search.Sort, search.ID, search.Version}
+
+	err = m.DB.QueryRow(query, args...).Scan(&search.UpdatedAt, &search.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m SavedSearchModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	result, err := m.DB.Exec(`DELETE FROM saved_searches WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func scanSavedSearch(row interface{ Scan(...any) error }) (*SavedSearch, error) {
+	var search SavedSearch
+	var noteQuery []byte
+
+	err := row.Scan(
+		&search.ID,
+		&search.CreatedAt,
+		&search.UpdatedAt,
+		&search.Name,
+		&noteQuery,
+		&search.Sort,
+		&search.OwnerID,
+		&search.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(noteQuery, &search.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &search, nil
+}
+
+func ValidateSavedSearch(v *validator.Validator, search *SavedSearch) {
+	v.Check(search.Name != "", "name", "must be provided")
+	v.Check(len(search.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	v.Check(validator.PermittedValue(search.Sort, NoteSortSafelist...), "sort", "invalid sort value")
+
+	ValidateNoteQuery(v, search.Query)
+}
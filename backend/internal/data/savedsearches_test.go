@@ -0,0 +1,125 @@
+package data_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func TestValidateSavedSearch(t *testing.T) {
+	tests := []struct {
+		name   string
+		search data.SavedSearch
+		valid  bool
+	}{
+		{
+			name:   "valid search",
+			search: data.SavedSearch{Name: "Work", Sort: "-updated_at", Query: data.NoteQuery{Tags: []string{"work"}}},
+			valid:  true,
+		},
+		{
+			name:   "missing name",
+			search: data.SavedSearch{Sort: "-updated_at"},
+		},
+		{
+			name:   "name too long",
+			search: data.SavedSearch{Name: strings.Repeat("a", 501), Sort: "-updated_at"},
+		},
+		{
+			name:   "unsafe sort",
+			search: data.SavedSearch{Name: "Work", Sort: "body"},
+		},
+		{
+			name:   "valid search expression",
+			search: data.SavedSearch{Name: "Dev", Sort: "title", Query: data.NoteQuery{Q: "tag:dev AND -tag:archive"}},
+			valid:  true,
+		},
+		{
+			name:   "malformed search expression",
+			search: data.SavedSearch{Name: "Dev", Sort: "title", Query: data.NoteQuery{Q: "tag:dev AND ("}},
+		},
+		{
+			name:   "duplicate tags",
+			search: data.SavedSearch{Name: "Work", Sort: "title", Query: data.NoteQuery{Tags: []string{"a", "a"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			data.ValidateSavedSearch(v, &tt.search)
+
+			if v.Valid() != tt.valid {
+				t.Errorf("expected valid = %t, got errors %v", tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestSavedSearchModel(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	models := data.NewModels(db)
+
+	owner := &data.User{Name: "Searcher", Email: fmt.Sprintf("searcher-%d@example.com", time.Now().UnixNano())}
+	if err := owner.Password.Set("pa55word1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Users.Insert(owner); err != nil {
+		t.Fatal(err)
+	}
+
+	archived := true
+	search := &data.SavedSearch{
+		Name:  "Old work",
+		Query: data.NoteQuery{Text: "report", Tags: []string{"work"}, Archived: &archived},
+		Sort:  "title",
+	}
+
+	if err := models.SavedSearches.Insert(search, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("round trips the query", func(t *testing.T) {
+		got, err := models.SavedSearches.Get(search.ID, owner.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Query.Text != "report" || got.Query.Archived == nil || !*got.Query.Archived || len(got.Query.Tags) != 1 {
+			t.Errorf("unexpected query %+v", got.Query)
+		}
+	})
+
+	t.Run("hidden from other users", func(t *testing.T) {
+		_, err := models.SavedSearches.Get(search.ID, 0)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("expected ErrRecordNotFound, got %v", err)
+		}
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		stale := *search
+		stale.Version++
+		if err := models.SavedSearches.Update(&stale); !errors.Is(err, data.ErrEditConflict) {
+			t.Errorf("expected ErrEditConflict, got %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := models.SavedSearches.Delete(search.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := models.SavedSearches.Delete(search.ID); !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("expected ErrRecordNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package data
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Share is a public, read-only link to a single note. Only a SHA-256 hash of
+// the token is stored, so the plaintext token is available once, when the
+// share is created.
+type Share struct {
+	ID                int64      `json:"id"`
+	NoteID            int64      `json:"note_id"`
+	Token             string     `json:"token,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	PasswordProtected bool       `json:"password_protected"`
+	AccessCount       int64      `json:"access_count"`
+	LastAccessedAt    *time.Time `json:"last_accessed_at"`
+	passwordHash      []byte
+}
+
+// PasswordMatches reports whether password unlocks the share. Shares without
+// a password accept any password.
+func (s *Share) PasswordMatches(password string) (bool, error) {
+	if s.passwordHash == nil {
+		return true, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword(s.passwordHash, []byte(password))
+	if err != nil {
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+type ShareModel struct {
+	DB *sql.DB
+}
+
+// Insert generates the share's token, hashes the password if one is given
+// and stores the share.
+func (m ShareModel) Insert(share *Share, password string) error {
+	token, tokenHash, err := newRandomToken()
+	if err != nil {
+		return err
+	}
+
+	var passwordHash []byte
+
+	if password != "" {
+		passwordHash, err = bcrypt.GenerateFromPassword([]byte(password), 12)
+		if err != nil {
+			return err
+		}
+	}
+
+	query := `
+        INSERT INTO note_shares (note_id, token_hash, password_hash, expires_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at`
+
+	err = m.DB.QueryRow(query, share.NoteID, tokenHash, passwordHash, share.ExpiresAt).Scan(&share.ID, &share.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	share.Token = token
+
+	share.passwordHash = passwordHash
+	share.PasswordProtected = passwordHash != nil
+
+	return nil
+}
+
+// GetByToken returns the share for a plaintext token. Expired shares are
+// reported as not found.
+func (m ShareModel) GetByToken(token string) (*Share, error) {
+	tokenHash := sha256.Sum256([]byte(token))
+
+	query := `
+        SELECT id, note_id, created_at, expires_at, password_hash, access_count, last_accessed_at
+        FROM note_shares
+        WHERE token_hash = $1
+        AND (expires_at IS NULL OR expires_at > NOW())`
+
+	var share Share
+
+	err := m.DB.QueryRow(query, tokenHash[:]).Scan(
+		&share.ID,
+		&share.NoteID,
+		&share.CreatedAt,
+		&share.ExpiresAt,
+		&share.passwordHash,
+		&share.AccessCount,
+		&share.LastAccessedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	share.PasswordProtected = share.passwordHash != nil
+
+	return &share, nil
+}
+
+func (m ShareModel) GetAllForNote(noteID int64) ([]*Share, error) {
+	query := `
+        SELECT id, note_id, created_at, expires_at, password_hash IS NOT NULL, access_count, last_accessed_at
+        FROM note_shares
+        WHERE note_id = $1
+        ORDER BY id`
+
+	rows, err := m.DB.Query(query, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []*Share{}
+
+	for rows.Next() {
+		var share Share
+
+		err := rows.Scan(
+			&share.ID,
+			&share.NoteID,
+			&share.CreatedAt,
+			&share.ExpiresAt,
+			&share.PasswordProtected,
+			&share.AccessCount,
+			&share.LastAccessedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		shares = append(shares, &share)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+// RecordAccess counts a successful view of a shared note.
+func (m ShareModel) RecordAccess(share *Share) error {
+	query := `
+        UPDATE note_shares
+        SET access_count = access_count + 1, last_accessed_at = NOW()
+        WHERE id = $1
+        RETURNING access_count, last_accessed_at`
+
+	err := m.DB.QueryRow(query, share.ID).Scan(&share.AccessCount, &share.LastAccessedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete revokes a share; its token stops working immediately.
+func (m ShareModel) Delete(noteID, id int64) error {
+	if noteID < 1 || id < 1 {
+		return ErrRecordNotFound
+	}
+
+	result, err := m.DB.Exec(`DELETE FROM note_shares WHERE note_id = $1 AND id = $2`, noteID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func ValidateShare(v *validator.Validator, share *Share, password string) {
+	if share.ExpiresAt != nil {
+		v.Check(share.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+	}
+
+	if password != "" {
+		v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
+		v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+	}
+}
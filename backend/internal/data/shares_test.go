@@ -0,0 +1,180 @@
+package data_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func TestValidateShare(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		share    data.Share
+		password string
+		valid    bool
+	}{
+		{
+			name:  "no expiry or password",
+			valid: true,
+		},
+		{
+			name:     "future expiry with password",
+			share:    data.Share{ExpiresAt: &future},
+			password: "correct horse",
+			valid:    true,
+		},
+		{
+			name:  "expiry in the past",
+			share: data.Share{ExpiresAt: &past},
+		},
+		{
+			name:     "password too short",
+			password: "short",
+		},
+		{
+			name:     "password too long",
+			password: strings.Repeat("a", 73),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			data.ValidateShare(v, &tt.share, tt.password)
+
+			if v.Valid() != tt.valid {
+				t.Errorf("expected valid = %t, got errors %v", tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestShareModel(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	notes := data.NoteModel{DB: db}
+	shares := data.ShareModel{DB: db}
+
+	note := &data.Note{Title: "Shared Note", Body: "Body", Tags: []string{}}
+	if err := notes.Insert(note); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("open share", func(t *testing.T) {
+		share := &data.Share{NoteID: note.ID}
+		if err := shares.Insert(share, ""); err != nil {
+			t.Fatal(err)
+		}
+		if len(share.Token) != 26 {
+			t.Fatalf("expected a 26 character token, got %q", share.Token)
+		}
+
+		got, err := shares.GetByToken(share.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.NoteID != note.ID || got.PasswordProtected {
+			t.Errorf("unexpected share %+v", got)
+		}
+
+		if err := shares.RecordAccess(got); err != nil {
+			t.Fatal(err)
+		}
+		if err := shares.RecordAccess(got); err != nil {
+			t.Fatal(err)
+		}
+		if got.AccessCount != 2 || got.LastAccessedAt == nil {
+			t.Errorf("expected 2 recorded accesses, got %d", got.AccessCount)
+		}
+	})
+
+	t.Run("password protected share", func(t *testing.T) {
+		share := &data.Share{NoteID: note.ID}
+		if err := shares.Insert(share, "correct horse"); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := shares.GetByToken(share.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for password, expected := range map[string]bool{"correct horse": true, "wrong horse": false, "": false} {
+			match, err := got.PasswordMatches(password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match != expected {
+				t.Errorf("PasswordMatches(%q) = %t, expected %t", password, match, expected)
+			}
+		}
+	})
+
+	t.Run("expired share", func(t *testing.T) {
+		expiresAt := time.Now().Add(2 * time.Second)
+		share := &data.Share{NoteID: note.ID, ExpiresAt: &expiresAt}
+		if err := shares.Insert(share, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := db.Exec(`UPDATE note_shares SET expires_at = NOW() - INTERVAL '1 minute' WHERE id = $1`, share.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = shares.GetByToken(share.Token)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("expected ErrRecordNotFound for an expired share, got %v", err)
+		}
+	})
+
+	t.Run("revoked share", func(t *testing.T) {
+		share := &data.Share{NoteID: note.ID}
+		if err := shares.Insert(share, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := shares.Delete(note.ID, share.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := shares.GetByToken(share.Token)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("expected ErrRecordNotFound for a revoked share, got %v", err)
+		}
+
+		err = shares.Delete(note.ID, share.ID)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("expected ErrRecordNotFound on second revoke, got %v", err)
+		}
+	})
+
+	t.Run("listing hides tokens", func(t *testing.T) {
+		all, err := shares.GetAllForNote(note.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 3 {
+			t.Fatalf("expected 3 shares, got %d", len(all))
+		}
+		for _, share := range all {
+			if share.Token != "" {
+				t.Errorf("expected listed share %d to have no token", share.ID)
+			}
+		}
+		if !all[1].PasswordProtected {
+			t.Errorf("expected second share to be password protected")
+		}
+	})
+}
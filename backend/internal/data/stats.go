@@ -0,0 +1,190 @@
+package data
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wordsPerMinute is the reading speed assumed for reading time estimates.
+const wordsPerMinute = 200
+
+var checkboxMarkerRX = regexp.MustCompile(`(?m)^\s*[-*+]\s+\[[ xX]\]`)
+
+// NoteStats describes the content of a note. It is derived from the body
+// whenever a note is written out rather than stored.
+type NoteStats struct {
+	Words          int          `json:"words"`
+	Characters     int          `json:"characters"`
+	ReadingMinutes int          `json:"reading_minutes"`
+	Tasks          TaskProgress `json:"tasks"`
+}
+
+// TaskProgress counts the tasks in a note, as found by ParseTasks.
+type TaskProgress struct {
+	Total int `json:"total"`
+	Done  int `json:"done"`
+}
+
+// ComputeNoteStats works out the statistics for a note body. Words are runs
+// of text containing at least one letter or digit, so Markdown syntax such
+// as list bullets, heading markers and checkboxes isn't counted.
+func ComputeNoteStats(body string) NoteStats {
+	var stats NoteStats
+
+	for _, field := range strings.Fields(checkboxMarkerRX.ReplaceAllString(body, "")) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			stats.Words++
+		}
+	}
+
+	stats.Characters = utf8.RuneCountInString(body)
+	stats.ReadingMinutes = (stats.Words + wordsPerMinute - 1) / wordsPerMinute
+
+	for _, task := range ParseTasks(body) {
+		stats.Tasks.Total++
+		if task.Done {
+			stats.Tasks.Done++
+		}
+	}
+
+	return stats
+}
+
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Notes int    `json:"notes"`
+}
+
+// WeekCount holds the number of notes created in the week starting on Week,
+// a date in YYYY-MM-DD form.
+type WeekCount struct {
+	Week  string `json:"week"`
+	Notes int    `json:"notes"`
+}
+
+// Stats aggregates the statistics of every note visible to a user.
+type Stats struct {
+	Notes          int          `json:"notes"`
+	Archived       int          `json:"archived"`
+	ArchivedRatio  float64      `json:"archived_ratio"`
+	Words          int          `json:"words"`
+	Characters     int          `json:"characters"`
+	ReadingMinutes int          `json:"reading_minutes"`
+	Tasks          TaskProgress `json:"tasks"`
+	NotesPerTag    []TagCount   `json:"notes_per_tag"`
+	NotesPerWeek   []WeekCount  `json:"notes_per_week"`
+}
+
+type StatsModel struct {
+	DB *sql.DB
+}
+
+// Get computes the statistics for the notes visible to the user. Weeks start
+// on Monday and only weeks in which notes were created are listed.
+func (m StatsModel) Get(userID int64) (*Stats, error) {
+	stats := &Stats{
+		NotesPerTag:  []TagCount{},
+		NotesPerWeek: []WeekCount{},
+	}
+
+	query := `
+        SELECT n.body, n.archived
+        FROM notes n
+        WHERE ` + visibleTo("n", 1)
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var body string
+		var archived bool
+
+		if err := rows.Scan(&body, &archived); err != nil {
+			return nil, err
+		}
+
+		noteStats := ComputeNoteStats(body)
+
+		stats.Notes++
+		if archived {
+			stats.Archived++
+		}
+		stats.Words += noteStats.Words
+		stats.Characters += noteStats.Characters
+		stats.ReadingMinutes += noteStats.ReadingMinutes
+		stats.Tasks.Total += noteStats.Tasks.Total
+		stats.Tasks.Done += noteStats.Tasks.Done
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if stats.Notes > 0 {
+		stats.ArchivedRatio = float64(stats.Archived) / float64(stats.Notes)
+	}
+
+	query = `
+        SELECT tag, count(*)
+        FROM notes n, unnest(n.tags) AS tag
+        WHERE ` + visibleTo("n", 1) + `
+        GROUP BY tag
+        ORDER BY count(*) DESC, tag`
+
+	tagRows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+
+	for tagRows.Next() {
+		var tagCount TagCount
+
+		if err := tagRows.Scan(&tagCount.Tag, &tagCount.Notes); err != nil {
+			return nil, err
+		}
+
+		stats.NotesPerTag = append(stats.NotesPerTag, tagCount)
+	}
+
+	if err = tagRows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+        SELECT date_trunc('week', n.created_at AT TIME ZONE 'UTC') AS week, count(*)
+        FROM notes n
+        WHERE ` + visibleTo("n", 1) + `
+        GROUP BY week
+        ORDER BY week`
+
+	weekRows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer weekRows.Close()
+
+	for weekRows.Next() {
+		var week time.Time
+		var notes int
+
+		if err := weekRows.Scan(&week, &notes); err != nil {
+			return nil, err
+		}
+
+		stats.NotesPerWeek = append(stats.NotesPerWeek, WeekCount{Week: week.Format(time.DateOnly), Notes: notes})
+	}
+
+	if err = weekRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
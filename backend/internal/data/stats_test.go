@@ -0,0 +1,72 @@
+package data_test
+
+import (
+	"testing"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+)
+
+func TestComputeNoteStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected data.NoteStats
+	}{
+		{
+			name:     "empty",
+			body:     "",
+			expected: data.NoteStats{},
+		},
+		{
+			name:     "markdown syntax is not counted as words",
+			body:     "# Heading\n\n- one two\n- three",
+			expected: data.NoteStats{Words: 4, Characters: 28, ReadingMinutes: 1},
+		},
+		{
+			name: "checkboxes",
+			body: "- [ ] buy milk\n- [x] walk dog\n* [X] pay rent\nnot a [ ] task",
+			expected: data.NoteStats{
+				Words:          9,
+				Characters:     59,
+				ReadingMinutes: 1,
+				Tasks:          data.TaskProgress{Total: 3, Done: 2},
+			},
+		},
+		{
+			name: "TODO lines are open tasks",
+			body: "Plan\n\nTODO: Book flights",
+			expected: data.NoteStats{
+				Words:          4,
+				Characters:     24,
+				ReadingMinutes: 1,
+				Tasks:          data.TaskProgress{Total: 1},
+			},
+		},
+		{
+			name:     "multi-byte characters",
+			body:     "日本 café",
+			expected: data.NoteStats{Words: 2, Characters: 7, ReadingMinutes: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := data.ComputeNoteStats(tt.body)
+
+			if got != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("reading time rounds up", func(t *testing.T) {
+		body := ""
+		for range 201 {
+			body += "word "
+		}
+
+		if got := data.ComputeNoteStats(body).ReadingMinutes; got != 2 {
+			t.Errorf("expected 2 minutes for 201 words, got %d", got)
+		}
+	})
+}
@@ -0,0 +1,298 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+	"github.com/lib/pq"
+)
+
+const (
+	SyncOpCreate = "create"
+	SyncOpUpdate = "update"
+	SyncOpDelete = "delete"
+)
+
+type SyncModel struct {
+	DB  *sql.DB
+	ctx context.Context
+}
+
+// WithContext returns a copy of the model whose queries are traced as part
+// of ctx and whose changes are audited as made by the AuditSource it
+// carries.
+func (m SyncModel) WithContext(ctx context.Context) SyncModel {
+	m.ctx = context.WithoutCancel(ctx)
+	return m
+}
+
+type Tombstone struct {
+	ID        int64     `json:"id"`
+	Version   int       `json:"version"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type ChangeSet struct {
+	Notes      []*Note      `json:"notes"`
+	Tombstones []*Tombstone `json:"tombstones"`
+	Cursor     int64        `json:"cursor"`
+	HasMore    bool         `json:"has_more"`
+}
+
+type SyncMutation struct {
+	ClientID string     `json:"client_id"`
+	Op       string     `json:"op"`
+	ID       int64      `json:"id"`
+	Version  int        `json:"version"`
+	Title    string     `json:"title"`
+	Body     string     `json:"body"`
+	Tags     []string   `json:"tags"`
+	Archived bool       `json:"archived"`
+	DueAt    *time.Time `json:"due_at"`
+}
+
+type SyncResult struct {
+	ClientID string `json:"client_id"`
+	Op       string `json:"op"`
+	Note     *Note  `json:"note,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type SyncConflict struct {
+	ClientID  string        `json:"client_id"`
+	Op        string        `json:"op"`
+	Server    *Note         `json:"server"`
+	Tombstone *Tombstone    `json:"tombstone,omitempty"`
+	Client    *SyncMutation `json:"client"`
+}
+
+// Changes returns every note visible to the user that was created or
+// modified, and every such note deleted, after the since cursor in change
+// order.
+// The returned cursor is the change_seq of the last entry included and
+// should be passed back as since on the next call; HasMore reports whether
+// another page is waiting. change_seq is assigned as each transaction
+// commits (see migration 000019), so no change can later appear behind a
+// cursor that has already been handed out.
+func (m SyncModel) Changes(since int64, limit int, userID int64) (*ChangeSet, error) {
+	ctx, span := startSpan(m.ctx, "sync.changes", "notes")
+	defer span.End()
+
+	query := `
+        SELECT n.change_seq, n.id, n.created_at, n.updated_at, n.title, n.body, n.tags, n.archived, n.pinned, n.favorite, n.notebook_id, n.due_at, n.version, FALSE
+        FROM notes n
+        WHERE n.change_seq > $1 AND ` + visibleTo("n", 3) + `
+        UNION ALL
+        SELECT change_seq, note_id, deleted_at, deleted_at, '', '', '{}', FALSE, FALSE, FALSE, NULL, NULL, version, TRUE
+        FROM note_tombstones
+        WHERE change_seq > $1 AND ` + tombstoneVisibleTo(3) + `
+        ORDER BY 1
+        LIMIT $2`
+
+	rows, err := m.DB.QueryContext(ctx, query, since, limit+1, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := &ChangeSet{
+		Notes:      []*Note{},
+		Tombstones: []*Tombstone{},
+		Cursor:     since,
+	}
+
+	count := 0
+
+	for rows.Next() {
+		var (
+			changeSeq int64
+			deleted   bool
+			note      Note
+		)
+
+		err := rows.Scan(
+			&changeSeq,
+			&note.ID,
+			&note.CreatedAt,
+			&note.UpdatedAt,
+			&note.Title,
+			&note.Body,
+			pq.Array(&note.Tags),
+			&note.Archived,
+			&note.Pinned,
+			&note.Favorite,
+			&note.NotebookID,
+			&note.DueAt,
+			&note.Version,
+			&deleted,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		count++
+		if count > limit {
+			changes.HasMore = true
+			break
+		}
+
+		if deleted {
+			changes.Tombstones = append(changes.Tombstones, &Tombstone{
+				ID:        note.ID,
+				Version:   note.Version,
+				DeletedAt: note.UpdatedAt,
+			})
+		} else {
+			changes.Notes = append(changes.Notes, &note)
+		}
+
+		changes.Cursor = changeSeq
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// tombstoneVisibleTo is the condition for a tombstone to be shown to the
+// user whose ID is the query parameter numbered param: they could read the
+// note before it was deleted.
+func tombstoneVisibleTo(param int) string {
+	return fmt.Sprintf(`(reader_ids IS NULL OR $%d = ANY(reader_ids))`, param)
+}
+
+// GetTombstone returns the tombstone left behind by a deleted note that the
+// user could read.
+func (m SyncModel) GetTombstone(id, userID int64) (*Tombstone, error) {
+	ctx, span := startSpan(m.ctx, "sync.get_tombstone", "note_tombstones")
+	defer span.End()
+
+	query := `
+        SELECT note_id, version, deleted_at
+        FROM note_tombstones
+        WHERE note_id = $1 AND ` + tombstoneVisibleTo(2)
+
+	var tombstone Tombstone
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(&tombstone.ID, &tombstone.Version, &tombstone.DeletedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &tombstone, nil
+}
+
+// DeleteAtVersion deletes a note only if it is still at the given version,
+// returning ErrEditConflict otherwise.
+func (m SyncModel) DeleteAtVersion(id int64, version int) error {
+	ctx, span := startSpan(m.ctx, "sync.delete_at_version", "notes")
+	defer span.End()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = deleteNote(tx, id, version, auditSourceFrom(m.ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return m.missOrConflict(ctx, id)
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// missOrConflict works out why a versioned write matched no rows: the note
+// either moved on to another version or is gone altogether.
+func (m SyncModel) missOrConflict(ctx context.Context, id int64) error {
+	query := `
+        SELECT EXISTS(SELECT 1 FROM notes WHERE id = $1)
+            OR EXISTS(SELECT 1 FROM note_tombstones WHERE note_id = $1)`
+
+	var exists bool
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrRecordNotFound
+	}
+
+	return ErrEditConflict
+}
+
+// Conflict describes a rejected mutation alongside the server's current copy
+// of the note, or its tombstone if the note has since been deleted and the
+// user could read it.
+func (m SyncModel) Conflict(mutation *SyncMutation, userID int64) (*SyncConflict, error) {
+	conflict := &SyncConflict{
+		ClientID: mutation.ClientID,
+		Op:       mutation.Op,
+		Client:   mutation,
+	}
+
+	note, err := NoteModel{DB: m.DB, ctx: m.ctx}.Get(mutation.ID)
+	switch {
+	case err == nil:
+		conflict.Server = note
+		return conflict, nil
+	case !errors.Is(err, ErrRecordNotFound):
+		return nil, err
+	}
+
+	tombstone, err := m.GetTombstone(mutation.ID, userID)
+	if err != nil && !errors.Is(err, ErrRecordNotFound) {
+		return nil, err
+	}
+
+	conflict.Tombstone = tombstone
+
+	return conflict, nil
+}
+
+func ValidateSyncMutation(v *validator.Validator, mutation *SyncMutation) {
+	v.Check(mutation.ClientID != "", "client_id", "must be provided")
+	v.Check(len(mutation.ClientID) <= 100, "client_id", "must not be more than 100 bytes long")
+
+	v.Check(validator.PermittedValue(mutation.Op, SyncOpCreate, SyncOpUpdate, SyncOpDelete), "op", "invalid value")
+
+	if mutation.Op == SyncOpUpdate || mutation.Op == SyncOpDelete {
+		v.Check(mutation.ID > 0, "id", "must be a positive integer")
+		v.Check(mutation.Version > 0, "version", "must be a positive integer")
+	}
+
+	if mutation.Op == SyncOpCreate || mutation.Op == SyncOpUpdate {
+		ValidateNote(v, mutation.Note())
+	}
+}
+
+// Note returns the note a create or update mutation would write.
+func (mutation *SyncMutation) Note() *Note {
+	return &Note{
+		ID:       mutation.ID,
+		Title:    mutation.Title,
+		Body:     mutation.Body,
+		Tags:     mutation.Tags,
+		Archived: mutation.Archived,
+		DueAt:    mutation.DueAt,
+		Version:  mutation.Version,
+	}
+}
@@ -0,0 +1,234 @@
+package data_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func newTestSyncModel(t *testing.T) data.SyncModel {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+	return data.SyncModel{DB: db}
+}
+
+func TestSyncModel_Changes(t *testing.T) {
+	notes := newTestModel(t)
+	model := newTestSyncModel(t)
+
+	start, err := model.Changes(0, 1000000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := &data.Note{Title: "Created", Body: "Body", Tags: []string{"sync"}}
+	if err := notes.Insert(created); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted := &data.Note{Title: "Deleted", Body: "Body", Tags: []string{"sync"}}
+	if err := notes.Insert(deleted); err != nil {
+		t.Fatal(err)
+	}
+	if err := notes.Delete(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := model.Changes(start.Cursor, 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes.Notes) != 1 || changes.Notes[0].ID != created.ID {
+		t.Errorf("expected only note %d to be returned, got %+v", created.ID, changes.Notes)
+	}
+	if len(changes.Tombstones) != 1 || changes.Tombstones[0].ID != deleted.ID {
+		t.Errorf("expected a tombstone for note %d, got %+v", deleted.ID, changes.Tombstones)
+	}
+	if changes.Cursor <= start.Cursor {
+		t.Errorf("expected cursor to advance past %d, got %d", start.Cursor, changes.Cursor)
+	}
+
+	page, err := model.Changes(start.Cursor, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !page.HasMore {
+		t.Error("expected HasMore to be true when the limit cuts the change set short")
+	}
+
+	empty, err := model.Changes(changes.Cursor, 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty.Notes) != 0 || len(empty.Tombstones) != 0 {
+		t.Errorf("expected no changes after the latest cursor, got %+v", empty)
+	}
+	if empty.Cursor != changes.Cursor {
+		t.Errorf("expected cursor to stay at %d, got %d", changes.Cursor, empty.Cursor)
+	}
+}
+
+func TestSyncModel_DeleteAtVersion(t *testing.T) {
+	notes := newTestModel(t)
+	model := newTestSyncModel(t)
+
+	note := &data.Note{Title: "To Delete", Body: "Body", Tags: []string{"sync"}}
+	if err := notes.Insert(note); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := model.DeleteAtVersion(note.ID, 2); !errors.Is(err, data.ErrEditConflict) {
+		t.Errorf("expected ErrEditConflict for a stale version, got %v", err)
+	}
+
+	if err := model.DeleteAtVersion(note.ID, 1); err != nil {
+		t.Fatalf("DeleteAtVersion() error = %v", err)
+	}
+
+	if err := model.DeleteAtVersion(note.ID, 1); !errors.Is(err, data.ErrEditConflict) {
+		t.Errorf("expected ErrEditConflict when deleting a tombstoned note, got %v", err)
+	}
+
+	tombstone, err := model.GetTombstone(note.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tombstone.Version != 1 {
+		t.Errorf("expected tombstone version 1, got %d", tombstone.Version)
+	}
+}
+
+func TestValidateSyncMutation(t *testing.T) {
+	tests := []struct {
+		name           string
+		mutation       *data.SyncMutation
+		expectedErrors []string
+	}{
+		{
+			name: "valid create",
+			mutation: &data.SyncMutation{
+				ClientID: "c1",
+				Op:       data.SyncOpCreate,
+				Title:    "Title",
+				Body:     "Body",
+				Tags:     []string{},
+			},
+		},
+		{
+			name: "valid delete",
+			mutation: &data.SyncMutation{
+				ClientID: "c2",
+				Op:       data.SyncOpDelete,
+				ID:       1,
+				Version:  1,
+			},
+		},
+		{
+			name: "unknown op",
+			mutation: &data.SyncMutation{
+				ClientID: "c3",
+				Op:       "merge",
+			},
+			expectedErrors: []string{"op"},
+		},
+		{
+			name: "update without base version",
+			mutation: &data.SyncMutation{
+				ClientID: "c4",
+				Op:       data.SyncOpUpdate,
+				ID:       1,
+				Title:    "Title",
+				Body:     "Body",
+				Tags:     []string{},
+			},
+			expectedErrors: []string{"version"},
+		},
+		{
+			name: "create missing note fields",
+			mutation: &data.SyncMutation{
+				Op: data.SyncOpCreate,
+			},
+			expectedErrors: []string{"client_id", "title", "body", "tags"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			data.ValidateSyncMutation(v, tt.mutation)
+
+			if len(v.Errors) != len(tt.expectedErrors) {
+				t.Errorf("expected %d errors, got %v", len(tt.expectedErrors), v.Errors)
+			}
+			for _, key := range tt.expectedErrors {
+				if _, exists := v.Errors[key]; !exists {
+					t.Errorf("expected error for field %s, but it was not found", key)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncModel_TombstonesOfPrivateNotes(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	models := data.NewModels(db)
+
+	newUser := func(name string) *data.User {
+		user := &data.User{Name: name, Email: fmt.Sprintf("%s-%d@example.com", name, time.Now().UnixNano())}
+		if err := user.Password.Set("pa55word1234"); err != nil {
+			t.Fatal(err)
+		}
+		if err := models.Users.Insert(user); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+
+	owner := newUser("owner")
+	stranger := newUser("stranger")
+
+	note := &data.Note{Title: "Private", Body: "Body", Tags: []string{}}
+	if err := models.Notes.InsertOwned(note, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := models.Sync.DeleteAtVersion(note.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted := func(userID int64) bool {
+		changes, err := models.Sync.Changes(0, 100_000, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tombstone := range changes.Tombstones {
+			if tombstone.ID == note.ID {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !deleted(owner.ID) {
+		t.Error("expected the owner to be told about the deletion")
+	}
+	if deleted(stranger.ID) {
+		t.Error("expected another user not to be told about the deletion")
+	}
+
+	if _, err := models.Sync.GetTombstone(note.ID, stranger.ID); !errors.Is(err, data.ErrRecordNotFound) {
+		t.Errorf("expected ErrRecordNotFound for another user's tombstone, got %v", err)
+	}
+}
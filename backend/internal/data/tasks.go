@@ -0,0 +1,219 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	checkboxRX = regexp.MustCompile(`^(\s*[-*+]\s+\[)([ xX])(\]\s*)(.*)$`)
+	todoLineRX = regexp.MustCompile(`^(\s*)(TODO|DONE):(\s*)(.*)$`)
+)
+
+// Task is a to-do item found in a note body: either a Markdown task list
+// item such as "- [ ] call Alex" or a "TODO: call Alex" line, which becomes
+// "DONE: call Alex" once completed. Position counts the tasks in the note
+// from zero and Line is the zero-based line of the body the task is on.
+// Tasks keep their ID for as long as their position in the note is
+// unchanged.
+type Task struct {
+	ID        int64  `json:"id"`
+	NoteID    int64  `json:"note_id"`
+	NoteTitle string `json:"note_title,omitempty"`
+	Position  int    `json:"position"`
+	Line      int    `json:"line"`
+	Text      string `json:"text"`
+	Done      bool   `json:"done"`
+}
+
+type TaskModel struct {
+	DB  *sql.DB
+	ctx context.Context
+}
+
+// WithContext returns a copy of the model whose queries are traced as part
+// of ctx.
+func (m TaskModel) WithContext(ctx context.Context) TaskModel {
+	m.ctx = context.WithoutCancel(ctx)
+	return m
+}
+
+// ParseTasks returns the tasks in body in the order they appear.
+func ParseTasks(body string) []Task {
+	tasks := []Task{}
+
+	for i, line := range strings.Split(body, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+
+		if match := checkboxRX.FindStringSubmatch(line); match != nil {
+			tasks = append(tasks, Task{Position: len(tasks), Line: i, Text: strings.TrimSpace(match[4]), Done: match[2] != " "})
+			continue
+		}
+
+		if match := todoLineRX.FindStringSubmatch(line); match != nil {
+			tasks = append(tasks, Task{Position: len(tasks), Line: i, Text: strings.TrimSpace(match[4]), Done: match[2] == "DONE"})
+		}
+	}
+
+	return tasks
+}
+
+// SetTaskDone rewrites the line of body holding the task at position so that
+// it is marked done or not done, leaving everything else untouched.
+func SetTaskDone(body string, position int, done bool) (string, error) {
+	tasks := ParseTasks(body)
+
+	if position < 0 || position >= len(tasks) {
+		return "", ErrRecordNotFound
+	}
+
+	lines := strings.Split(body, "\n")
+	i := tasks[position].Line
+	line := lines[i]
+
+	if match := checkboxRX.FindStringSubmatchIndex(line); match != nil {
+		mark := " "
+		if done {
+			mark = "x"
+		}
+		lines[i] = line[:match[4]] + mark + line[match[5]:]
+	} else {
+		match := todoLineRX.FindStringSubmatchIndex(line)
+		keyword := "TODO"
+		if done {
+			keyword = "DONE"
+		}
+		lines[i] = line[:match[4]] + keyword + line[match[5]:]
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (m TaskModel) Get(id int64) (*Task, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	ctx, span := startSpan(m.ctx, "tasks.get", "note_tasks")
+	defer span.End()
+
+	query := `
+        SELECT t.id, t.note_id, n.title, t.position, t.line, t.text, t.done
+        FROM note_tasks t
+        INNER JOIN notes n ON n.id = t.note_id
+        WHERE t.id = $1`
+
+	var task Task
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&task.ID,
+		&task.NoteID,
+		&task.NoteTitle,
+		&task.Position,
+		&task.Line,
+		&task.Text,
+		&task.Done,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &task, nil
+}
+
+// GetAll returns a page of the tasks in notes visible to the user, ordered
+// by when their note was last updated and then by position. A nil done
+// matches both open and completed tasks, an empty tag matches any note and a
+// zero noteID matches every note.
+func (m TaskModel) GetAll(done *bool, tag string, noteID int64, filters Filters, userID int64) ([]*Task, Metadata, error) {
+	ctx, span := startSpan(m.ctx, "tasks.get_all", "note_tasks")
+	defer span.End()
+
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), t.id, t.note_id, n.title, t.position, t.line, t.text, t.done
+        FROM note_tasks t
+        INNER JOIN notes n ON n.id = t.note_id
+        WHERE (t.done = $1 OR $1::boolean IS NULL)
+        AND ($2 = '' OR n.tags @> $3)
+        AND (t.note_id = $4 OR $4 = 0)
+        AND `+visibleTo("n", 5)+`
+        ORDER BY n.%s %s, t.note_id, t.position
+        LIMIT $6 OFFSET $7`, filters.sortColumn(), filters.sortDirection())
+
+	args := []any{done, tag, pq.Array([]string{tag}), noteID, userID, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	tasks := []*Task{}
+
+	for rows.Next() {
+		var task Task
+
+		err := rows.Scan(
+			&totalRecords,
+			&task.ID,
+			&task.NoteID,
+			&task.NoteTitle,
+			&task.Position,
+			&task.Line,
+			&task.Text,
+			&task.Done,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		tasks = append(tasks, &task)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return tasks, metadata, nil
+}
+
+// syncTasks brings the stored tasks of note in line with its body. Tasks are
+// matched up by position so that their IDs survive edits elsewhere in the
+// note.
+func syncTasks(tx *sql.Tx, note *Note) error {
+	tasks := ParseTasks(note.Body)
+
+	_, err := tx.Exec(`DELETE FROM note_tasks WHERE note_id = $1 AND position >= $2`, note.ID, len(tasks))
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO note_tasks (note_id, position, line, text, done)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (note_id, position)
+        DO UPDATE SET line = EXCLUDED.line, text = EXCLUDED.text, done = EXCLUDED.done`
+
+	for _, task := range tasks {
+		_, err = tx.Exec(query, note.ID, task.Position, task.Line, task.Text, task.Done)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
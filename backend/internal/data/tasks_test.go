@@ -0,0 +1,118 @@
+package data_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
+)
+
+func TestParseTasks(t *testing.T) {
+	body := "Groceries\n- [ ] milk\n  * [x] bread\nTODO: book flights\nDONE: pack\nnot - [ ] a task\r\n+ [X] eggs\r"
+
+	expected := []data.Task{
+		{Position: 0, Line: 1, Text: "milk"},
+		{Position: 1, Line: 2, Text: "bread", Done: true},
+		{Position: 2, Line: 3, Text: "book flights"},
+		{Position: 3, Line: 4, Text: "pack", Done: true},
+		{Position: 4, Line: 6, Text: "eggs", Done: true},
+	}
+
+	got := data.ParseTasks(body)
+
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestSetTaskDone(t *testing.T) {
+	body := "Intro\n- [ ] milk\nTODO: book flights\n- [x] bread"
+
+	tests := []struct {
+		name     string
+		position int
+		done     bool
+		expected string
+		wantErr  error
+	}{
+		{"check a checkbox", 0, true, "Intro\n- [x] milk\nTODO: book flights\n- [x] bread", nil},
+		{"uncheck a checkbox", 2, false, "Intro\n- [ ] milk\nTODO: book flights\n- [ ] bread", nil},
+		{"complete a TODO line", 1, true, "Intro\n- [ ] milk\nDONE: book flights\n- [x] bread", nil},
+		{"already in that state", 1, false, body, nil},
+		{"no such task", 3, true, "", data.ErrRecordNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := data.SetTaskDone(body, tt.position, tt.done)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTaskModel(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	models := data.NewModels(db)
+
+	note := &data.Note{Title: "Chores", Body: "- [ ] laundry\nTODO: dishes", Tags: []string{"chores-tasks"}}
+	if err := models.Notes.Insert(note); err != nil {
+		t.Fatal(err)
+	}
+
+	filters := data.Filters{Page: 1, PageSize: 50, Sort: "-updated_at", SortSafelist: []string{"-updated_at"}}
+
+	before, _, err := models.Tasks.GetAll(nil, "", note.ID, filters, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(before) != 2 {
+		t.Fatalf("expected 2 tasks, got %d", len(before))
+	}
+
+	note.Body = "Weekend\n- [x] laundry\nTODO: dishes"
+	if _, err := models.Notes.Update(note); err != nil {
+		t.Fatal(err)
+	}
+
+	after, _, err := models.Tasks.GetAll(nil, "chores-tasks", note.ID, filters, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != 2 {
+		t.Fatalf("expected 2 tasks after the update, got %d", len(after))
+	}
+
+	if after[0].ID != before[0].ID || !after[0].Done || after[0].Line != 1 {
+		t.Errorf("expected task %d to keep its ID and be done on line 1, got %+v", before[0].ID, after[0])
+	}
+
+	done := true
+	completed, _, err := models.Tasks.GetAll(&done, "", note.ID, filters, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(completed) != 1 {
+		t.Errorf("expected 1 completed task, got %d", len(completed))
+	}
+
+	note.Body = "No tasks left"
+	if _, err := models.Notes.Update(note); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := models.Tasks.Get(before[1].ID); !errors.Is(err, data.ErrRecordNotFound) {
+		t.Errorf("expected removed task to be gone, got %v", err)
+	}
+}
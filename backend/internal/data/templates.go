@@ -0,0 +1,388 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+	"github.com/lib/pq"
+)
+
+// maxExpandedBytes caps the size of an expanded template.
+const maxExpandedBytes = 1 << 20
+
+var errTemplateTooLong = errors.New("expands to more than 1MB")
+
+// NoteTemplate is a reusable starting point for notes. Title and Body are Go
+// text/template source; see ExpandNoteTemplate for what they may use. An
+// empty Title leaves the note titled with the title it's created with.
+type NoteTemplate struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Tags      []string  `json:"tags"`
+	OwnerID   *int64    `json:"-"`
+	Version   int       `json:"version"`
+}
+
+// TemplateContext holds what a template is expanded with.
+type TemplateContext struct {
+	Title     string
+	Now       time.Time
+	Variables map[string]string
+}
+
+// templateFuncs are the only functions templates may call. Nothing in them
+// reaches outside the template, and none of text/template's builtins that
+// can allocate without bound, such as printf, are allowed.
+func templateFuncs(ctx TemplateContext) template.FuncMap {
+	return template.FuncMap{
+		"date":    func() string { return ctx.Now.Format(time.DateOnly) },
+		"time":    func() string { return ctx.Now.Format("15:04") },
+		"weekday": func() string { return ctx.Now.Weekday().String() },
+		"title":   func() string { return ctx.Title },
+		"upper":   strings.ToUpper,
+		"lower":   strings.ToLower,
+		"trim":    strings.TrimSpace,
+		"default": func(fallback, value string) string {
+			if value == "" {
+				return fallback
+			}
+			return value
+		},
+	}
+}
+
+// allowedBuiltins are the text/template builtins templates may use on top
+// of templateFuncs.
+var allowedBuiltins = map[string]bool{
+	"and": true, "or": true, "not": true, "eq": true, "ne": true,
+}
+
+// parseNoteTemplate parses template source, rejecting anything outside the
+// restricted subset: calls to functions not in templateFuncs or
+// allowedBuiltins, range loops, and defining or invoking named templates.
+func parseNoteTemplate(name, src string, funcs template.FuncMap) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Funcs(funcs).Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tmpl.Templates()) > 1 {
+		return nil, errors.New("must not define templates")
+	}
+
+	if tmpl.Tree == nil {
+		return tmpl, nil
+	}
+
+	err = checkTemplateNode(tmpl.Tree.Root, funcs)
+	if err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+func checkTemplateNode(node parse.Node, funcs template.FuncMap) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkTemplateNode(child, funcs); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return checkTemplateNode(n.Pipe, funcs)
+	case *parse.IfNode:
+		return checkBranch(&n.BranchNode, funcs)
+	case *parse.WithNode:
+		return checkBranch(&n.BranchNode, funcs)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			if err := checkTemplateNode(cmd, funcs); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := checkTemplateNode(arg, funcs); err != nil {
+				return err
+			}
+		}
+	case *parse.ChainNode:
+		return checkTemplateNode(n.Node, funcs)
+	case *parse.IdentifierNode:
+		if _, ok := funcs[n.Ident]; !ok && !allowedBuiltins[n.Ident] {
+			return fmt.Errorf("function %q is not available in templates", n.Ident)
+		}
+	case *parse.RangeNode:
+		return errors.New("range is not available in templates")
+	case *parse.TemplateNode:
+		return errors.New("named templates are not available in templates")
+	}
+
+	return nil
+}
+
+func checkBranch(n *parse.BranchNode, funcs template.FuncMap) error {
+	if err := checkTemplateNode(n.Pipe, funcs); err != nil {
+		return err
+	}
+	if err := checkTemplateNode(n.List, funcs); err != nil {
+		return err
+	}
+	return checkTemplateNode(n.ElseList, funcs)
+}
+
+// expandTemplate runs template source with the context's variables as dot,
+// so that a variable "client" is written {{.client}}. Variables that weren't
+// supplied expand to "", which lets a template give optional ones a
+// fallback with {{.client | default "TBC"}}.
+func expandTemplate(name, src string, ctx TemplateContext) (string, error) {
+	tmpl, err := parseNoteTemplate(name, src, templateFuncs(ctx))
+	if err != nil {
+		return "", err
+	}
+
+	variables := ctx.Variables
+	if variables == nil {
+		variables = map[string]string{}
+	}
+
+	var out limitedBuilder
+
+	err = tmpl.Execute(&out, variables)
+	if err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+type limitedBuilder struct {
+	strings.Builder
+}
+
+func (b *limitedBuilder) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > maxExpandedBytes {
+		return 0, errTemplateTooLong
+	}
+	return b.Builder.Write(p)
+}
+
+// ExpandNoteTemplate builds a note from a template. Templates can call
+// date, time and weekday for the moment of expansion, title for the title
+// the note is being created with, the string helpers upper, lower, trim and
+// default, and the builtins and, or, not, eq and ne; user-supplied
+// variables are read as fields of dot. Errors describe what went wrong in
+// the template and are meant to be shown to the user.
+func ExpandNoteTemplate(tmpl *NoteTemplate, ctx TemplateContext) (*Note, error) {
+	title := ctx.Title
+
+	if tmpl.Title != "" {
+		var err error
+
+		title, err = expandTemplate("title", tmpl.Title, ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	body, err := expandTemplate("body", tmpl.Body, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := tmpl.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	note := &Note{
+		Title: strings.TrimSpace(title),
+		Body:  body,
+		Tags:  tags,
+	}
+
+	return note, nil
+}
+
+type NoteTemplateModel struct {
+	DB *sql.DB
+}
+
+func (m NoteTemplateModel) Insert(tmpl *NoteTemplate, owner *User) error {
+	if !owner.IsAnonymous() {
+		tmpl.OwnerID = &owner.ID
+	}
+
+	query := `
+        INSERT INTO note_templates (name, title, body, tags, owner_id)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at, updated_at, version`
+
+	args := []any{tmpl.Name, tmpl.Title, tmpl.Body, pq.Array(tmpl.Tags), tmpl.OwnerID}
+
+	return m.DB.QueryRow(query, args...).Scan(&tmpl.ID, &tmpl.CreatedAt, &tmpl.UpdatedAt, &tmpl.Version)
+}
+
+// Get returns a template visible to the user: their own, or one created
+// without an owner.
+func (m NoteTemplateModel) Get(id, userID int64) (*NoteTemplate, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, created_at, updated_at, name, title, body, tags, owner_id, version
+        FROM note_templates
+        WHERE id = $1 AND (owner_id IS NULL OR owner_id = $2)`
+
+	tmpl, err := scanNoteTemplate(m.DB.QueryRow(query, id, userID))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return tmpl, nil
+}
+
+func (m NoteTemplateModel) GetAll(userID int64) ([]*NoteTemplate, error) {
+	query := `
+        SELECT id, created_at, updated_at, name, title, body, tags, owner_id, version
+        FROM note_templates
+        WHERE owner_id IS NULL OR owner_id = $1
+        ORDER BY lower(name), id`
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []*NoteTemplate{}
+
+	for rows.Next() {
+		tmpl, err := scanNoteTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, tmpl)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+func (m NoteTemplateModel) Update(tmpl *NoteTemplate) error {
+	query := `
+        UPDATE note_templates
+        SET name = $1, title = $2, body = $3, tags = $4, updated_at = NOW(), version = version + 1
+        WHERE id = $5 AND version = $6
+        RETURNING updated_at, version`
+
+	args := []any{tmpl.Name, tmpl.Title, tmpl.Body, pq.Array(tmpl.Tags), tmpl.ID, tmpl.Version}
+
+	err := m.DB.QueryRow(query, args...).Scan(&tmpl.UpdatedAt, &tmpl.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m NoteTemplateModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	result, err := m.DB.Exec(`DELETE FROM note_templates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func scanNoteTemplate(row interface{ Scan(...any) error }) (*NoteTemplate, error) {
+	var tmpl NoteTemplate
+
+	err := row.Scan(
+		&tmpl.ID,
+		&tmpl.CreatedAt,
+		&tmpl.UpdatedAt,
+		&tmpl.Name,
+		&tmpl.Title,
+		&tmpl.Body,
+		pq.Array(&tmpl.Tags),
+		&tmpl.OwnerID,
+		&tmpl.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tmpl, nil
+}
+
+// ValidateNoteTemplate checks a template's fields and that its title and
+// body parse within the restricted template subset.
+func ValidateNoteTemplate(v *validator.Validator, tmpl *NoteTemplate) {
+	v.Check(tmpl.Name != "", "name", "must be provided")
+	v.Check(len(tmpl.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	v.Check(len(tmpl.Title) <= 500, "title", "must not be more than 500 bytes long")
+
+	v.Check(tmpl.Body != "", "body", "must be provided")
+	v.Check(len(tmpl.Body) <= maxExpandedBytes, "body", "must not be more than 1MB long")
+
+	v.Check(tmpl.Tags != nil, "tags", "must be provided")
+	v.Check(validator.Unique(tmpl.Tags), "tags", "must not contain duplicate values")
+
+	funcs := templateFuncs(TemplateContext{})
+
+	if _, err := parseNoteTemplate("title", tmpl.Title, funcs); err != nil {
+		v.AddError("title", err.Error())
+	}
+
+	if _, err := parseNoteTemplate("body", tmpl.Body, funcs); err != nil {
+		v.AddError("body", err.Error())
+	}
+}
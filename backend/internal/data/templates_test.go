@@ -0,0 +1,118 @@
+package data_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func TestExpandNoteTemplate(t *testing.T) {
+	ctx := data.TemplateContext{
+		Title:     "Standup",
+		Now:       time.Date(2024, 10, 3, 9, 30, 0, 0, time.UTC),
+		Variables: map[string]string{"client": "Acme", "room": ""},
+	}
+
+	tests := []struct {
+		name          string
+		title         string
+		body          string
+		expectedTitle string
+		expectedBody  string
+		wantErr       bool
+	}{
+		{
+			name:          "built in placeholders",
+			body:          "# {{title}} {{date}} {{time}} ({{weekday}})",
+			expectedTitle: "Standup",
+			expectedBody:  "# Standup 2024-10-03 09:30 (Thursday)",
+		},
+		{
+			name:          "title template and variables",
+			title:         "{{title}} with {{.client}}",
+			body:          "Client: {{.client | upper}}",
+			expectedTitle: "Standup with Acme",
+			expectedBody:  "Client: ACME",
+		},
+		{
+			name:          "fallbacks for empty and missing variables",
+			body:          "Room: {{.room | default \"TBC\"}}, agenda: {{.agenda | default \"none\"}}",
+			expectedTitle: "Standup",
+			expectedBody:  "Room: TBC, agenda: none",
+		},
+		{
+			name:          "conditionals",
+			body:          "{{if eq .client \"Acme\"}}VIP{{else}}regular{{end}}",
+			expectedTitle: "Standup",
+			expectedBody:  "VIP",
+		},
+		{name: "printf is not available", body: `{{printf "%999999999d" 1}}`, wantErr: true},
+		{name: "range is not available", body: "{{range 1000000000}}x{{end}}", wantErr: true},
+		{name: "named templates are not available", body: `{{define "x"}}y{{end}}{{template "x"}}`, wantErr: true},
+		{name: "call is not available", body: "{{call .client}}", wantErr: true},
+		{name: "bad syntax", body: "{{.client", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			note, err := data.ExpandNoteTemplate(&data.NoteTemplate{Title: tt.title, Body: tt.body, Tags: []string{"meeting"}}, ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if note.Title != tt.expectedTitle || note.Body != tt.expectedBody {
+				t.Errorf("expected %q / %q, got %q / %q", tt.expectedTitle, tt.expectedBody, note.Title, note.Body)
+			}
+		})
+	}
+}
+
+func TestExpandNoteTemplateOutputLimit(t *testing.T) {
+	body := strings.Repeat("{{.big}}", 20)
+
+	_, err := data.ExpandNoteTemplate(&data.NoteTemplate{Body: body}, data.TemplateContext{
+		Variables: map[string]string{"big": strings.Repeat("x", 100_000)},
+	})
+	if err == nil {
+		t.Error("expected an error for output over the limit")
+	}
+}
+
+func TestValidateNoteTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		tmpl        data.NoteTemplate
+		expectedKey string
+	}{
+		{"valid", data.NoteTemplate{Name: "Meeting", Title: "{{title}}", Body: "## {{date}}", Tags: []string{}}, ""},
+		{"missing name", data.NoteTemplate{Body: "x", Tags: []string{}}, "name"},
+		{"unknown function", data.NoteTemplate{Name: "Meeting", Body: "{{exec}}", Tags: []string{}}, "body"},
+		{"disallowed title", data.NoteTemplate{Name: "Meeting", Title: "{{range 3}}{{end}}", Body: "x", Tags: []string{}}, "title"},
+		{"duplicate tags", data.NoteTemplate{Name: "Meeting", Body: "x", Tags: []string{"a", "a"}}, "tags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			data.ValidateNoteTemplate(v, &tt.tmpl)
+
+			if tt.expectedKey == "" {
+				if !v.Valid() {
+					t.Errorf("expected no errors, got %v", v.Errors)
+				}
+				return
+			}
+
+			if _, ok := v.Errors[tt.expectedKey]; !ok {
+				t.Errorf("expected an error for %q, got %v", tt.expectedKey, v.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package data
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer comes from the global provider, so spans go wherever the API has
+// configured traces to be exported, and nowhere until it has.
+var tracer = otel.Tracer("github.com/johndennehy101/note-taking-web-app/backend/internal/data")
+
+// startSpan starts a span for running the named SQL statement, as a child
+// of the span in ctx if there is one.
+func startSpan(ctx context.Context, statement, table string) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return tracer.Start(ctx, statement,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBCollectionName(table),
+			semconv.DBOperationName(statement),
+		),
+	)
+}
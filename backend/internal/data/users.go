@@ -0,0 +1,288 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrDuplicateEmail = errors.New("duplicate email")
+
+	EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+)
+
+// AnonymousUser stands in for requests made without an authentication
+// token.
+var AnonymousUser = &User{}
+
+type User struct {
+	ID            int64     `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	Password      password  `json:"-"`
+	TimeZone      string    `json:"time_zone"`
+	DailyTemplate string    `json:"daily_template"`
+	Version       int       `json:"-"`
+}
+
+// Location returns the user's time zone. The anonymous user, and any user
+// whose zone no longer loads, gets UTC.
+func (u *User) Location() *time.Location {
+	loc, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+func (u *User) IsAnonymous() bool {
+	return u == AnonymousUser
+}
+
+type password struct {
+	plaintext *string
+	hash      []byte
+}
+
+func (p *password) Set(plaintextPassword string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
+	if err != nil {
+		return err
+	}
+
+	p.plaintext = &plaintextPassword
+	p.hash = hash
+
+	return nil
+}
+
+func (p *password) Matches(plaintextPassword string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(p.hash, []byte(plaintextPassword))
+	if err != nil {
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func ValidateEmail(v *validator.Validator, email string) {
+	v.Check(email != "", "email", "must be provided")
+	v.Check(EmailRX.MatchString(email), "email", "must be a valid email address")
+}
+
+func ValidatePasswordPlaintext(v *validator.Validator, password string) {
+	v.Check(password != "", "password", "must be provided")
+	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
+	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+}
+
+// ValidateTimeZone checks for an IANA time zone name such as
+// "Europe/Dublin".
+func ValidateTimeZone(v *validator.Validator, timeZone string) {
+	_, err := time.LoadLocation(timeZone)
+	v.Check(timeZone != "" && timeZone != "Local" && err == nil, "time_zone", "must be a valid IANA time zone")
+}
+
+func ValidateUser(v *validator.Validator, user *User) {
+	v.Check(user.Name != "", "name", "must be provided")
+	v.Check(len(user.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	ValidateEmail(v, user.Email)
+
+	if user.TimeZone != "" {
+		ValidateTimeZone(v, user.TimeZone)
+	}
+
+	v.Check(len(user.DailyTemplate) <= 10_000, "daily_template", "must not be more than 10000 bytes long")
+
+	if user.Password.plaintext != nil {
+		ValidatePasswordPlaintext(v, *user.Password.plaintext)
+	}
+
+	if user.Password.hash == nil {
+		panic("missing password hash for user")
+	}
+}
+
+type UserModel struct {
+	DB  *sql.DB
+	ctx context.Context
+}
+
+// WithContext returns a copy of the model whose queries are traced as part
+// of ctx and whose changes are audited as made by the AuditSource it carries.
+func (m UserModel) WithContext(ctx context.Context) UserModel {
+	m.ctx = context.WithoutCancel(ctx)
+	return m
+}
+
+// Insert registers a user, auditing the registration as made by the new
+// user.
+func (m UserModel) Insert(user *User) error {
+	ctx, span := startSpan(m.ctx, "users.insert", "users")
+	defer span.End()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO users (name, email, password_hash, time_zone, daily_template)
+        VALUES ($1, $2, $3, COALESCE(NULLIF($4, ''), 'UTC'), $5)
+        RETURNING id, created_at, time_zone, version`
+
+	args := []any{user.Name, user.Email, user.Password.hash, user.TimeZone, user.DailyTemplate}
+
+	err = tx.QueryRow(query, args...).Scan(&user.ID, &user.CreatedAt, &user.TimeZone, &user.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_lower_email_idx"`:
+			return ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+
+	source := auditSourceFrom(m.ctx)
+	source.ActorID = &user.ID
+
+	err = appendAuditEvent(tx, source.event(AuditUserRegister, nil, 0, user.Version))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update saves changes to a user, failing with ErrEditConflict if the user
+// was changed by another request since it was read.
+func (m UserModel) Update(user *User) error {
+	ctx, span := startSpan(m.ctx, "users.update", "users")
+	defer span.End()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        UPDATE users
+        SET name = $1, email = $2, password_hash = $3, time_zone = $4, daily_template = $5, version = version + 1
+        WHERE id = $6 AND version = $7
+        RETURNING version`
+
+	args := []any{user.Name, user.Email, user.Password.hash, user.TimeZone, user.DailyTemplate, user.ID, user.Version}
+
+	before := user.Version
+
+	err = tx.QueryRow(query, args...).Scan(&user.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_lower_email_idx"`:
+			return ErrDuplicateEmail
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = appendAuditEvent(tx, auditSourceFrom(m.ctx).event(AuditUserUpdate, nil, before, user.Version))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m UserModel) GetByEmail(email string) (*User, error) {
+	ctx, span := startSpan(m.ctx, "users.get_by_email", "users")
+	defer span.End()
+
+	query := `
+        SELECT id, created_at, name, email, password_hash, time_zone, daily_template, version
+        FROM users
+        WHERE lower(email) = lower($1)`
+
+	var user User
+
+	err := m.DB.QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.TimeZone,
+		&user.DailyTemplate,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+	ctx, span := startSpan(m.ctx, "users.get_for_token", "users")
+	defer span.End()
+
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+        SELECT users.id, users.created_at, users.name, users.email, users.password_hash,
+            users.time_zone, users.daily_template, users.version
+        FROM users
+        INNER JOIN tokens
+        ON users.id = tokens.user_id
+        WHERE tokens.hash = $1
+        AND tokens.scope = $2
+        AND tokens.expiry > $3`
+
+	args := []any{tokenHash[:], tokenScope, time.Now()}
+
+	var user User
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.TimeZone,
+		&user.DailyTemplate,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
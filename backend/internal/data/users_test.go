@@ -0,0 +1,138 @@
+package data_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func TestValidateUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     data.User
+		password string
+		valid    bool
+	}{
+		{
+			name:     "valid user",
+			user:     data.User{Name: "Alice", Email: "alice@example.com"},
+			password: "pa55word1234",
+			valid:    true,
+		},
+		{
+			name:     "missing name",
+			user:     data.User{Email: "alice@example.com"},
+			password: "pa55word1234",
+		},
+		{
+			name:     "invalid email",
+			user:     data.User{Name: "Alice", Email: "alice"},
+			password: "pa55word1234",
+		},
+		{
+			name:     "short password",
+			user:     data.User{Name: "Alice", Email: "alice@example.com"},
+			password: "pa55",
+		},
+		{
+			name:     "valid time zone",
+			user:     data.User{Name: "Alice", Email: "alice@example.com", TimeZone: "Europe/Dublin"},
+			password: "pa55word1234",
+			valid:    true,
+		},
+		{
+			name:     "unknown time zone",
+			user:     data.User{Name: "Alice", Email: "alice@example.com", TimeZone: "Mars/Olympus_Mons"},
+			password: "pa55word1234",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.user.Password.Set(tt.password); err != nil {
+				t.Fatal(err)
+			}
+
+			v := validator.New()
+			data.ValidateUser(v, &tt.user)
+
+			if v.Valid() != tt.valid {
+				t.Errorf("expected valid = %t, got errors %v", tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestUserModel(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	users := data.UserModel{DB: db}
+	tokens := data.TokenModel{DB: db}
+
+	user := &data.User{Name: "Model User", Email: fmt.Sprintf("model-%d@example.com", time.Now().UnixNano())}
+	if err := user.Password.Set("pa55word1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Insert(user); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("duplicate email", func(t *testing.T) {
+		duplicate := &data.User{Name: "Duplicate", Email: user.Email}
+		if err := duplicate.Password.Set("pa55word1234"); err != nil {
+			t.Fatal(err)
+		}
+
+		err := users.Insert(duplicate)
+		if !errors.Is(err, data.ErrDuplicateEmail) {
+			t.Errorf("expected ErrDuplicateEmail, got %v", err)
+		}
+	})
+
+	t.Run("get by email and check password", func(t *testing.T) {
+		got, err := users.GetByEmail(user.Email)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		match, err := got.Password.Matches("pa55word1234")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !match {
+			t.Error("expected password to match")
+		}
+	})
+
+	t.Run("get for token", func(t *testing.T) {
+		token, err := tokens.New(user.ID, time.Hour, data.ScopeAuthentication)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := users.GetForToken(data.ScopeAuthentication, token.Plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != user.ID {
+			t.Errorf("expected user %d, got %d", user.ID, got.ID)
+		}
+
+		if err := tokens.DeleteAllForUser(data.ScopeAuthentication, user.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = users.GetForToken(data.ScopeAuthentication, token.Plaintext)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("expected ErrRecordNotFound for a deleted token, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,642 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+	"github.com/lib/pq"
+)
+
+const (
+	WebhookEventNoteCreated = "note.created"
+	WebhookEventNoteUpdated = "note.updated"
+	WebhookEventNoteDeleted = "note.deleted"
+)
+
+var WebhookEvents = []string{WebhookEventNoteCreated, WebhookEventNoteUpdated, WebhookEventNoteDeleted}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+const (
+	// maxWebhookAttempts is how many times a delivery is tried before it's
+	// marked as failed.
+	maxWebhookAttempts = 8
+
+	// maxWebhookFailures is how many failed attempts in a row, across all of
+	// its deliveries, disable a webhook until its owner turns it back on.
+	maxWebhookFailures = 10
+
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = time.Hour
+
+	// webhookClaimTimeout is how long DeliverDue has to attempt the
+	// deliveries it claims before other instances may claim them again. It
+	// allows for a full batch of attempts that each run until the client
+	// times out.
+	webhookClaimTimeout = 15 * time.Minute
+)
+
+// Webhook subscribes a URL to events on the notes its owner can see. The
+// secret signs every delivery and is only shown when the webhook is created.
+// A webhook that keeps failing is switched off, recording DisabledAt.
+type Webhook struct {
+	ID                  int64      `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	OwnerID             int64      `json:"-"`
+	URL                 string     `json:"url"`
+	Events              []string   `json:"events"`
+	Secret              string     `json:"-"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	Version             int        `json:"version"`
+}
+
+// WebhookDelivery is one event queued for a webhook, with every attempt made
+// to deliver it.
+type WebhookDelivery struct {
+	ID            int64             `json:"id"`
+	WebhookID     int64             `json:"webhook_id"`
+	Event         string            `json:"event"`
+	Payload       json.RawMessage   `json:"payload"`
+	CreatedAt     time.Time         `json:"created_at"`
+	NextAttemptAt time.Time         `json:"next_attempt_at"`
+	Status        string            `json:"status"`
+	Attempts      int               `json:"attempts"`
+	DeliveredAt   *time.Time        `json:"delivered_at"`
+	LastError     string            `json:"last_error,omitempty"`
+	AttemptLog    []*WebhookAttempt `json:"attempt_log"`
+}
+
+// WebhookAttempt records a single delivery attempt. StatusCode is nil when
+// no response was received.
+type WebhookAttempt struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  *int      `json:"status_code"`
+	Error       string    `json:"error,omitempty"`
+	DurationMS  int       `json:"duration_ms"`
+}
+
+// DueWebhookDelivery is a delivery whose next attempt has come due, along
+// with where to send it and how to sign it.
+type DueWebhookDelivery struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// WebhookBackoff returns how long to wait before retrying a delivery that
+// has failed attempts times: 30 seconds after the first failure, doubling
+// with each one after that up to an hour.
+func WebhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, webhookMaxBackoff)
+}
+
+// enqueueNoteEvent queues an event about a note for every active webhook
+// subscribed to it whose owner can see the note. It runs in the transaction
+// making the change, so an event is queued if and only if the change is
+// committed. Deletions must be queued before the note is removed.
+func enqueueNoteEvent(tx *sql.Tx, event string, noteID int64, note any) error {
+	payload, err := json.Marshal(map[string]any{
+		"event":       event,
+		"occurred_at": time.Now().UTC(),
+		"note":        note,
+	})
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO webhook_deliveries (webhook_id, event, payload)
+        SELECT w.id, $1::text, $2::jsonb
+        FROM webhooks w
+        INNER JOIN notes n ON n.id = $3
+        WHERE w.active AND $1::text = ANY(w.events) AND ` + visibleToUser("n", "w.owner_id")
+
+	_, err = tx.Exec(query, event, string(payload), noteID)
+	return err
+}
+
+type WebhookModel struct {
+	DB  *sql.DB
+	ctx context.Context
+}
+
+// WithContext returns a copy of the model whose queries are traced as part
+// of ctx.
+func (m WebhookModel) WithContext(ctx context.Context) WebhookModel {
+	m.ctx = context.WithoutCancel(ctx)
+	return m
+}
+
+// Insert saves a webhook, generating a secret for it if it doesn't have
+// one.
+func (m WebhookModel) Insert(webhook *Webhook) error {
+	ctx, span := startSpan(m.ctx, "webhooks.insert", "webhooks")
+	defer span.End()
+
+	if webhook.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return err
+		}
+
+		webhook.Secret = secret
+	}
+
+	query := `
+        INSERT INTO webhooks (owner_id, url, events, secret)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at, active, consecutive_failures, disabled_at, version`
+
+	args := []any{webhook.OwnerID, webhook.URL, pq.Array(webhook.Events), webhook.Secret}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&webhook.ID,
+		&webhook.CreatedAt,
+		&webhook.Active,
+		&webhook.ConsecutiveFailures,
+		&webhook.DisabledAt,
+		&webhook.Version,
+	)
+}
+
+// Get returns one of the user's webhooks. Other users' webhooks are
+// reported as not found.
+func (m WebhookModel) Get(id, ownerID int64) (*Webhook, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	ctx, span := startSpan(m.ctx, "webhooks.get", "webhooks")
+	defer span.End()
+
+	query := `
+        SELECT id, created_at, owner_id, url, events, secret, active, consecutive_failures, disabled_at, version
+        FROM webhooks
+        WHERE id = $1 AND owner_id = $2`
+
+	var webhook Webhook
+
+	err := m.DB.QueryRowContext(ctx, query, id, ownerID).Scan(webhookFields(&webhook)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &webhook, nil
+}
+
+func (m WebhookModel) GetAll(ownerID int64) ([]*Webhook, error) {
+	ctx, span := startSpan(m.ctx, "webhooks.get_all", "webhooks")
+	defer span.End()
+
+	query := `
+        SELECT id, created_at, owner_id, url, events, secret, active, consecutive_failures, disabled_at, version
+        FROM webhooks
+        WHERE owner_id = $1
+        ORDER BY id`
+
+	rows, err := m.DB.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+
+	for rows.Next() {
+		var webhook Webhook
+
+		err := rows.Scan(webhookFields(&webhook)...)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, &webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// Update saves changes to a webhook. Turning a disabled webhook back on
+// clears its failure count, and its pending deliveries resume.
+func (m WebhookModel) Update(webhook *Webhook) error {
+	ctx, span := startSpan(m.ctx, "webhooks.update", "webhooks")
+	defer span.End()
+
+	query := `
+        UPDATE webhooks
+        SET url = $1, events = $2, secret = $3, active = $4,
+            consecutive_failures = CASE WHEN $4 AND NOT active THEN 0 ELSE consecutive_failures END,
+            disabled_at = CASE WHEN $4 THEN NULL WHEN active THEN NOW() ELSE disabled_at END,
+            version = version + 1
+        WHERE id = $5 AND version = $6
+        RETURNING consecutive_failures, disabled_at, version`
+
+	args := []any{webhook.URL, pq.Array(webhook.Events), webhook.Secret, webhook.Active, webhook.ID, webhook.Version}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&webhook.ConsecutiveFailures, &webhook.DisabledAt, &webhook.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m WebhookModel) Delete(id, ownerID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, span := startSpan(m.ctx, "webhooks.delete", "webhooks")
+	defer span.End()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND owner_id = $2`, id, ownerID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetDeliveries returns a webhook's most recent deliveries, newest first,
+// each with its attempts in the order they were made.
+func (m WebhookModel) GetDeliveries(webhookID int64, limit int) ([]*WebhookDelivery, error) {
+	ctx, span := startSpan(m.ctx, "webhooks.get_deliveries", "webhook_deliveries")
+	defer span.End()
+
+	query := `
+        SELECT id, webhook_id, event, payload, created_at, next_attempt_at, status, attempts, delivered_at, last_error
+        FROM webhook_deliveries
+        WHERE webhook_id = $1
+        ORDER BY id DESC
+        LIMIT $2`
+
+	rows, err := m.DB.QueryContext(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+	byID := make(map[int64]*WebhookDelivery)
+	ids := []int64{}
+
+	for rows.Next() {
+		delivery := WebhookDelivery{AttemptLog: []*WebhookAttempt{}}
+
+		err := rows.Scan(webhookDeliveryFields(&delivery)...)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, &delivery)
+		byID[delivery.ID] = &delivery
+		ids = append(ids, delivery.ID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return deliveries, nil
+	}
+
+	query = `
+        SELECT delivery_id, attempted_at, status_code, error, duration_ms
+        FROM webhook_delivery_attempts
+        WHERE delivery_id = ANY($1)
+        ORDER BY id`
+
+	rows, err = m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var deliveryID int64
+		var attempt WebhookAttempt
+
+		err := rows.Scan(&deliveryID, &attempt.AttemptedAt, &attempt.StatusCode, &attempt.Error, &attempt.DurationMS)
+		if err != nil {
+			return nil, err
+		}
+
+		delivery := byID[deliveryID]
+		delivery.AttemptLog = append(delivery.AttemptLog, &attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// DeliverDue hands up to limit deliveries whose next attempt is due to
+// deliver, which returns the receiver's status code, and records the
+// outcome, returning how many deliveries were handed over. Failed
+// deliveries are retried with exponential backoff until maxWebhookAttempts
+// is reached, and a webhook is disabled once maxWebhookFailures attempts in
+// a row have failed. As with reminders, the deliveries are claimed for
+// webhookClaimTimeout in a transaction of their own, skipping any locked by
+// concurrent workers, and each outcome is recorded in another once the
+// receiver has answered, so no locks are held while waiting on it.
+func (m WebhookModel) DeliverDue(limit int, deliver func(*DueWebhookDelivery) (int, error)) (int, error) {
+	claimedUntil := time.Now().Add(webhookClaimTimeout).Truncate(time.Second)
+
+	due, err := m.claimDue(limit, claimedUntil)
+	if err != nil {
+		return 0, err
+	}
+
+	slices.SortFunc(due, func(a, b *DueWebhookDelivery) int {
+		return cmp.Or(a.NextAttemptAt.Compare(b.NextAttemptAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	// Webhooks disabled part way through the batch get no more attempts;
+	// their remaining deliveries wait for them to be turned back on.
+	disabled := make(map[int64]bool)
+	attempted := 0
+
+	for _, delivery := range due {
+		if disabled[delivery.WebhookID] {
+			ctx, span := startSpan(m.ctx, "webhooks.release_claim", "webhook_deliveries")
+			_, err := m.DB.ExecContext(ctx, `UPDATE webhook_deliveries SET claimed_until = NULL WHERE id = $1 AND claimed_until = $2`, delivery.ID, claimedUntil)
+			span.End()
+			if err != nil {
+				return attempted, err
+			}
+			continue
+		}
+
+		attempted++
+		start := time.Now()
+
+		statusCode, deliverErr := deliver(delivery)
+
+		active, err := m.recordAttempt(delivery, claimedUntil, start, statusCode, deliverErr)
+		if err != nil {
+			return attempted, err
+		}
+
+		disabled[delivery.WebhookID] = !active
+	}
+
+	return attempted, nil
+}
+
+// claimDue claims up to limit due deliveries to active webhooks until
+// claimedUntil.
+func (m WebhookModel) claimDue(limit int, claimedUntil time.Time) ([]*DueWebhookDelivery, error) {
+	ctx, span := startSpan(m.ctx, "webhooks.claim_due", "webhook_deliveries")
+	defer span.End()
+
+	query := `
+        UPDATE webhook_deliveries d
+        SET claimed_until = $2
+        FROM webhooks w
+        WHERE d.id IN (
+            SELECT pd.id
+            FROM webhook_deliveries pd
+            INNER JOIN webhooks pw ON pw.id = pd.webhook_id
+            WHERE pd.status = 'pending' AND pd.next_attempt_at <= NOW() AND pw.active
+            AND (pd.claimed_until IS NULL OR pd.claimed_until <= NOW())
+            ORDER BY pd.next_attempt_at, pd.id
+            LIMIT $1
+            FOR UPDATE OF pd SKIP LOCKED
+        )
+        AND w.id = d.webhook_id
+        RETURNING d.id, d.webhook_id, d.event, d.payload, d.created_at, d.next_attempt_at, d.status, d.attempts,
+            d.delivered_at, d.last_error, w.url, w.secret`
+
+	rows, err := m.DB.QueryContext(ctx, query, limit, claimedUntil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := []*DueWebhookDelivery{}
+
+	for rows.Next() {
+		var delivery DueWebhookDelivery
+
+		err := rows.Scan(append(webhookDeliveryFields(&delivery.WebhookDelivery), &delivery.URL, &delivery.Secret)...)
+		if err != nil {
+			return nil, err
+		}
+
+		due = append(due, &delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return due, nil
+}
+
+// recordAttempt records the outcome of an attempt at a delivery claimed
+// until claimedUntil, reporting whether its webhook is still active. Nothing
+// is recorded if the claim ran out and the delivery was claimed again.
+func (m WebhookModel) recordAttempt(delivery *DueWebhookDelivery, claimedUntil, start time.Time, statusCode int, deliverErr error) (bool, error) {
+	finish := time.Now()
+
+	errMessage := ""
+	if deliverErr != nil {
+		errMessage = deliverErr.Error()
+	}
+
+	ctx, span := startSpan(m.ctx, "webhooks.record_attempt", "webhook_deliveries")
+	defer span.End()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	attempts := delivery.Attempts + 1
+
+	status := WebhookDeliveryDelivered
+	switch {
+	case deliverErr == nil:
+	case attempts >= maxWebhookAttempts:
+		status = WebhookDeliveryFailed
+	default:
+		status = WebhookDeliveryPending
+	}
+
+	query := `
+        UPDATE webhook_deliveries
+        SET status = $1, attempts = $2, last_error = $3, claimed_until = NULL,
+            delivered_at = CASE WHEN $1 = 'delivered' THEN $4::timestamptz ELSE delivered_at END,
+            next_attempt_at = CASE WHEN $1 = 'pending' THEN $5::timestamptz ELSE next_attempt_at END
+        WHERE id = $6 AND claimed_until = $7`
+
+	args := []any{status, attempts, errMessage, finish, finish.Add(WebhookBackoff(attempts)), delivery.ID, claimedUntil}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if rowsAffected == 0 {
+		return true, nil
+	}
+
+	query = `
+        INSERT INTO webhook_delivery_attempts (delivery_id, attempted_at, status_code, error, duration_ms)
+        VALUES ($1, $2, NULLIF($3, 0), $4, $5)`
+
+	_, err = tx.Exec(query, delivery.ID, start, statusCode, errMessage, finish.Sub(start).Milliseconds())
+	if err != nil {
+		return false, err
+	}
+
+	active := true
+
+	if deliverErr == nil {
+		_, err = tx.Exec(`UPDATE webhooks SET consecutive_failures = 0 WHERE id = $1`, delivery.WebhookID)
+	} else {
+		query = `
+            UPDATE webhooks
+            SET consecutive_failures = consecutive_failures + 1,
+                active = consecutive_failures + 1 < $1,
+                disabled_at = CASE WHEN consecutive_failures + 1 < $1 THEN disabled_at ELSE $3::timestamptz END
+            WHERE id = $2
+            RETURNING active`
+
+		err = tx.QueryRow(query, maxWebhookFailures, delivery.WebhookID, finish).Scan(&active)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return active, nil
+}
+
+func webhookFields(webhook *Webhook) []any {
+	return []any{
+		&webhook.ID,
+		&webhook.CreatedAt,
+		&webhook.OwnerID,
+		&webhook.URL,
+		pq.Array(&webhook.Events),
+		&webhook.Secret,
+		&webhook.Active,
+		&webhook.ConsecutiveFailures,
+		&webhook.DisabledAt,
+		&webhook.Version,
+	}
+}
+
+func webhookDeliveryFields(delivery *WebhookDelivery) []any {
+	return []any{
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.Event,
+		&delivery.Payload,
+		&delivery.CreatedAt,
+		&delivery.NextAttemptAt,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.DeliveredAt,
+		&delivery.LastError,
+	}
+}
+
+// newWebhookSecret returns a random 256-bit secret, hex encoded.
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// validateWebhookURL checks that rawURL is an absolute http or https URL.
+// Whether its host is a public address can only be known when connecting,
+// so that's left to the dialer deliveries are made through (see
+// notify.PublicDialer).
+func validateWebhookURL(v *validator.Validator, key, rawURL string) {
+	v.Check(rawURL != "", key, "must be provided")
+	v.Check(len(rawURL) <= 2000, key, "must not be more than 2000 bytes long")
+
+	u, err := url.Parse(rawURL)
+	v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", key, "must be an absolute http or https URL")
+}
+
+func ValidateWebhook(v *validator.Validator, webhook *Webhook) {
+	validateWebhookURL(v, "url", webhook.URL)
+
+	v.Check(len(webhook.Events) > 0, "events", "must contain at least 1 event")
+	v.Check(validator.Unique(webhook.Events), "events", "must not contain duplicate values")
+
+	for _, event := range webhook.Events {
+		v.Check(validator.PermittedValue(event, WebhookEvents...), "events", "must only contain note.created, note.updated or note.deleted")
+	}
+
+	// A new webhook without a secret is given a random one when inserted.
+	if webhook.ID != 0 || webhook.Secret != "" {
+		v.Check(len(webhook.Secret) >= 16, "secret", "must be at least 16 bytes long")
+		v.Check(len(webhook.Secret) <= 200, "secret", "must not be more than 200 bytes long")
+	}
+}
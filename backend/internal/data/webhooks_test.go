@@ -0,0 +1,359 @@
+package data_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/data"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/notify"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/testutil"
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/validator"
+)
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{50, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempts), func(t *testing.T) {
+			if got := data.WebhookBackoff(tt.attempts); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	tests := []struct {
+		name     string
+		webhook  data.Webhook
+		errorKey string
+	}{
+		{"valid", data.Webhook{URL: "https://example.com/hook", Events: []string{"note.created"}}, ""},
+		{"missing url", data.Webhook{Events: []string{"note.created"}}, "url"},
+		{"relative url", data.Webhook{URL: "/hook", Events: []string{"note.created"}}, "url"},
+		{"no events", data.Webhook{URL: "https://example.com/hook", Events: []string{}}, "events"},
+		{"unknown event", data.Webhook{URL: "https://example.com/hook", Events: []string{"note.read"}}, "events"},
+		{"duplicate events", data.Webhook{URL: "https://example.com/hook", Events: []string{"note.created", "note.created"}}, "events"},
+		{"short secret", data.Webhook{URL: "https://example.com/hook", Events: []string{"note.created"}, Secret: "abc"}, "secret"},
+		{"cleared secret", data.Webhook{ID: 1, URL: "https://example.com/hook", Events: []string{"note.created"}}, "secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			data.ValidateWebhook(v, &tt.webhook)
+
+			if tt.errorKey == "" {
+				if !v.Valid() {
+					t.Errorf("expected no errors, got %v", v.Errors)
+				}
+				return
+			}
+
+			if _, ok := v.Errors[tt.errorKey]; !ok {
+				t.Errorf("expected an error for %q, got %v", tt.errorKey, v.Errors)
+			}
+		})
+	}
+}
+
+func TestWebhookModel_DeliverDue(t *testing.T) {
+	db, err := testutil.GetTestDB()
+	if err != nil {
+		t.Fatalf("failed to get test DB: %v", err)
+	}
+
+	models := data.NewModels(db)
+
+	newUser := func(name string) *data.User {
+		user := &data.User{Name: name, Email: fmt.Sprintf("%s-%d@example.com", name, time.Now().UnixNano())}
+		if err := user.Password.Set("pa55word1234"); err != nil {
+			t.Fatal(err)
+		}
+		if err := models.Users.Insert(user); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+
+	owner := newUser("subscriber")
+	stranger := newUser("stranger")
+
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+
+	var mu sync.Mutex
+	var received []string
+	secrets := map[string]string{}
+
+	events := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return strings.Join(received, ",")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		secret := secrets[r.URL.Path]
+		received = append(received, r.Header.Get("X-Webhook-Event"))
+		mu.Unlock()
+
+		timestamp, _ := strconv.ParseInt(r.Header.Get("X-Webhook-Timestamp"), 10, 64)
+		if r.Header.Get("X-Webhook-Signature") != "sha256="+notify.Sign(secret, timestamp, body) {
+			t.Errorf("expected a valid signature on the %s delivery", r.URL.Path)
+		}
+
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+
+	webhook := &data.Webhook{OwnerID: owner.ID, URL: srv.URL + "/changes", Events: []string{"note.created", "note.updated"}, Secret: "0123456789abcdef"}
+	deletions := &data.Webhook{OwnerID: owner.ID, URL: srv.URL + "/deletions", Events: []string{"note.deleted"}}
+	unseen := &data.Webhook{OwnerID: stranger.ID, URL: srv.URL + "/unseen", Events: data.WebhookEvents}
+
+	for _, wh := range []*data.Webhook{webhook, deletions, unseen} {
+		if err := models.Webhooks.Insert(wh); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	secrets["/changes"] = webhook.Secret
+	secrets["/deletions"] = deletions.Secret
+
+	if len(deletions.Secret) != 64 {
+		t.Errorf("expected a generated secret, got %q", deletions.Secret)
+	}
+
+	client := notify.NewWebhook(&net.Dialer{})
+
+	deliver := func() {
+		t.Helper()
+
+		_, err := models.Webhooks.DeliverDue(100, func(due *data.DueWebhookDelivery) (int, error) {
+			if due.WebhookID != webhook.ID && due.WebhookID != deletions.ID {
+				return http.StatusOK, nil
+			}
+			return client.Deliver(context.Background(), due.URL, due.Secret, notify.Event{DeliveryID: due.ID, Name: due.Event, Body: due.Payload})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deliveries := func(wh *data.Webhook) []*data.WebhookDelivery {
+		t.Helper()
+
+		deliveries, err := models.Webhooks.GetDeliveries(wh.ID, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return deliveries
+	}
+
+	note := &data.Note{Title: "Launch plan", Body: "Ship it", Tags: []string{}}
+	if err := models.Notes.InsertOwned(note, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	note.Body = "Ship it on Friday"
+	if _, err := models.Notes.Update(note); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("delivered", func(t *testing.T) {
+		if got := deliveries(unseen); len(got) != 0 {
+			t.Errorf("expected no events for a user who can't see the note, got %d", len(got))
+		}
+
+		deliver()
+
+		if got := events(); got != "note.created,note.updated" {
+			t.Errorf("expected the created and updated events in order, got %s", got)
+		}
+
+		for _, delivery := range deliveries(webhook) {
+			if delivery.Status != data.WebhookDeliveryDelivered || delivery.DeliveredAt == nil || len(delivery.AttemptLog) != 1 {
+				t.Errorf("expected a single successful attempt, got %+v", delivery)
+			}
+			if code := delivery.AttemptLog[0].StatusCode; code == nil || *code != http.StatusOK {
+				t.Errorf("expected the receiver's status to be recorded, got %v", code)
+			}
+		}
+	})
+
+	t.Run("retried with backoff", func(t *testing.T) {
+		status.Store(http.StatusServiceUnavailable)
+		before := events()
+
+		note.Body = "Ship it next week"
+		if _, err := models.Notes.Update(note); err != nil {
+			t.Fatal(err)
+		}
+
+		deliver()
+		deliver()
+
+		if got := events(); got != before+",note.updated" {
+			t.Fatalf("expected one attempt before the backoff elapses, got %s", got)
+		}
+
+		latest := deliveries(webhook)[0]
+		if latest.Status != data.WebhookDeliveryPending || latest.Attempts != 1 || !latest.NextAttemptAt.After(time.Now()) {
+			t.Errorf("expected the delivery to be rescheduled, got %+v", latest)
+		}
+
+		for range 7 {
+			if _, err := db.Exec(`UPDATE webhook_deliveries SET next_attempt_at = NOW() WHERE webhook_id = $1`, webhook.ID); err != nil {
+				t.Fatal(err)
+			}
+			deliver()
+		}
+
+		latest = deliveries(webhook)[0]
+		if latest.Status != data.WebhookDeliveryFailed || latest.Attempts != 8 || len(latest.AttemptLog) != 8 {
+			t.Errorf("expected the delivery to fail after 8 attempts, got %+v", latest)
+		}
+		if latest.LastError != "webhook responded with status 503" {
+			t.Errorf("unexpected last error %q", latest.LastError)
+		}
+	})
+
+	t.Run("disabled after repeated failures", func(t *testing.T) {
+		for range 3 {
+			note.Body += "!"
+			if _, err := models.Notes.Update(note); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		deliver()
+
+		got, err := models.Webhooks.Get(webhook.ID, owner.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Active || got.DisabledAt == nil || got.ConsecutiveFailures != 10 {
+			t.Fatalf("expected the webhook to be disabled, got %+v", got)
+		}
+
+		if latest := deliveries(webhook)[0]; latest.Attempts != 0 || latest.Status != data.WebhookDeliveryPending {
+			t.Errorf("expected the last delivery to wait for the webhook, got %+v", latest)
+		}
+
+		queued := len(deliveries(webhook))
+
+		note.Body += "?"
+		if _, err := models.Notes.Update(note); err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries(webhook)) != queued {
+			t.Error("expected no events to be queued for a disabled webhook")
+		}
+
+		got.Active = true
+		if err := models.Webhooks.Update(got); err != nil {
+			t.Fatal(err)
+		}
+		if got.ConsecutiveFailures != 0 || got.DisabledAt != nil {
+			t.Errorf("expected re-enabling to clear the failures, got %+v", got)
+		}
+	})
+
+	t.Run("pins, favorites and moves are queued as updates", func(t *testing.T) {
+		queued := len(deliveries(webhook))
+
+		if err := models.Notes.SetPinned(note, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := models.Notes.SetFavorite(note, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := models.Notes.Move(note, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		got := deliveries(webhook)
+		if len(got) != queued+3 {
+			t.Fatalf("expected 3 more deliveries, got %d", len(got)-queued)
+		}
+		for _, delivery := range got[:3] {
+			if delivery.Event != data.WebhookEventNoteUpdated {
+				t.Errorf("expected a note.updated event, got %q", delivery.Event)
+			}
+		}
+	})
+
+	t.Run("claimed deliveries are left to their worker", func(t *testing.T) {
+		claim := `UPDATE webhook_deliveries SET claimed_until = NOW() + INTERVAL '1 hour' WHERE webhook_id = $1 AND status = 'pending'`
+		if _, err := db.Exec(claim, webhook.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		before := events()
+
+		deliver()
+
+		if got := events(); got != before {
+			t.Errorf("expected no attempts at claimed deliveries, got %s", strings.TrimPrefix(got, before))
+		}
+
+		if _, err := db.Exec(`UPDATE webhook_deliveries SET claimed_until = NOW() WHERE webhook_id = $1`, webhook.ID); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		status.Store(http.StatusNoContent)
+
+		if err := models.Notes.Delete(note.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		got := deliveries(deletions)
+		if len(got) != 1 {
+			t.Fatalf("expected one deletion event, got %d", len(got))
+		}
+
+		var payload struct {
+			Event string `json:"event"`
+			Note  struct {
+				ID int64 `json:"id"`
+			} `json:"note"`
+		}
+		if err := json.Unmarshal(got[0].Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.Event != "note.deleted" || payload.Note.ID != note.ID {
+			t.Errorf("unexpected payload %s", got[0].Payload)
+		}
+
+		deliver()
+
+		if got := deliveries(deletions); got[0].Status != data.WebhookDeliveryDelivered {
+			t.Errorf("expected the deletion to be delivered, got %+v", got[0])
+		}
+	})
+}
@@ -0,0 +1,155 @@
+// Package ical writes calendars in the iCalendar format of RFC 5545, for
+// subscribing to from calendar apps.
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const prodID = "-//note-taking-web-app//Notes//EN"
+
+// maxLineOctets is the longest a content line may be, not counting the
+// CRLF, before it has to be folded.
+const maxLineOctets = 75
+
+// Event is a VEVENT. RRule, if set, is a recurrence rule such as
+// "FREQ=WEEKLY;INTERVAL=2" and Alarm adds a display alarm at Start.
+type Event struct {
+	UID         string
+	Stamp       time.Time
+	Start       time.Time
+	Summary     string
+	Description string
+	RRule       string
+	Alarm       bool
+}
+
+// Todo is a VTODO due at Due.
+type Todo struct {
+	UID         string
+	Stamp       time.Time
+	Due         time.Time
+	Summary     string
+	Description string
+	Categories  []string
+	Completed   bool
+}
+
+type Calendar struct {
+	Name   string
+	Events []Event
+	Todos  []Todo
+}
+
+// Encode renders the calendar. The output depends only on the calendar's
+// contents, so it can be hashed for an ETag.
+func (c *Calendar) Encode() []byte {
+	var w writer
+
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", prodID)
+	w.line("CALSCALE", "GREGORIAN")
+	if c.Name != "" {
+		w.line("X-WR-CALNAME", escape(c.Name))
+	}
+
+	for _, event := range c.Events {
+		w.line("BEGIN", "VEVENT")
+		w.line("UID", escape(event.UID))
+		w.line("DTSTAMP", formatTime(event.Stamp))
+		w.line("DTSTART", formatTime(event.Start))
+		w.line("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			w.line("DESCRIPTION", escape(event.Description))
+		}
+		if event.RRule != "" {
+			w.line("RRULE", event.RRule)
+		}
+		if event.Alarm {
+			w.line("BEGIN", "VALARM")
+			w.line("ACTION", "DISPLAY")
+			w.line("DESCRIPTION", escape(event.Summary))
+			w.line("TRIGGER", "PT0S")
+			w.line("END", "VALARM")
+		}
+		w.line("END", "VEVENT")
+	}
+
+	for _, todo := range c.Todos {
+		w.line("BEGIN", "VTODO")
+		w.line("UID", escape(todo.UID))
+		w.line("DTSTAMP", formatTime(todo.Stamp))
+		w.line("DUE", formatTime(todo.Due))
+		w.line("SUMMARY", escape(todo.Summary))
+		if todo.Description != "" {
+			w.line("DESCRIPTION", escape(todo.Description))
+		}
+		if len(todo.Categories) > 0 {
+			categories := make([]string, len(todo.Categories))
+			for i, category := range todo.Categories {
+				categories[i] = escape(category)
+			}
+			w.line("CATEGORIES", strings.Join(categories, ","))
+		}
+		if todo.Completed {
+			w.line("STATUS", "COMPLETED")
+		} else {
+			w.line("STATUS", "NEEDS-ACTION")
+		}
+		w.line("END", "VTODO")
+	}
+
+	w.line("END", "VCALENDAR")
+
+	return w.buf.Bytes()
+}
+
+type writer struct {
+	buf bytes.Buffer
+}
+
+// line writes a content line, folding it onto continuation lines that start
+// with a space wherever it would run past 75 octets. Folds never split a
+// UTF-8 sequence.
+func (w *writer) line(name, value string) {
+	line := name + ":" + value
+	limit := maxLineOctets
+
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		w.buf.WriteString(line[:cut])
+		w.buf.WriteString("\r\n ")
+
+		line = line[cut:]
+		limit = maxLineOctets - 1
+	}
+
+	w.buf.WriteString(line)
+	w.buf.WriteString("\r\n")
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// escape escapes a TEXT value.
+func escape(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
@@ -0,0 +1,107 @@
+package ical_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/ical"
+)
+
+func TestCalendarEncode(t *testing.T) {
+	stamp := time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC)
+
+	cal := &ical.Calendar{
+		Name: "Notes",
+		Events: []ical.Event{{
+			UID:     "reminder-1@notes",
+			Stamp:   stamp,
+			Start:   time.Date(2026, 3, 2, 9, 0, 0, 0, time.FixedZone("CET", 3600)),
+			Summary: "Call Ann; then Bob, maybe",
+			RRule:   "FREQ=WEEKLY;INTERVAL=2",
+			Alarm:   true,
+		}},
+		Todos: []ical.Todo{{
+			UID:         "note-2@notes",
+			Stamp:       stamp,
+			Due:         time.Date(2026, 3, 5, 17, 0, 0, 0, time.UTC),
+			Summary:     "Tax return",
+			Description: "Line one\nC:\\receipts",
+			Categories:  []string{"money", "a,b"},
+		}},
+	}
+
+	expected := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//note-taking-web-app//Notes//EN",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:Notes",
+		"BEGIN:VEVENT",
+		"UID:reminder-1@notes",
+		"DTSTAMP:20260301T083000Z",
+		"DTSTART:20260302T080000Z",
+		`SUMMARY:Call Ann\; then Bob\, maybe`,
+		"RRULE:FREQ=WEEKLY;INTERVAL=2",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		`DESCRIPTION:Call Ann\; then Bob\, maybe`,
+		"TRIGGER:PT0S",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:note-2@notes",
+		"DTSTAMP:20260301T083000Z",
+		"DUE:20260305T170000Z",
+		"SUMMARY:Tax return",
+		`DESCRIPTION:Line one\nC:\\receipts`,
+		`CATEGORIES:money,a\,b`,
+		"STATUS:NEEDS-ACTION",
+		"END:VTODO",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+
+	if got := string(cal.Encode()); got != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+}
+
+func TestLineFolding(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary string
+	}{
+		{"ascii", strings.Repeat("a", 200)},
+		{"multibyte", strings.Repeat("é", 100) + strings.Repeat("日本", 40)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cal := &ical.Calendar{Todos: []ical.Todo{{UID: "x", Summary: tt.summary}}}
+
+			var unfolded strings.Builder
+
+			for i, line := range strings.Split(strings.TrimSuffix(string(cal.Encode()), "\r\n"), "\r\n") {
+				if len(line) > 75 {
+					t.Errorf("line %d is %d octets long", i, len(line))
+				}
+
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d splits a UTF-8 sequence", i)
+				}
+
+				if strings.HasPrefix(line, " ") {
+					unfolded.WriteString(line[1:])
+				} else {
+					unfolded.WriteString("\n" + line)
+				}
+			}
+
+			if !strings.Contains(unfolded.String(), "\nSUMMARY:"+tt.summary+"\n") {
+				t.Errorf("expected the summary to survive unfolding, got %q", unfolded.String())
+			}
+		})
+	}
+}
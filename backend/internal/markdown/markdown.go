@@ -0,0 +1,122 @@
+package markdown
+
+import (
+	"bytes"
+	"container/list"
+	"regexp"
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Renderer turns note bodies into sanitized HTML. Output is cached per note
+// and keyed by version, so a note is only rendered again once it changes.
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[int64]*list.Element
+	recent     *list.List
+}
+
+type cacheEntry struct {
+	id      int64
+	version int
+	html    string
+}
+
+func NewRenderer(maxEntries int) *Renderer {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^(|checked|disabled)$`)).OnElements("input")
+
+	return &Renderer{
+		md: goldmark.New(
+			goldmark.WithExtensions(extension.Table, extension.TaskList),
+		),
+		policy:     policy,
+		maxEntries: maxEntries,
+		entries:    make(map[int64]*list.Element),
+		recent:     list.New(),
+	}
+}
+
+// Render converts CommonMark, with GitHub-flavoured tables and task lists,
+// to HTML and strips anything outside the allow-list.
+func (r *Renderer) Render(source string) (string, error) {
+	var buf bytes.Buffer
+
+	err := r.md.Convert([]byte(source), &buf)
+	if err != nil {
+		return "", err
+	}
+
+	return r.policy.Sanitize(buf.String()), nil
+}
+
+// RenderNote is Render with caching for the given note ID and version.
+func (r *Renderer) RenderNote(id int64, version int, body string) (string, error) {
+	if html, ok := r.cached(id, version); ok {
+		return html, nil
+	}
+
+	html, err := r.Render(body)
+	if err != nil {
+		return "", err
+	}
+
+	r.store(id, version, html)
+
+	return html, nil
+}
+
+func (r *Renderer) cached(id int64, version int) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elem, ok := r.entries[id]
+	if !ok {
+		return "", false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if entry.version != version {
+		return "", false
+	}
+
+	r.recent.MoveToFront(elem)
+
+	return entry.html, true
+}
+
+func (r *Renderer) store(id int64, version int, html string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.maxEntries <= 0 {
+		return
+	}
+
+	if elem, ok := r.entries[id]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if entry.version > version {
+			return
+		}
+		entry.version = version
+		entry.html = html
+		r.recent.MoveToFront(elem)
+		return
+	}
+
+	r.entries[id] = r.recent.PushFront(&cacheEntry{id: id, version: version, html: html})
+
+	for r.recent.Len() > r.maxEntries {
+		oldest := r.recent.Back()
+		r.recent.Remove(oldest)
+		delete(r.entries, oldest.Value.(*cacheEntry).id)
+	}
+}
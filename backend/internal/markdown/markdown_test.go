@@ -0,0 +1,111 @@
+package markdown_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/markdown"
+)
+
+func TestRender(t *testing.T) {
+	renderer := markdown.NewRenderer(10)
+
+	tests := []struct {
+		name        string
+		source      string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:     "commonmark",
+			source:   "# Heading\n\nSome *emphasis* and `code`.",
+			contains: []string{"<h1", "Heading</h1>", "<em>emphasis</em>", "<code>code</code>"},
+		},
+		{
+			name:     "table",
+			source:   "| a | b |\n|---|---|\n| 1 | 2 |",
+			contains: []string{"<table>", "<th>a</th>", "<td>2</td>"},
+		},
+		{
+			name:     "task list",
+			source:   "- [ ] todo\n- [x] done",
+			contains: []string{`type="checkbox"`, `checked=""`, "todo", "done"},
+		},
+		{
+			name:        "raw script is dropped",
+			source:      "hello <script>alert(1)</script>",
+			notContains: []string{"<script", "alert(1)</script>"},
+		},
+		{
+			name:        "javascript link is dropped",
+			source:      "[click](javascript:alert(1))",
+			notContains: []string{"javascript:"},
+		},
+		{
+			name:        "event handler attribute is dropped",
+			source:      `<img src="x" onerror="alert(1)">`,
+			notContains: []string{"onerror"},
+		},
+		{
+			name:        "only checkbox inputs survive",
+			source:      `<input type="text" value="x">`,
+			notContains: []string{`type="text"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := renderer.Render(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, s := range tt.contains {
+				if !strings.Contains(html, s) {
+					t.Errorf("expected output to contain %q, got %s", s, html)
+				}
+			}
+			for _, s := range tt.notContains {
+				if strings.Contains(html, s) {
+					t.Errorf("expected output not to contain %q, got %s", s, html)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderNoteCache(t *testing.T) {
+	renderer := markdown.NewRenderer(2)
+
+	first, err := renderer.RenderNote(1, 1, "version one")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cached, err := renderer.RenderNote(1, 1, "ignored because version 1 is cached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached != first {
+		t.Errorf("expected cached output %q, got %q", first, cached)
+	}
+
+	second, err := renderer.RenderNote(1, 2, "version two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(second, "version two") {
+		t.Errorf("expected a new version to be rendered again, got %q", second)
+	}
+
+	_, _ = renderer.RenderNote(2, 1, "note two")
+	_, _ = renderer.RenderNote(3, 1, "note three")
+
+	evicted, err := renderer.RenderNote(1, 2, "rendered again after eviction")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(evicted, "rendered again after eviction") {
+		t.Errorf("expected least recently used entry to be evicted, got %q", evicted)
+	}
+}
@@ -0,0 +1,218 @@
+package merge
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	markerOurs   = "<<<<<<< server"
+	markerSep    = "======="
+	markerTheirs = ">>>>>>> client"
+)
+
+// Lines performs a line-level three-way merge of ours and theirs, both of
+// which were derived from base. Hunks changed on only one side are taken from
+// that side; hunks changed identically on both sides are taken once. Hunks
+// changed differently on both sides are written out between git-style
+// conflict markers, with the server's lines (ours) first, and counted in the
+// returned conflicts total.
+func Lines(base, ours, theirs string) (merged string, conflicts int) {
+	o := strings.Split(base, "\n")
+	a := strings.Split(ours, "\n")
+	b := strings.Split(theirs, "\n")
+
+	ma := matches(o, a)
+	mb := matches(o, b)
+
+	var out []string
+
+	i, ia, ib := 0, 0, 0
+
+	for i < len(o) || ia < len(a) || ib < len(b) {
+		k := 0
+		for i+k < len(o) && ma[i+k] == ia+k && mb[i+k] == ib+k {
+			k++
+		}
+
+		if k > 0 {
+			out = append(out, o[i:i+k]...)
+			i, ia, ib = i+k, ia+k, ib+k
+			continue
+		}
+
+		j, ja, jb := i, len(a), len(b)
+		for ; j < len(o); j++ {
+			if ma[j] >= 0 && mb[j] >= 0 {
+				ja, jb = ma[j], mb[j]
+				break
+			}
+		}
+
+		baseHunk, oursHunk, theirsHunk := o[i:j], a[ia:ja], b[ib:jb]
+
+		switch {
+		case slices.Equal(oursHunk, baseHunk):
+			out = append(out, theirsHunk...)
+		case slices.Equal(theirsHunk, baseHunk), slices.Equal(oursHunk, theirsHunk):
+			out = append(out, oursHunk...)
+		default:
+			conflicts++
+			out = append(out, markerOurs)
+			out = append(out, oursHunk...)
+			out = append(out, markerSep)
+			out = append(out, theirsHunk...)
+			out = append(out, markerTheirs)
+		}
+
+		i, ia, ib = j, ja, jb
+	}
+
+	return strings.Join(out, "\n"), conflicts
+}
+
+// Set merges two edits of an unordered set of strings: anything either side
+// added is kept and anything either side removed is dropped. The result
+// keeps the order of ours followed by additions from theirs.
+func Set(base, ours, theirs []string) []string {
+	merged := []string{}
+
+	for _, value := range ours {
+		if slices.Contains(base, value) && !slices.Contains(theirs, value) {
+			continue
+		}
+		if !slices.Contains(merged, value) {
+			merged = append(merged, value)
+		}
+	}
+
+	for _, value := range theirs {
+		if slices.Contains(base, value) && !slices.Contains(ours, value) {
+			continue
+		}
+		if !slices.Contains(merged, value) {
+			merged = append(merged, value)
+		}
+	}
+
+	return merged
+}
+
+// Value merges a single value that cannot be combined, such as a title.
+// It reports false when both sides changed it to different things.
+func Value[T comparable](base, ours, theirs T) (T, bool) {
+	switch {
+	case ours == theirs, theirs == base:
+		return ours, true
+	case ours == base:
+		return theirs, true
+	default:
+		return ours, false
+	}
+}
+
+// matches pairs up the lines of a and b along a shortest edit script,
+// returning for each line of a the index of the matching line in b, or -1
+// if the line was deleted.
+func matches(a, b []string) []int {
+	m := make([]int, len(a))
+	for i := range m {
+		m[i] = -1
+	}
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		m[prefix] = prefix
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		m[len(a)-1-suffix] = len(b) - 1 - suffix
+		suffix++
+	}
+
+	for x, y := range myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		if y >= 0 {
+			m[prefix+x] = prefix + y
+		}
+	}
+
+	return m
+}
+
+// myers implements Myers' O(ND) difference algorithm. Only the band of
+// diagonals reachable at each step is kept for backtracking, so memory
+// grows with the square of the edit distance rather than the input size.
+func myers(a, b []string) []int {
+	n, m := len(a), len(b)
+
+	match := make([]int, n)
+	for i := range match {
+		match[i] = -1
+	}
+
+	if n == 0 || m == 0 {
+		return match
+	}
+
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	var trace [][]int
+
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, slices.Clone(v[offset-d-1:offset+d+2]))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				backtrack(trace, match, n, m)
+				return match
+			}
+		}
+	}
+
+	return match
+}
+
+func backtrack(trace [][]int, match []int, x, y int) {
+	for d := len(trace) - 1; d >= 0; d-- {
+		band := trace[d]
+		at := func(k int) int { return band[k+d+1] }
+
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			match[x] = y
+		}
+
+		x, y = prevX, prevY
+	}
+}
@@ -0,0 +1,189 @@
+package merge_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/merge"
+)
+
+func TestLines(t *testing.T) {
+	base := "one\ntwo\nthree\nfour\nfive"
+
+	tests := []struct {
+		name              string
+		ours              string
+		theirs            string
+		expected          string
+		expectedConflicts int
+	}{
+		{
+			name:     "no changes",
+			ours:     base,
+			theirs:   base,
+			expected: base,
+		},
+		{
+			name:     "only ours changed",
+			ours:     "one\nTWO\nthree\nfour\nfive",
+			theirs:   base,
+			expected: "one\nTWO\nthree\nfour\nfive",
+		},
+		{
+			name:     "only theirs changed",
+			ours:     base,
+			theirs:   "one\ntwo\nthree\nfour\nFIVE",
+			expected: "one\ntwo\nthree\nfour\nFIVE",
+		},
+		{
+			name:     "non-overlapping changes",
+			ours:     "one\nTWO\nthree\nfour\nfive",
+			theirs:   "one\ntwo\nthree\nfour\nFIVE",
+			expected: "one\nTWO\nthree\nfour\nFIVE",
+		},
+		{
+			name:     "identical changes",
+			ours:     "one\ntwo\nTHREE\nfour\nfive",
+			theirs:   "one\ntwo\nTHREE\nfour\nfive",
+			expected: "one\ntwo\nTHREE\nfour\nfive",
+		},
+		{
+			name:     "insertions at different places",
+			ours:     "zero\none\ntwo\nthree\nfour\nfive",
+			theirs:   "one\ntwo\nthree\nfour\nfive\nsix",
+			expected: "zero\none\ntwo\nthree\nfour\nfive\nsix",
+		},
+		{
+			name:     "deletion and edit elsewhere",
+			ours:     "one\nthree\nfour\nfive",
+			theirs:   "one\ntwo\nthree\nfour\nFIVE",
+			expected: "one\nthree\nfour\nFIVE",
+		},
+		{
+			name:              "conflicting changes",
+			ours:              "one\ntwo\nserver\nfour\nfive",
+			theirs:            "one\ntwo\nclient\nfour\nfive",
+			expected:          "one\ntwo\n<<<<<<< server\nserver\n=======\nclient\n>>>>>>> client\nfour\nfive",
+			expectedConflicts: 1,
+		},
+		{
+			name:              "conflicting insertions at the same place",
+			ours:              "one\ntwo\nthree\nfour\nfive\nserver",
+			theirs:            "one\ntwo\nthree\nfour\nfive\nclient",
+			expected:          "one\ntwo\nthree\nfour\nfive\n<<<<<<< server\nserver\n=======\nclient\n>>>>>>> client",
+			expectedConflicts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts := merge.Lines(base, tt.ours, tt.theirs)
+
+			if conflicts != tt.expectedConflicts {
+				t.Errorf("expected %d conflicts, got %d", tt.expectedConflicts, conflicts)
+			}
+			if merged != tt.expected {
+				t.Errorf("expected merge:\n%s\ngot:\n%s", tt.expected, merged)
+			}
+		})
+	}
+}
+
+func TestLinesLargeInput(t *testing.T) {
+	lines := make([]string, 5000)
+	for i := range lines {
+		lines[i] = strings.Repeat("x", i%7) + string(rune('a'+i%26))
+	}
+	base := strings.Join(lines, "\n")
+
+	ours := slices.Clone(lines)
+	ours[10] = "server edit"
+	theirs := slices.Clone(lines)
+	theirs[4000] = "client edit"
+
+	merged, conflicts := merge.Lines(base, strings.Join(ours, "\n"), strings.Join(theirs, "\n"))
+
+	if conflicts != 0 {
+		t.Fatalf("expected no conflicts, got %d", conflicts)
+	}
+
+	got := strings.Split(merged, "\n")
+	if len(got) != len(lines) || got[10] != "server edit" || got[4000] != "client edit" {
+		t.Error("expected both edits to be kept")
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     []string
+		ours     []string
+		theirs   []string
+		expected []string
+	}{
+		{
+			name:     "both add",
+			base:     []string{"dev"},
+			ours:     []string{"dev", "react"},
+			theirs:   []string{"dev", "go"},
+			expected: []string{"dev", "react", "go"},
+		},
+		{
+			name:     "one removes",
+			base:     []string{"dev", "react"},
+			ours:     []string{"dev", "react"},
+			theirs:   []string{"dev"},
+			expected: []string{"dev"},
+		},
+		{
+			name:     "remove and add",
+			base:     []string{"dev", "react"},
+			ours:     []string{"react", "travel"},
+			theirs:   []string{"dev", "react", "go"},
+			expected: []string{"react", "travel", "go"},
+		},
+		{
+			name:     "both add the same tag",
+			base:     []string{},
+			ours:     []string{"dev"},
+			theirs:   []string{"dev"},
+			expected: []string{"dev"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := merge.Set(tt.base, tt.ours, tt.theirs)
+			if !slices.Equal(merged, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, merged)
+			}
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		ours     string
+		theirs   string
+		expected string
+		ok       bool
+	}{
+		{name: "unchanged", base: "a", ours: "a", theirs: "a", expected: "a", ok: true},
+		{name: "ours changed", base: "a", ours: "b", theirs: "a", expected: "b", ok: true},
+		{name: "theirs changed", base: "a", ours: "a", theirs: "c", expected: "c", ok: true},
+		{name: "same change", base: "a", ours: "b", theirs: "b", expected: "b", ok: true},
+		{name: "different changes", base: "a", ours: "b", theirs: "c", expected: "b", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := merge.Value(tt.base, tt.ours, tt.theirs)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tt.expected, tt.ok, got, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+// Package notify delivers reminders about notes, either by email over SMTP or
+// by posting them to a webhook, and posts signed note events to webhook
+// subscribers.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrNotConfigured    = errors.New("email delivery is not configured")
+	ErrNonPublicAddress = errors.New("webhook address is not public")
+)
+
+// Reminder is what a delivered reminder says about its note.
+type Reminder struct {
+	ID        int64     `json:"id"`
+	NoteID    int64     `json:"note_id"`
+	NoteTitle string    `json:"note_title"`
+	FireAt    time.Time `json:"fire_at"`
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Sender   string
+}
+
+// Mailer sends reminders as plain text emails. A Mailer without a host
+// reports ErrNotConfigured rather than failing to connect.
+type Mailer struct {
+	cfg SMTPConfig
+}
+
+func NewMailer(cfg SMTPConfig) *Mailer {
+	return &Mailer{cfg: cfg}
+}
+
+func (m *Mailer) Send(to string, reminder Reminder) error {
+	if m.cfg.Host == "" {
+		return ErrNotConfigured
+	}
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+
+	return smtp.SendMail(addr, auth, m.cfg.Sender, []string{to}, message(m.cfg.Sender, to, reminder))
+}
+
+func message(from, to string, reminder Reminder) []byte {
+	// The title is user input; keep it from adding headers of its own.
+	title := strings.Join(strings.Fields(reminder.NoteTitle), " ")
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: Reminder: %s\r\n", title)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	fmt.Fprintf(&b, "This is your reminder about the note %q (note %d), due %s.\r\n",
+		title, reminder.NoteID, reminder.FireAt.UTC().Format(time.RFC1123))
+
+	return b.Bytes()
+}
+
+// Webhook posts reminders and events as JSON. Any response other than 2xx
+// counts as a failed delivery.
+type Webhook struct {
+	client *http.Client
+}
+
+// NewWebhook returns a Webhook that connects through dialer, or through
+// PublicDialer if dialer is nil. Proxies from the environment aren't used,
+// so that it's always the receiver's address that the dialer checks.
+func NewWebhook(dialer *net.Dialer) *Webhook {
+	if dialer == nil {
+		dialer = PublicDialer()
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Webhook{client: &http.Client{Transport: transport, Timeout: 10 * time.Second}}
+}
+
+// nonPublicPrefixes are the ranges refused by PublicDialer on top of those
+// the netip.Addr methods already identify: "this network", which Linux
+// connects to the local host, shared address space for carrier NAT, IETF
+// protocol assignments, benchmarking, the reserved class E range, and NAT64,
+// which can reach IPv4 addresses of any kind.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// PublicDialer returns a dialer that refuses to connect to loopback,
+// private, link-local and other addresses that aren't publicly routable,
+// such as the cloud metadata service at 169.254.169.254. The check is made
+// on the address being connected to, after the host name has been resolved
+// and for every redirect, so users can't point webhooks at the API's own
+// network.
+func PublicDialer() *net.Dialer {
+	return &net.Dialer{Timeout: 5 * time.Second, Control: refuseNonPublic}
+}
+
+func refuseNonPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	if !isPublic(ip) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, ip)
+	}
+
+	return nil
+}
+
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (wh *Webhook) Post(ctx context.Context, url string, reminder Reminder) error {
+	body, err := json.Marshal(map[string]any{"event": "reminder", "reminder": reminder})
+	if err != nil {
+		return err
+	}
+
+	_, err = wh.post(ctx, url, body, nil)
+	return err
+}
+
+// Event is a signed event delivery. Body is sent as is, so it must already
+// be JSON.
+type Event struct {
+	DeliveryID int64
+	Name       string
+	Body       []byte
+}
+
+// Deliver posts an event signed with secret and returns the receiver's
+// status code, which is 0 if no response was received. The signature is
+// sent in the X-Webhook-Signature header as "sha256=" followed by the hex
+// HMAC-SHA256 of the X-Webhook-Timestamp header, a full stop and the body;
+// signing the timestamp lets receivers reject replayed deliveries.
+func (wh *Webhook) Deliver(ctx context.Context, url, secret string, event Event) (int, error) {
+	timestamp := time.Now().Unix()
+
+	headers := http.Header{}
+	headers.Set("X-Webhook-Event", event.Name)
+	headers.Set("X-Webhook-Delivery", strconv.FormatInt(event.DeliveryID, 10))
+	headers.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	headers.Set("X-Webhook-Signature", "sha256="+Sign(secret, timestamp, event.Body))
+
+	return wh.post(ctx, url, event.Body, headers)
+}
+
+// Sign returns the hex HMAC-SHA256 signature of a delivery body sent at
+// timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (wh *Webhook) post(ctx context.Context, url string, body []byte, headers http.Header) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := wh.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
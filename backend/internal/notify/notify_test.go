@@ -0,0 +1,160 @@
+package notify_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/notify"
+)
+
+func TestWebhookPost(t *testing.T) {
+	reminder := notify.Reminder{ID: 7, NoteID: 3, NoteTitle: "Renew passport", FireAt: time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"accepted", http.StatusNoContent, false},
+		{"rejected", http.StatusInternalServerError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received struct {
+				Event    string          `json:"event"`
+				Reminder notify.Reminder `json:"reminder"`
+			}
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+					t.Errorf("expected JSON content type, got %q", ct)
+				}
+
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Error(err)
+				}
+
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			err := notify.NewWebhook(&net.Dialer{}).Post(context.Background(), srv.URL, reminder)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if received.Event != "reminder" || received.Reminder != reminder {
+				t.Errorf("expected reminder %+v to be posted, got %+v", reminder, received)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliver(t *testing.T) {
+	event := notify.Event{DeliveryID: 42, Name: "note.created", Body: []byte(`{"event":"note.created"}`)}
+
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"accepted", http.StatusOK, false},
+		{"rejected", http.StatusGone, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received http.Header
+			var body []byte
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Clone()
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			status, err := notify.NewWebhook(&net.Dialer{}).Deliver(context.Background(), srv.URL, "s3cret", event)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if status != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, status)
+			}
+
+			if string(body) != string(event.Body) {
+				t.Errorf("expected body %s, got %s", event.Body, body)
+			}
+			if received.Get("X-Webhook-Event") != "note.created" || received.Get("X-Webhook-Delivery") != "42" {
+				t.Errorf("unexpected event headers %v", received)
+			}
+
+			timestamp := received.Get("X-Webhook-Timestamp")
+			if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+				t.Fatalf("expected a unix timestamp, got %q", timestamp)
+			}
+
+			mac := hmac.New(sha256.New, []byte("s3cret"))
+			mac.Write([]byte(timestamp + "." + string(body)))
+			expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+			if got := received.Get("X-Webhook-Signature"); got != expected {
+				t.Errorf("expected signature %q, got %q", expected, got)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliverUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	status, err := notify.NewWebhook(&net.Dialer{}).Deliver(context.Background(), srv.URL, "s3cret", notify.Event{Body: []byte("{}")})
+	if err == nil || status != 0 {
+		t.Errorf("expected an error and no status, got %d, %v", status, err)
+	}
+}
+
+func TestWebhookRefusesNonPublicAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("expected no request to reach %s", r.Host)
+	}))
+	defer srv.Close()
+
+	for _, url := range []string{
+		srv.URL,
+		"http://localhost:8080/",
+		"http://[::1]/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/",
+		"http://192.168.1.1:8443/",
+		"http://[fd00::1]/",
+		"http://100.64.0.1/",
+		"http://0.0.0.0:4000/",
+		"http://[::ffff:127.0.0.1]/",
+	} {
+		status, err := notify.NewWebhook(nil).Deliver(context.Background(), url, "s3cret", notify.Event{Body: []byte("{}")})
+		if !errors.Is(err, notify.ErrNonPublicAddress) || status != 0 {
+			t.Errorf("%s: expected ErrNonPublicAddress and no status, got %d, %v", url, status, err)
+		}
+	}
+}
+
+func TestMailerNotConfigured(t *testing.T) {
+	err := notify.NewMailer(notify.SMTPConfig{}).Send("someone@example.com", notify.Reminder{ID: 1})
+	if !errors.Is(err, notify.ErrNotConfigured) {
+		t.Errorf("expected ErrNotConfigured, got %v", err)
+	}
+}
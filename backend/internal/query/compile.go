@@ -0,0 +1,87 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Compile turns a parsed query into a SQL boolean expression over the notes
+// table aliased as alias. Values are never interpolated: each is passed as a
+// positional parameter, numbered from firstParam, and returned in args.
+//
+// Dates are whole UTC days, so updated:>2024-10-01 matches notes updated on
+// or after 2024-10-02 and updated:2024-10-01 matches the whole of that day.
+func Compile(node Node, alias string, firstParam int) (clause string, args []any) {
+	c := &compiler{alias: alias, next: firstParam}
+	return c.compile(node), c.args
+}
+
+type compiler struct {
+	alias string
+	next  int
+	args  []any
+}
+
+func (c *compiler) param(value any) string {
+	c.args = append(c.args, value)
+	c.next++
+	return fmt.Sprintf("$%d", c.next-1)
+}
+
+func (c *compiler) column(name string) string {
+	return c.alias + "." + name
+}
+
+func (c *compiler) compile(node Node) string {
+	switch n := node.(type) {
+	case And:
+		return fmt.Sprintf("(%s AND %s)", c.compile(n.Left), c.compile(n.Right))
+	case Or:
+		return fmt.Sprintf("(%s OR %s)", c.compile(n.Left), c.compile(n.Right))
+	case Not:
+		return fmt.Sprintf("(NOT %s)", c.compile(n.Operand))
+	case Term:
+		return c.term(n)
+	default:
+		panic(fmt.Sprintf("query: unexpected node %T", node))
+	}
+}
+
+func (c *compiler) term(t Term) string {
+	switch fields[t.Field] {
+	case boolField:
+		b, _ := strconv.ParseBool(t.Value)
+		return fmt.Sprintf("%s = %s", c.column(t.Field), c.param(b))
+	case dateField:
+		return c.date(c.column(t.Field+"_at"), t.Op, t.Value)
+	}
+
+	switch t.Field {
+	case "tag":
+		return fmt.Sprintf("%s = ANY(%s)", c.param(t.Value), c.column("tags"))
+	case "title", "body":
+		return fmt.Sprintf("strpos(lower(%s), lower(%s)) > 0", c.column(t.Field), c.param(t.Value))
+	default:
+		return fmt.Sprintf("to_tsvector('simple', %s || ' ' || %s) @@ plainto_tsquery('simple', %s)",
+			c.column("title"), c.column("body"), c.param(t.Value))
+	}
+}
+
+func (c *compiler) date(column, op, value string) string {
+	day, _ := time.Parse(dateLayout, value)
+	nextDay := day.AddDate(0, 0, 1)
+
+	switch op {
+	case ">":
+		return fmt.Sprintf("%s >= %s", column, c.param(nextDay))
+	case ">=":
+		return fmt.Sprintf("%s >= %s", column, c.param(day))
+	case "<":
+		return fmt.Sprintf("%s < %s", column, c.param(day))
+	case "<=":
+		return fmt.Sprintf("%s < %s", column, c.param(nextDay))
+	default:
+		return fmt.Sprintf("(%s >= %s AND %s < %s)", column, c.param(day), column, c.param(nextDay))
+	}
+}
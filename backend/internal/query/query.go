@@ -0,0 +1,372 @@
+// Package query parses the note search language and compiles it into
+// parameterized SQL. A query is a sequence of terms combined with AND, OR
+// and NOT (or a leading "-"), grouped with parentheses. Adjacent terms are
+// ANDed. A term is either free text, matched against the title and body, or
+// a field filter such as tag:dev, title:"react native", updated:>2024-10-01
+// or archived:true.
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const dateLayout = "2006-01-02"
+
+// SyntaxError reports a problem with a query. Pos is the 1-based character
+// position the problem was found at.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// Node is a node in a parsed query.
+type Node interface {
+	node()
+}
+
+type And struct {
+	Left, Right Node
+}
+
+type Or struct {
+	Left, Right Node
+}
+
+type Not struct {
+	Operand Node
+}
+
+// Term is a single filter. Field is empty for free text. Op is set for the
+// date fields only and is one of =, <, <=, > or >=.
+type Term struct {
+	Field string
+	Op    string
+	Value string
+}
+
+func (And) node()  {}
+func (Or) node()   {}
+func (Not) node()  {}
+func (Term) node() {}
+
+type fieldKind int
+
+const (
+	textField fieldKind = iota
+	boolField
+	dateField
+)
+
+var fields = map[string]fieldKind{
+	"tag":      textField,
+	"title":    textField,
+	"body":     textField,
+	"archived": boolField,
+	"pinned":   boolField,
+	"favorite": boolField,
+	"created":  dateField,
+	"updated":  dateField,
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+	tokTerm
+)
+
+type token struct {
+	kind tokenKind
+	pos  int
+	term Term
+}
+
+// Parse parses a query, returning a *SyntaxError if it is malformed.
+func Parse(input string) (Node, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "unexpected " + describe(tok)}
+	}
+
+	return node, nil
+}
+
+func lex(input string) ([]token, error) {
+	runes := []rune(input)
+
+	var tokens []token
+
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, pos: pos})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			tokens = append(tokens, token{kind: tokNot, pos: pos})
+			i++
+		default:
+			tok, next, err := lexTerm(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			i = next
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(runes) + 1}), nil
+}
+
+// lexTerm reads a term, or one of the AND, OR and NOT keywords, starting at
+// runes[start] and returns it with the index just past it.
+func lexTerm(runes []rune, start int) (token, int, error) {
+	pos := start + 1
+
+	if runes[start] == '"' {
+		value, next, err := lexQuoted(runes, start)
+		if err != nil {
+			return token{}, 0, err
+		}
+		return token{kind: tokTerm, pos: pos, term: Term{Value: value}}, next, nil
+	}
+
+	i := start
+	for i < len(runes) && !isDelimiter(runes[i]) && runes[i] != ':' {
+		i++
+	}
+
+	word := string(runes[start:i])
+
+	if i >= len(runes) || runes[i] != ':' {
+		switch word {
+		case "AND":
+			return token{kind: tokAnd, pos: pos}, i, nil
+		case "OR":
+			return token{kind: tokOr, pos: pos}, i, nil
+		case "NOT":
+			return token{kind: tokNot, pos: pos}, i, nil
+		}
+		return token{kind: tokTerm, pos: pos, term: Term{Value: word}}, i, nil
+	}
+
+	field := strings.ToLower(word)
+
+	kind, ok := fields[field]
+	if !ok {
+		return token{}, 0, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("unknown field %q", word)}
+	}
+
+	i++
+	valuePos := i + 1
+
+	var value string
+
+	if i < len(runes) && runes[i] == '"' {
+		var err error
+		value, i, err = lexQuoted(runes, i)
+		if err != nil {
+			return token{}, 0, err
+		}
+	} else {
+		valueStart := i
+		for i < len(runes) && !isDelimiter(runes[i]) {
+			i++
+		}
+		value = string(runes[valueStart:i])
+	}
+
+	term := Term{Field: field, Value: value}
+
+	if kind == dateField {
+		term.Op, term.Value = splitOperator(value)
+		valuePos += len(term.Op)
+	}
+
+	if term.Value == "" {
+		return token{}, 0, &SyntaxError{Pos: valuePos, Msg: fmt.Sprintf("missing value for %s", field)}
+	}
+
+	switch kind {
+	case boolField:
+		if _, err := strconv.ParseBool(term.Value); err != nil {
+			return token{}, 0, &SyntaxError{Pos: valuePos, Msg: fmt.Sprintf("%s must be true or false", field)}
+		}
+	case dateField:
+		if _, err := time.Parse(dateLayout, term.Value); err != nil {
+			return token{}, 0, &SyntaxError{Pos: valuePos, Msg: fmt.Sprintf("%s must be a date in YYYY-MM-DD format", field)}
+		}
+	}
+
+	return token{kind: tokTerm, pos: pos, term: term}, i, nil
+}
+
+func lexQuoted(runes []rune, start int) (string, int, error) {
+	var b strings.Builder
+
+	for i := start + 1; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\' && i+1 < len(runes):
+			i++
+			b.WriteRune(runes[i])
+		case runes[i] == '"':
+			return b.String(), i + 1, nil
+		default:
+			b.WriteRune(runes[i])
+		}
+	}
+
+	return "", 0, &SyntaxError{Pos: start + 1, Msg: "unterminated quoted string"}
+}
+
+func splitOperator(value string) (string, string) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, op) {
+			return op, value[len(op):]
+		}
+	}
+
+	return "=", value
+}
+
+func isDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokOr {
+		p.next()
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = Or{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokTerm, tokNot, tokLParen:
+		default:
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = And{Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokNot:
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Operand: operand}, nil
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, &SyntaxError{Pos: closing.pos, Msg: fmt.Sprintf("expected ) to close ( at position %d", tok.pos)}
+		}
+		return node, nil
+	case tokTerm:
+		return tok.term, nil
+	default:
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "unexpected " + describe(tok)}
+	}
+}
+
+func describe(tok token) string {
+	switch tok.kind {
+	case tokEOF:
+		return "end of query"
+	case tokLParen:
+		return "("
+	case tokRParen:
+		return ")"
+	case tokAnd:
+		return "AND"
+	case tokOr:
+		return "OR"
+	case tokNot:
+		return "NOT"
+	default:
+		return "term"
+	}
+}
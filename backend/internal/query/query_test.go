@@ -0,0 +1,135 @@
+package query_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/query"
+)
+
+func TestCompile(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		name         string
+		input        string
+		expectedSQL  string
+		expectedArgs []any
+	}{
+		{
+			name:         "tag with negation",
+			input:        "tag:dev AND -tag:archive",
+			expectedSQL:  "($3 = ANY(n.tags) AND (NOT $4 = ANY(n.tags)))",
+			expectedArgs: []any{"dev", "archive"},
+		},
+		{
+			name:         "quoted title",
+			input:        `title:"react native"`,
+			expectedSQL:  "strpos(lower(n.title), lower($3)) > 0",
+			expectedArgs: []any{"react native"},
+		},
+		{
+			name:         "updated after",
+			input:        "updated:>2024-10-01",
+			expectedSQL:  "n.updated_at >= $3",
+			expectedArgs: []any{day("2024-10-02")},
+		},
+		{
+			name:         "created on day",
+			input:        "created:2024-10-01",
+			expectedSQL:  "(n.created_at >= $3 AND n.created_at < $4)",
+			expectedArgs: []any{day("2024-10-01"), day("2024-10-02")},
+		},
+		{
+			name:         "archived",
+			input:        "archived:true",
+			expectedSQL:  "n.archived = $3",
+			expectedArgs: []any{true},
+		},
+		{
+			name:         "grouping and implicit and",
+			input:        "(tag:go OR tag:rust) NOT archived:true",
+			expectedSQL:  "(($3 = ANY(n.tags) OR $4 = ANY(n.tags)) AND (NOT n.archived = $5))",
+			expectedArgs: []any{"go", "rust", true},
+		},
+		{
+			name:         "and binds tighter than or",
+			input:        "tag:a OR tag:b tag:c",
+			expectedSQL:  "($3 = ANY(n.tags) OR ($4 = ANY(n.tags) AND $5 = ANY(n.tags)))",
+			expectedArgs: []any{"a", "b", "c"},
+		},
+		{
+			name:         "free text",
+			input:        `meeting "next week"`,
+			expectedSQL:  "(to_tsvector('simple', n.title || ' ' || n.body) @@ plainto_tsquery('simple', $3) AND to_tsvector('simple', n.title || ' ' || n.body) @@ plainto_tsquery('simple', $4))",
+			expectedArgs: []any{"meeting", "next week"},
+		},
+		{
+			name:         "injection stays a parameter",
+			input:        `title:"'; DROP TABLE notes; --"`,
+			expectedSQL:  "strpos(lower(n.title), lower($3)) > 0",
+			expectedArgs: []any{"'; DROP TABLE notes; --"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := query.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			sql, args := query.Compile(node, "n", 3)
+
+			if sql != tt.expectedSQL {
+				t.Errorf("expected SQL\n%s\ngot\n%s", tt.expectedSQL, sql)
+			}
+
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("expected args %v, got %v", tt.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expectedPos int
+	}{
+		{"unknown field", "tag:dev colour:red", 9},
+		{"missing value", "tag:", 5},
+		{"bad boolean", "archived:maybe", 10},
+		{"bad date", "updated:>2024-13-01", 10},
+		{"unclosed group", "(tag:a OR tag:b", 16},
+		{"stray closing paren", "tag:a)", 6},
+		{"dangling operator", "tag:a AND", 10},
+		{"leading operator", "OR tag:a", 1},
+		{"unterminated quote", `title:"react`, 7},
+		{"empty query", "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := query.Parse(tt.input)
+
+			var syntaxErr *query.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected a SyntaxError, got %v", err)
+			}
+
+			if syntaxErr.Pos != tt.expectedPos {
+				t.Errorf("expected position %d, got %d (%v)", tt.expectedPos, syntaxErr.Pos, err)
+			}
+		})
+	}
+}
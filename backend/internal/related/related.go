@@ -0,0 +1,307 @@
+// Package related finds notes on the same topic as a given note. It keeps an
+// in-memory term index of every note's title, body and tags and ranks notes
+// by the cosine similarity of their TF-IDF vectors, boosted by how many tags
+// they share.
+package related
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// titleWeight and tagWeight count each occurrence of a term in the title
+	// or tags as this many occurrences in the body.
+	titleWeight = 2
+	tagWeight   = 3
+
+	// tagBoost is added to the score in proportion to the Jaccard overlap of
+	// the two notes' tag sets.
+	tagBoost = 0.25
+)
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "has": true, "have": true, "in": true, "is": true, "it": true, "its": true,
+	"of": true, "on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "was": true,
+	"we": true, "were": true, "will": true, "with": true, "you": true,
+}
+
+// Match is a note related to the one asked about.
+type Match struct {
+	ID    int64    `json:"id"`
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
+	Score float64  `json:"score"`
+}
+
+type document struct {
+	title string
+	tags  []string
+	terms map[string]int
+}
+
+// Index is safe for concurrent use. Document frequencies are maintained as
+// notes are added and removed; the TF-IDF weights themselves are computed at
+// query time since every change to the corpus shifts them.
+type Index struct {
+	mu   sync.RWMutex
+	docs map[int64]*document
+	df   map[string]int
+}
+
+func newDocument(title, body string, tags []string) *document {
+	terms := make(map[string]int)
+
+	for _, term := range tokenize(title) {
+		terms[term] += titleWeight
+	}
+	for _, term := range tokenize(body) {
+		terms[term]++
+	}
+	for _, tag := range tags {
+		for _, term := range tokenize(tag) {
+			terms[term] += tagWeight
+		}
+	}
+
+	return &document{title: title, tags: slices.Clone(tags), terms: terms}
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs: make(map[int64]*document),
+		df:   make(map[string]int),
+	}
+}
+
+// Add indexes a note, replacing any earlier version of it.
+func (ix *Index) Add(id int64, title, body string, tags []string) {
+	doc := newDocument(title, body, tags)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+
+	for term := range doc.terms {
+		ix.df[term]++
+	}
+
+	ix.docs[id] = doc
+}
+
+// Remove drops a note from the index. Removing an unknown note is a no-op.
+func (ix *Index) Remove(id int64) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id int64) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+
+	for term := range doc.terms {
+		ix.df[term]--
+		if ix.df[term] == 0 {
+			delete(ix.df, term)
+		}
+	}
+
+	delete(ix.docs, id)
+}
+
+// Contains reports whether a note has been indexed.
+func (ix *Index) Contains(id int64) bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	_, ok := ix.docs[id]
+	return ok
+}
+
+// Related ranks every other indexed note by its similarity to note id, most
+// similar first. Notes with nothing in common are left out, as is everything
+// when id hasn't been indexed.
+func (ix *Index) Related(id int64) []Match {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	target, ok := ix.docs[id]
+	if !ok {
+		return nil
+	}
+
+	return ix.rank(target, id)
+}
+
+// Similar ranks every indexed note by its similarity to a draft that hasn't
+// been saved yet.
+func (ix *Index) Similar(title, body string) []Match {
+	draft := newDocument(title, body, nil)
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return ix.rank(draft, 0)
+}
+
+func (ix *Index) rank(target *document, skip int64) []Match {
+	targetVector, targetNorm := ix.vector(target)
+
+	var matches []Match
+
+	for otherID, other := range ix.docs {
+		if otherID == skip {
+			continue
+		}
+
+		otherVector, otherNorm := ix.vector(other)
+
+		score := 0.0
+
+		if targetNorm > 0 && otherNorm > 0 {
+			dot := 0.0
+			for term, weight := range targetVector {
+				dot += weight * otherVector[term]
+			}
+			score = dot / (targetNorm * otherNorm)
+		}
+
+		score += tagBoost * jaccard(target.tags, other.tags)
+
+		if score <= 0 {
+			continue
+		}
+
+		matches = append(matches, Match{ID: otherID, Title: other.title, Tags: slices.Clone(other.tags), Score: score})
+	}
+
+	slices.SortFunc(matches, func(a, b Match) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return matches
+}
+
+// vector returns a document's TF-IDF weights and their Euclidean norm. Term
+// frequencies are dampened logarithmically so that a long note repeating a
+// word doesn't swamp everything else, and IDF is smoothed so that terms found
+// in every note still carry a little weight.
+func (ix *Index) vector(doc *document) (map[string]float64, float64) {
+	n := float64(len(ix.docs))
+
+	vector := make(map[string]float64, len(doc.terms))
+	norm := 0.0
+
+	for term, count := range doc.terms {
+		idf := math.Log((1+n)/(1+float64(ix.df[term]))) + 1
+		weight := (1 + math.Log(float64(count))) * idf
+
+		vector[term] = weight
+		norm += weight * weight
+	}
+
+	return vector, math.Sqrt(norm)
+}
+
+func jaccard(a, b []string) float64 {
+	setA := tagSet(a)
+	setB := tagSet(b)
+
+	if len(setA) == 0 || len(setB) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for tag := range setA {
+		if setB[tag] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(setA)+len(setB)-shared)
+}
+
+func tagSet(tags []string) map[string]bool {
+	set := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		set[strings.ToLower(tag)] = true
+	}
+	return set
+}
+
+func tokenize(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) < 2 || stopWords[word] {
+			continue
+		}
+		terms = append(terms, word)
+	}
+
+	return terms
+}
+
+// TagSuggestion is a tag offered for a draft note.
+type TagSuggestion struct {
+	Tag   string  `json:"tag"`
+	Score float64 `json:"score"`
+}
+
+// SuggestTags turns a ranking of notes similar to a draft into a ranking of
+// their tags: each tag scores the summed similarity of the notes carrying
+// it, so tags that co-occur with the draft's terms across many notes come
+// first. Tags in exclude, typically those already on the draft, are skipped.
+func SuggestTags(matches []Match, exclude []string, limit int) []TagSuggestion {
+	skip := tagSet(exclude)
+
+	scores := make(map[string]*TagSuggestion)
+
+	for _, match := range matches {
+		for _, tag := range match.Tags {
+			key := strings.ToLower(tag)
+			if skip[key] {
+				continue
+			}
+
+			if scores[key] == nil {
+				scores[key] = &TagSuggestion{Tag: tag}
+			}
+			scores[key].Score += match.Score
+		}
+	}
+
+	suggestions := make([]TagSuggestion, 0, len(scores))
+	for _, suggestion := range scores {
+		suggestions = append(suggestions, *suggestion)
+	}
+
+	slices.SortFunc(suggestions, func(a, b TagSuggestion) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Tag, b.Tag)
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions
+}
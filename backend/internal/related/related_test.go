@@ -0,0 +1,107 @@
+package related_test
+
+import (
+	"testing"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/related"
+)
+
+func TestIndexRelated(t *testing.T) {
+	ix := related.NewIndex()
+
+	ix.Add(1, "Go concurrency", "Goroutines and channels make concurrent programs simple.", []string{"go"})
+	ix.Add(2, "Channels in Go", "Buffered channels and goroutines, select statements.", []string{"go"})
+	ix.Add(3, "Sourdough bread", "Flour, water, salt and a lively starter.", []string{"baking"})
+	ix.Add(4, "Go modules", "Versioning dependencies.", []string{"go", "tooling"})
+
+	tests := []struct {
+		name          string
+		id            int64
+		expectedOrder []int64
+	}{
+		{"shared terms rank first", 1, []int64{2, 4}},
+		{"unrelated note has no matches", 3, nil},
+		{"unknown note", 99, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := ix.Related(tt.id)
+
+			if len(matches) != len(tt.expectedOrder) {
+				t.Fatalf("expected %d matches, got %+v", len(tt.expectedOrder), matches)
+			}
+
+			for i, id := range tt.expectedOrder {
+				if matches[i].ID != id {
+					t.Errorf("expected match %d to be note %d, got %d", i, id, matches[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexIncrementalUpdates(t *testing.T) {
+	ix := related.NewIndex()
+
+	ix.Add(1, "Go concurrency", "Goroutines and channels.", nil)
+	ix.Add(2, "Sourdough bread", "Flour and water.", nil)
+
+	if matches := ix.Related(1); len(matches) != 0 {
+		t.Fatalf("expected no matches before the update, got %+v", matches)
+	}
+
+	ix.Add(2, "Channels", "Goroutines talk over channels.", nil)
+
+	matches := ix.Related(1)
+	if len(matches) != 1 || matches[0].ID != 2 || matches[0].Title != "Channels" {
+		t.Fatalf("expected the updated note to match, got %+v", matches)
+	}
+
+	ix.Remove(2)
+
+	if ix.Contains(2) {
+		t.Error("expected note 2 to be removed")
+	}
+
+	if matches := ix.Related(1); len(matches) != 0 {
+		t.Errorf("expected no matches after removal, got %+v", matches)
+	}
+}
+
+func TestSuggestTags(t *testing.T) {
+	ix := related.NewIndex()
+
+	ix.Add(1, "Goroutine leaks", "Finding leaked goroutines with pprof.", []string{"go", "debugging"})
+	ix.Add(2, "Channels", "Goroutines communicate over channels.", []string{"go", "concurrency"})
+	ix.Add(3, "Sourdough", "Flour, water and salt.", []string{"baking"})
+
+	matches := ix.Similar("Channels and workers", "Worker pools built from goroutines and channels.")
+
+	tests := []struct {
+		name         string
+		exclude      []string
+		limit        int
+		expectedTags []string
+	}{
+		{"shared tag ranks first", nil, 5, []string{"go", "concurrency", "debugging"}},
+		{"existing tags are skipped", []string{"GO"}, 5, []string{"concurrency", "debugging"}},
+		{"limit", nil, 1, []string{"go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions := related.SuggestTags(matches, tt.exclude, tt.limit)
+
+			if len(suggestions) != len(tt.expectedTags) {
+				t.Fatalf("expected %d suggestions, got %+v", len(tt.expectedTags), suggestions)
+			}
+
+			for i, tag := range tt.expectedTags {
+				if suggestions[i].Tag != tag {
+					t.Errorf("expected suggestion %d to be %q, got %q", i, tag, suggestions[i].Tag)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/johndennehy101/note-taking-web-app/backend/internal/storage"
+)
+
+const (
+	testAccessKey = "minioadmin"
+	testSecretKey = "minioadmin-secret"
+)
+
+// fakeS3 is a minimal MinIO-style stand-in: it checks Signature Version 4
+// request signatures and keeps objects in memory.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newFakeS3(t *testing.T) *httptest.Server {
+	fake := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.verify(r) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("<Error><Code>SignatureDoesNotMatch</Code></Error>"))
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := r.URL.Path
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		body, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[key])
+		_, _ = w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeS3) verify(r *http.Request) bool {
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	fields := map[string]string{}
+	for _, part := range strings.Split(auth, ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		fields[name] = value
+	}
+
+	credential := strings.Split(fields["Credential"], "/")
+	if len(credential) != 5 || credential[0] != testAccessKey || credential[3] != "s3" {
+		return false
+	}
+
+	var headers strings.Builder
+	for _, name := range strings.Split(fields["SignedHeaders"], ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + value + "\n")
+	}
+
+	canonical := strings.Join([]string{
+		r.Method, r.URL.EscapedPath(), r.URL.RawQuery, headers.String(),
+		fields["SignedHeaders"], r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	scope := strings.Join(credential[1:], "/")
+	toSign := "AWS4-HMAC-SHA256\n" + r.Header.Get("X-Amz-Date") + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range credential[1:] {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(toSign))
+
+	return hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(fields["Signature"]))
+}
+
+func TestBlobStores(t *testing.T) {
+	local := storage.NewLocalStore(t.TempDir())
+
+	srv := newFakeS3(t)
+	s3, err := storage.NewS3Store(storage.S3Config{
+		Endpoint:  srv.URL,
+		Bucket:    "attachments",
+		AccessKey: testAccessKey,
+		SecretKey: testSecretKey,
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]storage.BlobStore{"local": local, "s3": s3}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			content := []byte("attachment contents")
+
+			err := store.Put(ctx, "notes/1/file name.txt", bytes.NewReader(content), int64(len(content)), "text/plain")
+			if err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+
+			rc, err := store.Get(ctx, "notes/1/file name.txt")
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			got, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("expected %q, got %q", content, got)
+			}
+
+			err = store.Delete(ctx, "notes/1/file name.txt")
+			if err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+
+			_, err = store.Get(ctx, "notes/1/file name.txt")
+			if !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("expected ErrNotFound after delete, got %v", err)
+			}
+
+			err = store.Delete(ctx, "notes/1/missing")
+			if err != nil {
+				t.Errorf("expected deleting a missing blob to succeed, got %v", err)
+			}
+
+			for _, key := range []string{"", "../escape", "/absolute", "notes/../../escape", "notes//double"} {
+				err = store.Put(ctx, key, strings.NewReader("x"), 1, "text/plain")
+				if !errors.Is(err, storage.ErrInvalidKey) {
+					t.Errorf("expected ErrInvalidKey for %q, got %v", key, err)
+				}
+			}
+		})
+	}
+}
+
+func TestS3StoreRejectedSignature(t *testing.T) {
+	srv := newFakeS3(t)
+
+	store, err := storage.NewS3Store(storage.S3Config{
+		Endpoint:  srv.URL,
+		Bucket:    "attachments",
+		AccessKey: testAccessKey,
+		SecretKey: "wrong-secret",
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Put(context.Background(), "notes/1/a", strings.NewReader("x"), 1, "text/plain")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected a 403 error for a bad signature, got %v", err)
+	}
+}
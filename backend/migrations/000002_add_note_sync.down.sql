@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS note_tombstones;
+
+ALTER TABLE notes DROP COLUMN IF EXISTS change_seq;
+
+DROP SEQUENCE IF EXISTS note_change_seq;
@@ -0,0 +1,14 @@
+CREATE SEQUENCE IF NOT EXISTS note_change_seq;
+
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS change_seq bigint NOT NULL DEFAULT nextval('note_change_seq');
+
+CREATE INDEX IF NOT EXISTS notes_change_seq_idx ON notes (change_seq);
+
+CREATE TABLE IF NOT EXISTS note_tombstones (
+    note_id bigint PRIMARY KEY,
+    version integer NOT NULL,
+    deleted_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    change_seq bigint NOT NULL DEFAULT nextval('note_change_seq')
+);
+
+CREATE INDEX IF NOT EXISTS note_tombstones_change_seq_idx ON note_tombstones (change_seq);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS note_revisions (
+    note_id bigint NOT NULL REFERENCES notes ON DELETE CASCADE,
+    version integer NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    title text NOT NULL,
+    body text NOT NULL,
+    tags text[] NOT NULL,
+    archived boolean NOT NULL,
+    PRIMARY KEY (note_id, version)
+);
+
+INSERT INTO note_revisions (note_id, version, created_at, title, body, tags, archived)
+SELECT id, version, updated_at, title, body, tags, archived
+FROM notes
+ON CONFLICT DO NOTHING;
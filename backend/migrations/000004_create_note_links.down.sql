@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS notes_lower_title_idx;
+
+DROP TABLE IF EXISTS note_links;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS note_links (
+    source_id bigint NOT NULL REFERENCES notes ON DELETE CASCADE,
+    position integer NOT NULL,
+    text text NOT NULL,
+    target_title text,
+    target_ref bigint,
+    target_id bigint REFERENCES notes ON DELETE SET NULL,
+    PRIMARY KEY (source_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS note_links_target_id_idx ON note_links (target_id);
+CREATE INDEX IF NOT EXISTS note_links_target_title_idx ON note_links (lower(target_title)) WHERE target_id IS NULL;
+CREATE INDEX IF NOT EXISTS notes_lower_title_idx ON notes (lower(title));
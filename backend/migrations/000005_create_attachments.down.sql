@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS blob_deletions;
+
+DROP TABLE IF EXISTS attachments;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS note_shares (
+    id bigserial PRIMARY KEY,
+    note_id bigint NOT NULL REFERENCES notes ON DELETE CASCADE,
+    token_hash bytea NOT NULL UNIQUE,
+    password_hash bytea,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    expires_at timestamp(0) with time zone,
+    access_count bigint NOT NULL DEFAULT 0,
+    last_accessed_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS note_shares_note_id_idx ON note_shares (note_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS note_collaborators;
+
+DROP TABLE IF EXISTS tokens;
+
+DROP TABLE IF EXISTS users;
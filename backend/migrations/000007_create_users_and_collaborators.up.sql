@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name text NOT NULL,
+    email text NOT NULL,
+    password_hash bytea NOT NULL,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_lower_email_idx ON users (lower(email));
+
+CREATE TABLE IF NOT EXISTS tokens (
+    hash bytea PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    expiry timestamp(0) with time zone NOT NULL,
+    scope text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS note_collaborators (
+    note_id bigint NOT NULL REFERENCES notes ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    role text NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+    invited_by bigint REFERENCES users ON DELETE SET NULL,
+    invited_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    accepted_at timestamp(0) with time zone,
+    PRIMARY KEY (note_id, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS note_collaborators_owner_idx ON note_collaborators (note_id) WHERE role = 'owner';
+CREATE INDEX IF NOT EXISTS note_collaborators_user_id_idx ON note_collaborators (user_id);
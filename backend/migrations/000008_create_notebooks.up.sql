@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS notebooks (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name text NOT NULL,
+    parent_id bigint REFERENCES notebooks ON DELETE CASCADE,
+    owner_id bigint REFERENCES users ON DELETE CASCADE,
+    version integer NOT NULL DEFAULT 1,
+    CHECK (parent_id <> id)
+);
+
+CREATE INDEX IF NOT EXISTS notebooks_parent_id_idx ON notebooks (parent_id);
+CREATE INDEX IF NOT EXISTS notebooks_owner_id_idx ON notebooks (owner_id);
+
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS notebook_id bigint REFERENCES notebooks ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS notes_notebook_id_idx ON notes (notebook_id);
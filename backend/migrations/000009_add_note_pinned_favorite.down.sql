@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS notes_favorite_idx;
+
+ALTER TABLE notes DROP COLUMN IF EXISTS favorite;
+ALTER TABLE notes DROP COLUMN IF EXISTS pinned;
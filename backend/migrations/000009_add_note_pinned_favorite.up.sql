@@ -0,0 +1,4 @@
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS pinned boolean NOT NULL DEFAULT FALSE;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS favorite boolean NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS notes_favorite_idx ON notes (id) WHERE favorite;
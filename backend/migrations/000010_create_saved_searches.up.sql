@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name text NOT NULL,
+    query jsonb NOT NULL,
+    sort text NOT NULL,
+    owner_id bigint REFERENCES users ON DELETE CASCADE,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_owner_id_idx ON saved_searches (owner_id);
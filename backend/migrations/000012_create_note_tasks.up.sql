@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS note_tasks (
+    id bigserial PRIMARY KEY,
+    note_id bigint NOT NULL REFERENCES notes ON DELETE CASCADE,
+    position integer NOT NULL,
+    line integer NOT NULL,
+    text text NOT NULL,
+    done boolean NOT NULL,
+    UNIQUE (note_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS note_tasks_done_idx ON note_tasks (done);
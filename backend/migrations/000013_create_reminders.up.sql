@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS reminders (
+    id bigserial PRIMARY KEY,
+    note_id bigint NOT NULL REFERENCES notes ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    fire_at timestamp(0) with time zone NOT NULL,
+    recurrence text NOT NULL DEFAULT '',
+    channel text NOT NULL CHECK (channel IN ('email', 'webhook')),
+    webhook_url text NOT NULL DEFAULT '',
+    delivered_at timestamp(0) with time zone,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS reminders_note_id_user_id_idx ON reminders (note_id, user_id);
+CREATE INDEX IF NOT EXISTS reminders_pending_fire_at_idx ON reminders (fire_at) WHERE delivered_at IS NULL OR delivered_at < fire_at;
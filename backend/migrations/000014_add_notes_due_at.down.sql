@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS notes_due_at_idx;
+
+ALTER TABLE notes DROP COLUMN IF EXISTS due_at;
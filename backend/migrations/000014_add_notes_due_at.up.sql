@@ -0,0 +1,3 @@
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS due_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS notes_due_at_idx ON notes (due_at) WHERE due_at IS NOT NULL;
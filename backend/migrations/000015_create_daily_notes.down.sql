@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS daily_notes;
+
+ALTER TABLE users DROP COLUMN IF EXISTS daily_template;
+ALTER TABLE users DROP COLUMN IF EXISTS time_zone;
@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS time_zone text NOT NULL DEFAULT 'UTC';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS daily_template text NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS daily_notes (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    day date NOT NULL,
+    note_id bigint NOT NULL UNIQUE REFERENCES notes ON DELETE CASCADE,
+    PRIMARY KEY (user_id, day)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS note_templates (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name text NOT NULL,
+    title text NOT NULL DEFAULT '',
+    body text NOT NULL,
+    tags text[] NOT NULL DEFAULT '{}',
+    owner_id bigint REFERENCES users ON DELETE CASCADE,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS note_templates_owner_id_idx ON note_templates (owner_id);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS note_tombstones_change_seq_at_commit ON note_tombstones;
+DROP TRIGGER IF EXISTS notes_change_seq_at_commit ON notes;
+DROP FUNCTION IF EXISTS note_tombstones_assign_change_seq();
+DROP FUNCTION IF EXISTS notes_assign_change_seq();
@@ -0,0 +1,37 @@
+-- change_seq is drawn when a row is written, but transactions can commit in
+-- a different order, so a sync client could move its cursor past a number
+-- that only becomes visible later. Drawing it again at commit, while holding
+-- a lock that is only released once the transaction is visible, makes the
+-- numbers follow commit order.
+CREATE OR REPLACE FUNCTION notes_assign_change_seq() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('note_change_seq'));
+    UPDATE notes SET change_seq = nextval('note_change_seq') WHERE id = NEW.id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION note_tombstones_assign_change_seq() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('note_change_seq'));
+    UPDATE note_tombstones SET change_seq = nextval('note_change_seq') WHERE note_id = NEW.note_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- The depth check keeps the triggers' own updates from queueing them again.
+DROP TRIGGER IF EXISTS notes_change_seq_at_commit ON notes;
+CREATE CONSTRAINT TRIGGER notes_change_seq_at_commit
+    AFTER INSERT OR UPDATE ON notes
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    WHEN (pg_trigger_depth() = 0)
+    EXECUTE FUNCTION notes_assign_change_seq();
+
+DROP TRIGGER IF EXISTS note_tombstones_change_seq_at_commit ON note_tombstones;
+CREATE CONSTRAINT TRIGGER note_tombstones_change_seq_at_commit
+    AFTER INSERT OR UPDATE ON note_tombstones
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    WHEN (pg_trigger_depth() = 0)
+    EXECUTE FUNCTION note_tombstones_assign_change_seq();
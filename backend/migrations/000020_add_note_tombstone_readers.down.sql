@@ -0,0 +1 @@
+ALTER TABLE note_tombstones DROP COLUMN IF EXISTS reader_ids;
@@ -0,0 +1,5 @@
+-- The users who could read a note when it was deleted, so that only they
+-- are told about it when syncing. NULL is for notes that were open to
+-- everyone. Tombstones from before this was recorded are shown to nobody.
+ALTER TABLE note_tombstones ADD COLUMN IF NOT EXISTS reader_ids bigint[] DEFAULT '{}';
+ALTER TABLE note_tombstones ALTER COLUMN reader_ids DROP DEFAULT;
@@ -0,0 +1 @@
+ALTER TABLE reminders ADD COLUMN IF NOT EXISTS claimed_until timestamp(0) with time zone;
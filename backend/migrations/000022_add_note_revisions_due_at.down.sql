@@ -0,0 +1 @@
+ALTER TABLE note_revisions DROP COLUMN IF EXISTS due_at;
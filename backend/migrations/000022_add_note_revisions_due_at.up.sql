@@ -0,0 +1,7 @@
+ALTER TABLE note_revisions ADD COLUMN IF NOT EXISTS due_at timestamp(0) with time zone;
+
+-- Only the latest revision of each note has a known due date.
+UPDATE note_revisions r
+SET due_at = n.due_at
+FROM notes n
+WHERE n.id = r.note_id AND n.version = r.version;